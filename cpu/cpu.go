@@ -0,0 +1,103 @@
+// Package cpu reports the instruction set extensions of the host CPU.
+package cpu
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Feature is a set of x86-64 instruction set extensions.
+//
+// A Feature is usually a single extension. However, a program's
+// requirements are a set of extensions, so the values are spaced to be
+// combined as a bit field.
+type Feature uint64
+
+const (
+	SSE2 Feature = 1 << iota
+	SSE3
+	SSSE3
+	SSE41
+	SSE42
+	POPCNT
+	CX16 // CMPXCHG16B
+	LZCNT
+	BMI1
+	BMI2
+	AVX
+	AVX2
+	FMA
+	AVX512F
+	AVX512BW
+
+	lastFeature
+)
+
+var featureName = []string{
+	"SSE2",
+	"SSE3",
+	"SSSE3",
+	"SSE41",
+	"SSE42",
+	"POPCNT",
+	"CX16",
+	"LZCNT",
+	"BMI1",
+	"BMI2",
+	"AVX",
+	"AVX2",
+	"FMA",
+	"AVX512F",
+	"AVX512BW",
+}
+
+// The x86-64 microarchitecture levels, as defined by the x86-64 psABI.
+// Each level includes the features of the levels before it. Only the
+// features this package tracks are included.
+const (
+	V1 = SSE2
+	V2 = V1 | CX16 | POPCNT | SSE3 | SSSE3 | SSE41 | SSE42
+	V3 = V2 | AVX | AVX2 | BMI1 | BMI2 | FMA | LZCNT
+	V4 = V3 | AVX512F | AVX512BW
+)
+
+// ParseLevel returns the features of a microarchitecture level named
+// "x86-64", "x86-64-v2", "x86-64-v3" or "x86-64-v4". The "x86-64-"
+// prefix may be omitted, as in GOAMD64.
+func ParseLevel(name string) (Feature, error) {
+	switch strings.TrimPrefix(name, "x86-64-") {
+	case "x86-64", "v1":
+		return V1, nil
+	case "v2":
+		return V2, nil
+	case "v3":
+		return V3, nil
+	case "v4":
+		return V4, nil
+	}
+	return 0, fmt.Errorf("cpu: unknown level %q", name)
+}
+
+// Host is the set of features supported by the CPU and operating system
+// the program is running on.
+var Host = detect()
+
+// Has reports whether f includes all of the features in g.
+func (f Feature) Has(g Feature) bool { return f&g == g }
+
+// String returns the names of the features in f, separated by |.
+func (f Feature) String() string {
+	if f == 0 {
+		return "none"
+	}
+	var names []string
+	for i, name := range featureName {
+		if f&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if f >= lastFeature {
+		names = append(names, "unknown")
+	}
+	return strings.Join(names, "|")
+}
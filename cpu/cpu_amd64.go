@@ -0,0 +1,56 @@
+package cpu
+
+// cpuid executes CPUID with the given EAX and ECX.
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+// xgetbv returns XCR0, the extended state enabled by the operating system.
+func xgetbv() (eax, edx uint32)
+
+func detect() Feature {
+	bit := func(r uint32, n uint) bool { return r&(1<<n) != 0 }
+	var f Feature
+	set := func(ok bool, g Feature) {
+		if ok {
+			f |= g
+		}
+	}
+
+	max, _, _, _ := cpuid(0, 0)
+	if max < 1 {
+		return f
+	}
+	_, _, ecx1, edx1 := cpuid(1, 0)
+	set(bit(edx1, 26), SSE2)
+	set(bit(ecx1, 0), SSE3)
+	set(bit(ecx1, 9), SSSE3)
+	set(bit(ecx1, 13), CX16)
+	set(bit(ecx1, 19), SSE41)
+	set(bit(ecx1, 20), SSE42)
+	set(bit(ecx1, 23), POPCNT)
+
+	// The AVX registers are only usable if the operating system saves
+	// them, which it reports in XCR0.
+	var avx, avx512 bool
+	if bit(ecx1, 27) { // OSXSAVE
+		xcr0, _ := xgetbv()
+		avx = xcr0&0x6 == 0x6      // XMM and YMM state
+		avx512 = xcr0&0xe6 == 0xe6 // and opmask and ZMM state
+	}
+	set(avx && bit(ecx1, 28), AVX)
+	set(avx && bit(ecx1, 12), FMA)
+
+	if max >= 7 {
+		_, ebx7, _, _ := cpuid(7, 0)
+		set(bit(ebx7, 3), BMI1)
+		set(bit(ebx7, 8), BMI2)
+		set(avx && bit(ebx7, 5), AVX2)
+		set(avx512 && bit(ebx7, 16), AVX512F)
+		set(avx512 && bit(ebx7, 16) && bit(ebx7, 30), AVX512BW)
+	}
+
+	if maxExt, _, _, _ := cpuid(0x80000000, 0); maxExt >= 0x80000001 {
+		_, _, ecx, _ := cpuid(0x80000001, 0)
+		set(bit(ecx, 5), LZCNT)
+	}
+	return f
+}
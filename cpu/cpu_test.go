@@ -0,0 +1,64 @@
+package cpu
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		f    Feature
+		want string
+	}{
+		{0, "none"},
+		{SSE2, "SSE2"},
+		{AVX2 | BMI2 | SSE41, "SSE41|BMI2|AVX2"},
+		{AVX512BW | 1<<63, "AVX512BW|unknown"},
+	}
+	for _, test := range tests {
+		if got := test.f.String(); got != test.want {
+			t.Errorf("%#x.String()=%q, want %q", uint64(test.f), got, test.want)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	t.Logf("host features: %v", Host)
+	if runtime.GOARCH == "amd64" && !Host.Has(SSE2) {
+		t.Errorf("amd64 host does not have SSE2: %v", Host)
+	}
+	if Host.Has(AVX2) && !Host.Has(AVX) {
+		t.Errorf("host has AVX2 without AVX: %v", Host)
+	}
+	if Host.Has(AVX512BW) && !Host.Has(AVX512F) {
+		t.Errorf("host has AVX512BW without AVX512F: %v", Host)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		want Feature
+	}{
+		{"x86-64", V1},
+		{"x86-64-v2", V2},
+		{"v3", V3},
+		{"x86-64-v4", V4},
+	}
+	for _, test := range tests {
+		got, err := ParseLevel(test.name)
+		if err != nil {
+			t.Errorf("ParseLevel(%q): %v", test.name, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseLevel(%q)=%v, want %v", test.name, got, test.want)
+		}
+	}
+	if _, err := ParseLevel("v5"); err == nil {
+		t.Error("ParseLevel(\"v5\") succeeded")
+	}
+	if !V3.Has(V2) || V2.Has(AVX) || !V4.Has(AVX512BW) {
+		t.Errorf("levels are not nested: v2=%v, v3=%v, v4=%v", V2, V3, V4)
+	}
+}
@@ -25,6 +25,12 @@ const (
 	Rel16 AddrType = 1 << 8  // relative address, signed 16 bits.
 	Rel32 AddrType = 1 << 9  // relative address, signed 32 bits.
 	Label AddrType = 1 << 10 // label, no binary representation.
+	List  AddrType = 1 << 11 // list of source operands, see Args.
+	Ymm   AddrType = 1 << 12 // AVX register, 256 bits.
+	Zmm   AddrType = 1 << 13 // AVX-512 register, 512 bits.
+	Kreg  AddrType = 1 << 14 // AVX-512 opmask register.
+
+	ImmAny AddrType = 1 << 15 // immediate data, width chosen by the encoder.
 )
 
 // Addr is an address used by an instruction.
@@ -32,14 +38,21 @@ const (
 type Addr struct {
 	Type  AddrType
 	Value interface{} // either an int primitive or a Register
-	Disp  uint64      // displacement value
+	Disp  uint64      // displacement value, two's complement
 	Name  string      // label, or debugging information
 }
 
 // Imm builds an Addr that represents immediate data.
-// The value of v must be of type uint8, uint32, or uint64.
+// The value of v must be of type uint8, uint32, or uint64, which fixes
+// the width of the immediate, or int or int64. The width of an int or
+// int64 immediate is chosen by the encoder as the shortest that can
+// represent the value.
 func Imm(v interface{}) Addr {
-	switch v.(type) {
+	switch v := v.(type) {
+	case int:
+		return Addr{Type: ImmAny, Value: int64(v)}
+	case int64:
+		return Addr{Type: ImmAny, Value: v}
 	case uint8:
 		return Addr{Type: Imm8, Value: v}
 	case uint32:
@@ -62,6 +75,14 @@ func Rel(v interface{}) Addr {
 	panic(fmt.Sprintf("unknown Rel value: %T (%v)", v, v))
 }
 
+// Args builds an Addr listing the source operands of an instruction with
+// more than two operands. As in the Go assembler, operands are listed
+// source first and the destination is To. For example, to compute
+// AX = ^BX & CX:
+//
+//	Instruction{ANDNQ, Args(CX.Addr(), BX.Addr()), AX.Addr()}
+func Args(a ...Addr) Addr { return Addr{Type: List, Value: a} }
+
 // LabelAddr builds an Addr that represents a label.
 func LabelAddr(name string) Addr { return Addr{Type: Label, Name: name} }
 
@@ -100,13 +121,15 @@ func (a *Addr) valueUint64() uint64 {
 func (p *Addr) printText(w io.Writer, codeblockEnd int) {
 	switch p.Type {
 	case None:
-	case Reg, Xmm:
+	case Reg, Xmm, Ymm, Zmm, Kreg:
 		name := p.Value.(Register).String()
 		io.WriteString(w, name)
 	case Ind:
 		name := p.Value.(Register).String()
-		if p.Disp != 0 {
-			fmt.Fprintf(w, "%x+(%s)", p.Disp, name)
+		if d := int64(p.Disp); d < 0 {
+			fmt.Fprintf(w, "-%x+(%s)", uint64(-d), name)
+		} else if d > 0 {
+			fmt.Fprintf(w, "%x+(%s)", d, name)
 		} else {
 			fmt.Fprintf(w, "(%s)", name)
 		}
@@ -120,8 +143,17 @@ func (p *Addr) printText(w io.Writer, codeblockEnd int) {
 		}
 	case Imm8, Imm16, Imm32, Imm64:
 		fmt.Fprintf(w, "0x%x", p.Value)
+	case ImmAny:
+		fmt.Fprintf(w, "%#x", p.Value)
 	case Label:
 		fmt.Fprint(w, p.Name)
+	case List:
+		for i, a := range p.Value.([]Addr) {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			a.printText(w, codeblockEnd)
+		}
 	default:
 		panic(fmt.Sprintf("unknown addr type: %v", p.Type))
 	}
@@ -140,6 +172,12 @@ var addrTypeName = map[AddrType]string{
 	Rel16: "Rel16",
 	Rel32: "Rel32",
 	Label: "Label",
+	List:  "List",
+	Ymm:   "Ymm",
+	Zmm:   "Zmm",
+	Kreg:  "Kreg",
+
+	ImmAny: "ImmAny",
 }
 
 func (a AddrType) String() string {
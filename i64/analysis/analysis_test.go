@@ -0,0 +1,222 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/crawshaw/asm/i64"
+)
+
+var defUseTests = []struct {
+	in       Instruction
+	def, use string
+}{
+	{Instruction{Op: LABEL, From: LabelAddr("x")}, "{}", "{}"},
+	{Instruction{MOVQ, Imm(1), AX.Addr()}, "{AX}", "{}"},
+	{Instruction{MOVQ, BX.Ind(8), AX.Addr()}, "{AX}", "{BX}"},
+	{Instruction{MOVQ, AX.Addr(), BX.Ind(8)}, "{}", "{AX BX}"},
+	{Instruction{MOVB, Imm(uint8(1)), AX.Addr()}, "{AX}", "{AX}"},
+	{Instruction{ADDQ, BX.Addr(), AX.Addr()}, "{AX FLAGS}", "{AX BX}"},
+	{Instruction{ADCQ, BX.Addr(), AX.Addr()}, "{AX FLAGS}", "{AX BX FLAGS}"},
+	{Instruction{CMPQ, BX.Addr(), AX.Addr()}, "{FLAGS}", "{AX BX}"},
+	{Instruction{LEAQ, AX.Addr(), BX.Ind(8)}, "{AX}", "{BX}"},
+	{Instruction{IMULQ, AX.Addr(), BX.Addr()}, "{AX FLAGS}", "{AX BX}"},
+	{Instruction{IMULQ, Args(Imm(3), BX.Addr()), AX.Addr()}, "{AX FLAGS}", "{BX}"},
+	{Instruction{Op: IDIVQ, To: CX.Addr()}, "{AX DX FLAGS}", "{AX CX DX}"},
+	{Instruction{XCHGQ, AX.Addr(), BX.Addr()}, "{AX BX}", "{AX BX}"},
+	{Instruction{CMPXCHGQ | LOCK, BX.Addr(), SI.Ind(0)}, "{AX FLAGS}", "{AX BX SI}"},
+	{Instruction{MULXQ, Args(BX.Addr(), R8.Addr()), R9.Addr()}, "{R8 R9}", "{DX BX}"},
+	{Instruction{SHLDQ, Args(CX.Addr(), BX.Addr()), AX.Addr()}, "{AX FLAGS}", "{AX CX BX FLAGS}"},
+	{Instruction{Op: SETE, To: AX.Addr()}, "{AX}", "{AX FLAGS}"},
+	{Instruction{CMOVQNE, BX.Addr(), AX.Addr()}, "{AX}", "{AX BX FLAGS}"},
+	{Instruction{Op: JNE, To: LabelAddr("x")}, "{}", "{FLAGS}"},
+	{Instruction{Op: CALL, To: LabelAddr("f")}, (CallerSaved | Of(SP) | Flags).String(), "{SP}"},
+	{Instruction{Op: CALL, To: AX.Addr()}, (CallerSaved | Of(SP) | Flags).String(), "{AX SP}"},
+	{Instruction{Op: PUSHQ, From: BX.Addr()}, "{SP}", "{BX SP}"},
+	{Instruction{Op: POPQ, To: BX.Addr()}, "{BX SP}", "{SP}"},
+	{Instruction{Op: RET}, "{SP}", "{SP}"},
+	{Instruction{Op: MOVSB | REP}, "{CX SI DI}", "{CX SI DI}"},
+	{Instruction{Op: SCASB | REPNE}, "{CX DI FLAGS}", "{AX CX DI FLAGS}"},
+	{Instruction{Op: CPUID}, "{AX CX DX BX}", "{AX CX}"},
+	{Instruction{ANDNQ, Args(BX.Addr(), CX.Addr()), AX.Addr()}, "{AX FLAGS}", "{CX BX}"},
+	{Instruction{BLSRQ, BX.Addr(), AX.Addr()}, "{AX FLAGS}", "{BX}"},
+	{Instruction{MOVSS, AX.Ind(0), X1.Addr()}, "{X1}", "{AX}"},
+	{Instruction{MOVSS, X2.Addr(), X1.Addr()}, "{X1}", "{X1 X2}"},
+	{Instruction{MOVUPS, X2.Addr(), X1.Addr()}, "{X1}", "{X2}"},
+	{Instruction{ADDPS, X2.Addr(), X1.Addr()}, "{X1}", "{X1 X2}"},
+	{Instruction{SHUFPS, Args(Imm(uint8(0)), X2.Addr()), X1.Addr()}, "{X1}", "{X1 X2}"},
+	{Instruction{PSHUFD, Args(Imm(uint8(0)), X2.Addr()), X1.Addr()}, "{X1}", "{X2}"},
+	{Instruction{PBLENDVB, X2.Addr(), X1.Addr()}, "{X1}", "{X0 X1 X2}"},
+	{Instruction{UCOMISD, X2.Addr(), X1.Addr()}, "{FLAGS}", "{X1 X2}"},
+	{Instruction{VPADDD, Args(Y2.Addr(), Y3.Addr()), Y1.Addr()}, "{X1}", "{X2 X3}"},
+	{Instruction{VFMADD231PS, Args(Y2.Addr(), Y3.Addr()), Y1.Addr()}, "{X1}", "{X1 X2 X3}"},
+	{Instruction{VPADDD, Args(Z2.Addr(), Z17.Addr(), K1.Addr()), Z1.Addr()}, "{X1}", "{X1 X2 Z17 K1}"},
+	{Instruction{VPADDD | ZERO, Args(Z2.Addr(), Z17.Addr(), K1.Addr()), Z1.Addr()}, "{X1}", "{X2 Z17 K1}"},
+	{Instruction{Op: VZEROUPPER}, "{}", "{}"},
+	{Instruction{XORL, AX.Addr(), AX.Addr()}, "{AX FLAGS}", "{}"},
+	{Instruction{XORQ, R9.Addr(), R9.Addr()}, "{R9 FLAGS}", "{}"},
+	{Instruction{SUBL, CX.Addr(), CX.Addr()}, "{CX FLAGS}", "{}"},
+	{Instruction{SUBQ, CX.Addr(), CX.Addr()}, "{CX FLAGS}", "{}"},
+	{Instruction{XORQ, BX.Addr(), AX.Addr()}, "{AX FLAGS}", "{AX BX}"},
+	{Instruction{PXOR, X3.Addr(), X3.Addr()}, "{X3}", "{}"},
+	{Instruction{PXOR, X2.Addr(), X3.Addr()}, "{X3}", "{X2 X3}"},
+	{Instruction{VPXOR, Args(Y2.Addr(), Y2.Addr()), Y1.Addr()}, "{X1}", "{}"},
+	{Instruction{VPXOR, Args(Y2.Addr(), Y3.Addr()), Y1.Addr()}, "{X1}", "{X2 X3}"},
+	{Instruction{VPXORD, Args(Z2.Addr(), Z2.Addr(), K1.Addr()), Z1.Addr()}, "{X1}", "{X1 K1}"},
+}
+
+func TestDefUse(t *testing.T) {
+	for _, test := range defUseTests {
+		def, use, err := DefUse(test.in)
+		if err != nil {
+			t.Errorf("%v: %v", test.in, err)
+			continue
+		}
+		if def.String() != test.def || use.String() != test.use {
+			t.Errorf("%v: def=%v use=%v, want def=%s use=%s", test.in, def, use, test.def, test.use)
+		}
+	}
+}
+
+func TestDefUseError(t *testing.T) {
+	if _, _, err := DefUse(Instruction{MOVQ, X1.Addr(), Y1.Addr()}); err == nil {
+		t.Error("no error for an unknown combination")
+	}
+}
+
+func TestRegSet(t *testing.T) {
+	s := Of(R15, AX, Y3, X3, K7, Z31) | Flags
+	if got, want := s.String(), "{AX R15 X3 Z31 K7 FLAGS}"; got != want {
+		t.Errorf("String()=%s, want %s", got, want)
+	}
+	if !s.Has(Z3) || !s.Has(AX) || s.Has(BX) || s.Has(K1) {
+		t.Errorf("Has wrong for %v", s)
+	}
+}
+
+func TestLive(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(0), AX.Addr()}, // 0
+		{Op: LABEL, From: LabelAddr("loop")},
+		{ADDQ, SI.Ind(0), AX.Addr()}, // 2
+		{ADDQ, Imm(8), SI.Addr()},
+		{SUBQ, Imm(1), CX.Addr()},
+		{Op: JNE, To: LabelAddr("loop")}, // 5
+		{MOVQ, AX.Addr(), BX.Addr()},
+		{Op: RET}, // 7
+	}
+	l, err := Live(p, Of(AX, SP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ in, out string }{
+		{"{CX SP SI}", "{AX CX SP SI}"},
+		{"{AX CX SP SI}", "{AX CX SP SI}"},
+		{"{AX CX SP SI}", "{AX CX SP SI}"},
+		{"{AX CX SP SI}", "{AX CX SP SI}"},
+		{"{AX CX SP SI}", "{AX CX SP SI FLAGS}"},
+		{"{AX CX SP SI FLAGS}", "{AX CX SP SI}"},
+		{"{AX SP}", "{AX SP}"},
+		{"{AX SP}", "{AX SP}"},
+	}
+	for i, w := range want {
+		if in, out := l.In[i].String(), l.Out[i].String(); in != w.in || out != w.out {
+			t.Errorf("ins %d %v: in=%s out=%s, want in=%s out=%s", i, p[i], in, out, w.in, w.out)
+		}
+	}
+	if got := l.BlockIn[1].String(); got != "{AX CX SP SI}" {
+		t.Errorf("loop block in=%s", got)
+	}
+
+	// A write of a register not live after it is dead.
+	var dead []int
+	for i := range p {
+		if l.Def[i]&^Flags != 0 && l.Def[i]&l.Out[i] == 0 {
+			dead = append(dead, i)
+		}
+	}
+	if len(dead) != 1 || dead[0] != 6 {
+		t.Errorf("dead writes at %v, want [6]", dead)
+	}
+}
+
+func TestCallerSaved(t *testing.T) {
+	want := Of(AX, CX, DX, SI, DI, R8, R9, R10, R11)
+	for r := X0; r <= X15; r++ {
+		want |= Of(r)
+	}
+	for r := Z16; r <= Z31; r++ {
+		want |= Of(r)
+	}
+	for r := K0; r <= K7; r++ {
+		want |= Of(r)
+	}
+	if CallerSaved != want {
+		t.Errorf("CallerSaved=%v, want %v", CallerSaved, want)
+	}
+}
+
+func TestLiveCall(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(1), R11.Addr()}, // 0
+		{MOVQ, Imm(2), BX.Addr()},
+		{MOVQ, Imm(3), DI.Addr()},
+		{Op: CALL, To: LabelAddr("ext")}, // 3
+		{MOVQ, R11.Addr(), AX.Addr()},
+		{ADDQ, BX.Addr(), AX.Addr()},
+		{Op: CALL, To: LabelAddr("f")}, // 6
+		{Op: RET},
+		{Op: LABEL, From: LabelAddr("f")}, // 8
+		{ADDQ, DI.Addr(), AX.Addr()},
+		{Op: RET},
+	}
+	l, err := Live(p, Of(AX, SP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The call out of the program clobbers R11 and DI, not BX.
+	if out := l.Out[0]; out.Has(R11) {
+		t.Errorf("R11 live across CALL ext: out=%v", out)
+	}
+	if in := l.In[3]; in.Has(R11) || in.Has(DI) || !in.Has(BX) {
+		t.Errorf("in at CALL ext=%v, want BX without R11 or DI", in)
+	}
+	if in := l.In[4]; !in.Has(R11) {
+		t.Errorf("R11 not live after CALL ext: in=%v", in)
+	}
+	// The call of f in the program keeps the registers f uses live.
+	if in := l.In[6]; !in.Has(DI) || !in.Has(AX) {
+		t.Errorf("in at CALL f=%v, want AX and DI", in)
+	}
+	if def := l.Def[6]; def != Of(SP)|Flags {
+		t.Errorf("def of CALL f=%v, want {SP FLAGS}", def)
+	}
+}
+
+func TestLiveZeroMove(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(0), AX.Addr()},
+		{Op: RET},
+	}.Optimize([]Rule{ZeroMove})
+	if p[0].Op != XORL {
+		t.Fatalf("ZeroMove did not apply: %v", p)
+	}
+	l, err := Live(p, Of(AX, SP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in := l.In[0]; in.Has(AX) {
+		t.Errorf("AX live into %v: in=%v", p[0], in)
+	}
+}
+
+func TestLiveError(t *testing.T) {
+	p := Program{
+		Pos{File: "f.s", Line: 3}.Instruction(),
+		{MOVQ, X1.Addr(), Y1.Addr()},
+	}
+	_, err := Live(p, 0)
+	if err == nil || !strings.HasPrefix(err.Error(), "analysis: f.s:3: ins 1: ") {
+		t.Errorf("err=%v", err)
+	}
+}
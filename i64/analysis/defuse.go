@@ -0,0 +1,64 @@
+package analysis
+
+import "github.com/crawshaw/asm/i64"
+
+// DefUse returns the registers the instruction writes (def) and reads
+// (use), including the registers it uses implicitly, such as the AX and
+// DX of IDIVQ, and the status flags. The base register of a memory
+// operand is used. Memory is not tracked.
+//
+// How each operand is used is taken from the opcode table, see
+// i64.Instruction.Form: the destination of MOVQ is written, that of
+// ADDQ is read and written, that of CMPQ is only read. An instruction
+// writing part of a register, such as MOVB, SETcc or CMOVQNE, reads it
+// too.
+//
+// A jump or CALL uses nothing for its target. A RET does not use the
+// registers of the function returned to. A CALL defines the flags and
+// is taken to leave the program for a function following the System V
+// AMD64 ABI, so it also defines the CallerSaved registers. Live instead
+// follows a CALL of a label in the Program.
+func DefUse(in i64.Instruction) (def, use RegSet, err error) {
+	form, err := in.Form()
+	if err != nil {
+		return 0, 0, err
+	}
+	ops := in.Operands()
+	for i, acc := range form.Access {
+		r := operand(ops[i])
+		switch {
+		case ops[i].Type == i64.Ind:
+			use |= r
+		default:
+			if acc&i64.AccessRead != 0 {
+				use |= r
+			}
+			if acc&i64.AccessWrite != 0 {
+				def |= r
+			}
+		}
+	}
+	use |= Of(form.ImplicitUse...)
+	def |= Of(form.ImplicitDef...)
+	if form.Flags&i64.AccessRead != 0 {
+		use |= Flags
+	}
+	if form.Flags&i64.AccessWrite != 0 {
+		def |= Flags
+	}
+	if in.Op.Base() == i64.CALL {
+		def |= CallerSaved | Flags
+	}
+	return def, use, nil
+}
+
+// operand returns the registers named by a register or memory operand.
+func operand(a i64.Addr) RegSet {
+	switch a.Type {
+	case i64.Reg, i64.Ind, i64.Xmm, i64.Ymm, i64.Zmm, i64.Kreg:
+		if r, ok := a.Value.(i64.Register); ok {
+			return bit(r)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package analysis
+
+import (
+	"fmt"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+// Liveness holds the registers live at each instruction of a Program,
+// those holding a value that may be read before it is next written.
+// Each slice indexed by instruction has an element for every
+// instruction in the Program, including LABEL and POS.
+type Liveness struct {
+	CFG *i64.CFG
+
+	Def, Use []RegSet // registers defined and used by each instruction
+	In, Out  []RegSet // registers live before and after each instruction
+
+	BlockIn, BlockOut []RegSet // registers live at entry to and exit from each block
+}
+
+// Live computes the liveness of the registers of p. exit is the set of
+// registers live when control leaves p, by RET, an indirect jump or
+// running off its end, such as those holding results and the
+// callee-saved registers.
+//
+// A block ending in a CALL of a label in p has as its live-out set the
+// registers live at the label and after the CALL, and the CALL defines
+// only SP and the flags. A CALL out of the program, or through a
+// register or memory, defines the CallerSaved registers, see DefUse.
+func Live(p i64.Program, exit RegSet) (*Liveness, error) {
+	g, err := p.CFG()
+	if err != nil {
+		return nil, err
+	}
+	l := &Liveness{
+		CFG:      g,
+		Def:      make([]RegSet, len(p)),
+		Use:      make([]RegSet, len(p)),
+		In:       make([]RegSet, len(p)),
+		Out:      make([]RegSet, len(p)),
+		BlockIn:  make([]RegSet, len(g.Blocks)),
+		BlockOut: make([]RegSet, len(g.Blocks)),
+	}
+	labels := make(map[string]bool)
+	for _, in := range p {
+		if in.Op == i64.LABEL {
+			labels[in.From.Name] = true
+		}
+	}
+	var positions []i64.Pos
+	for i := range p {
+		def, use, err := DefUse(p[i])
+		if err != nil {
+			if positions == nil {
+				positions = p.Positions()
+			}
+			if pos := positions[i]; pos.IsValid() {
+				return nil, fmt.Errorf("analysis: %v: ins %d: %v", pos, i, err)
+			}
+			return nil, fmt.Errorf("analysis: ins %d: %v", i, err)
+		}
+		if in := p[i]; in.Op.Base() == i64.CALL && in.To.Type == i64.Label && labels[in.To.Name] {
+			// The registers the function defines are found by
+			// following the CFG to the label.
+			def &^= CallerSaved
+		}
+		l.Def[i], l.Use[i] = def, use
+	}
+
+	// gen holds the registers each block uses before defining them,
+	// kill those it defines.
+	gen := make([]RegSet, len(g.Blocks))
+	kill := make([]RegSet, len(g.Blocks))
+	for _, b := range g.Blocks {
+		for i := b.End - 1; i >= b.Start; i-- {
+			gen[b.Index] = gen[b.Index]&^l.Def[i] | l.Use[i]
+			kill[b.Index] |= l.Def[i]
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for j := len(g.Blocks) - 1; j >= 0; j-- {
+			b := g.Blocks[j]
+			out := exit
+			if len(b.Succs) > 0 {
+				out = 0
+				for _, s := range b.Succs {
+					out |= l.BlockIn[s.Index]
+				}
+			}
+			in := gen[j] | out&^kill[j]
+			if in != l.BlockIn[j] || out != l.BlockOut[j] {
+				l.BlockIn[j], l.BlockOut[j] = in, out
+				changed = true
+			}
+		}
+	}
+
+	for _, b := range g.Blocks {
+		live := l.BlockOut[b.Index]
+		for i := b.End - 1; i >= b.Start; i-- {
+			l.Out[i] = live
+			live = live&^l.Def[i] | l.Use[i]
+			l.In[i] = live
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,107 @@
+// Package analysis computes facts about i64 Programs: the registers each
+// instruction defines and uses, and which registers are live at each
+// instruction, for building linters and register allocators.
+//
+// A Program is split into basic blocks by i64.Program.CFG.
+package analysis
+
+import (
+	"strings"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+// A RegSet is a set of registers and the status flags.
+//
+// An XMM, YMM and ZMM register of the same number are one register, as
+// they overlap. The parts of a vector register an instruction leaves
+// as they were are not tracked, so a write of X1 defines Z1.
+type RegSet uint64
+
+// Flags is the set holding the status flags, written by instructions
+// such as CMPQ and read by conditional jumps. The direction flag set by
+// CLD and STD is not tracked.
+const Flags RegSet = 1 << 63
+
+const (
+	gprBit    = 0  // AX-R15
+	vectorBit = 16 // X0-X15, Y0-Y15, Z0-Z31
+	maskBit   = 48 // K0-K7
+)
+
+// CallerSaved is the set of registers a function may change without
+// restoring them under the System V AMD64 ABI: AX, CX, DX, SI, DI,
+// R8-R11 and all the vector and opmask registers.
+const CallerSaved RegSet = (1<<0|1<<1|1<<2|1<<6|1<<7|0xf<<8)<<gprBit |
+	(1<<32-1)<<vectorBit | 0xff<<maskBit
+
+// Of returns the set of the registers regs.
+func Of(regs ...i64.Register) RegSet {
+	var s RegSet
+	for _, r := range regs {
+		s |= bit(r)
+	}
+	return s
+}
+
+func bit(r i64.Register) RegSet {
+	switch {
+	case r >= i64.AX && r <= i64.R15:
+		return 1 << (gprBit + uint(r-i64.AX))
+	case r >= i64.X0 && r <= i64.X15:
+		return 1 << (vectorBit + uint(r-i64.X0))
+	case r >= i64.Y0 && r <= i64.Y15:
+		return 1 << (vectorBit + uint(r-i64.Y0))
+	case r >= i64.Z0 && r <= i64.Z31:
+		return 1 << (vectorBit + uint(r-i64.Z0))
+	case r >= i64.K0 && r <= i64.K7:
+		return 1 << (maskBit + uint(r-i64.K0))
+	}
+	return 0
+}
+
+// Has reports whether s holds register r.
+func (s RegSet) Has(r i64.Register) bool {
+	b := bit(r)
+	return b != 0 && s&b != 0
+}
+
+// Regs returns the registers in s, in the order AX-R15, vector
+// registers, K0-K7. A vector register is named X0-X15 or Z16-Z31.
+// The status flags are not a register, test for them with s&Flags.
+func (s RegSet) Regs() []i64.Register {
+	var regs []i64.Register
+	for i := uint(0); i < 16; i++ {
+		if s&(1<<(gprBit+i)) != 0 {
+			regs = append(regs, i64.AX+i64.Register(i))
+		}
+	}
+	for i := uint(0); i < 32; i++ {
+		if s&(1<<(vectorBit+i)) == 0 {
+			continue
+		}
+		if i < 16 {
+			regs = append(regs, i64.X0+i64.Register(i))
+		} else {
+			regs = append(regs, i64.Z0+i64.Register(i))
+		}
+	}
+	for i := uint(0); i < 8; i++ {
+		if s&(1<<(maskBit+i)) != 0 {
+			regs = append(regs, i64.K0+i64.Register(i))
+		}
+	}
+	return regs
+}
+
+// String returns the registers of s, such as "{AX CX FLAGS}".
+func (s RegSet) String() string {
+	var names []string
+	for _, r := range s.Regs() {
+		names = append(names, r.String())
+	}
+	if s&Flags != 0 {
+		names = append(names, "FLAGS")
+	}
+	return "{" + strings.Join(names, " ") + "}"
+}
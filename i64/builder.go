@@ -0,0 +1,136 @@
+package i64
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// A Builder appends instructions to a Program.
+//
+// Each instruction is checked against the opcode table as it is
+// appended, and errors report the Go source position of the call that
+// appended it. After the first error the Builder ignores further calls
+// and Program returns the error.
+//
+// Builder has a method for each Op, generated into zbuilder.go:
+//
+//	b.MOVQ(Imm(uint32(10)), CX.Addr())
+//	b.Label("loop")
+//	b.SUBQ(Imm(uint8(1)), CX.Addr())
+//	b.JNE("loop")
+//
+// Ops with more than two operands take them variadically, sources first
+// and the destination last. Jumps and calls take a label. Prefixed ops,
+// such as LOCK|XADDQ, and indirect jumps are appended with Add.
+type Builder struct {
+	// Positions, if set, makes the Builder record the position of each
+	// call in POS instructions, so that errors, listings and debug
+	// information about the Program refer to the Go source. It changes
+	// the indexes of the instructions in the Program.
+	Positions bool
+
+	prog   Program
+	pos    []Pos
+	last   Pos // of the last POS instruction
+	labels map[string]int
+	err    error
+}
+
+// Add appends the instruction {op, from, to}.
+func (b *Builder) Add(op Op, from, to Addr) { b.add(op, from, to) }
+
+// Label defines a label at the current end of the program.
+func (b *Builder) Label(name string) { b.label(name) }
+
+func (b *Builder) label(name string) {
+	pos := caller()
+	if b.err != nil {
+		return
+	}
+	if i, ok := b.labels[name]; ok {
+		b.err = fmt.Errorf("%v: label %q previously defined at %v", pos, name, b.pos[i])
+		return
+	}
+	if b.labels == nil {
+		b.labels = make(map[string]int)
+	}
+	b.labels[name] = len(b.prog)
+	b.prog = append(b.prog, Instruction{Op: LABEL, From: LabelAddr(name)})
+	b.pos = append(b.pos, pos)
+}
+
+// Err returns the first error encountered by b.
+func (b *Builder) Err() error { return b.err }
+
+// Program returns the instructions appended to b. It reports an error
+// if any instruction was invalid or jumps to an undefined label.
+func (b *Builder) Program() (Program, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	for i, in := range b.prog {
+		if in.To.Type != Label {
+			continue
+		}
+		if _, ok := b.labels[in.To.Name]; !ok {
+			return nil, fmt.Errorf("%v: %v: undefined label %q", b.pos[i], in.Op, in.To.Name)
+		}
+	}
+	return b.prog, nil
+}
+
+func (b *Builder) append(in Instruction, pos Pos) {
+	if b.err != nil {
+		return
+	}
+	if in.Op == LABEL {
+		b.err = fmt.Errorf("%v: use Label to define a label", pos)
+		return
+	}
+	if in.To.Type != Label {
+		var c ins
+		if err := c.make(&in); err != nil {
+			b.err = fmt.Errorf("%v: %v: %v", pos, in, err)
+			return
+		}
+	}
+	if b.Positions && pos != b.last {
+		b.prog = append(b.prog, pos.Instruction())
+		b.pos = append(b.pos, pos)
+		b.last = pos
+	}
+	b.prog = append(b.prog, in)
+	b.pos = append(b.pos, pos)
+}
+
+func (b *Builder) add(op Op, from, to Addr) {
+	b.append(Instruction{op, from, to}, caller())
+}
+
+// jump appends a jump or call to a label.
+func (b *Builder) jump(op Op, label string) {
+	b.append(Instruction{Op: op, To: LabelAddr(label)}, caller())
+}
+
+// addOps appends op with args, sources first and the destination last.
+func (b *Builder) addOps(op Op, args []Addr) {
+	var in Instruction
+	in.Op = op
+	switch n := len(args); n {
+	case 0:
+	case 1:
+		in.To = args[0]
+	case 2:
+		in.From, in.To = args[0], args[1]
+	default:
+		in.From, in.To = Args(args[:n-1]...), args[n-1]
+	}
+	b.append(in, caller())
+}
+
+// caller returns the position of the call to the exported Builder
+// method that called the helper calling caller.
+func caller() Pos {
+	_, file, line, _ := runtime.Caller(3)
+	return Pos{File: file, Line: line}
+}
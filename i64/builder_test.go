@@ -0,0 +1,183 @@
+package i64
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite zbuilder.go")
+
+// TestZBuilder checks that zbuilder.go has a Builder method for each Op
+// in the optab. Run go test -run TestZBuilder -update to regenerate it.
+func TestZBuilder(t *testing.T) {
+	const (
+		none = 1 << iota
+		src
+		dst
+		two
+		list
+		jump
+	)
+	forms := make(map[Op]int)
+	for k := range optab {
+		switch {
+		case k.To == Rel8 || k.To == Rel32:
+			forms[k.Op] |= jump
+		case k.From == List:
+			forms[k.Op] |= list
+		case k.From == None && k.To == None:
+			forms[k.Op] |= none
+		case k.To == None:
+			forms[k.Op] |= src
+		case k.From == None:
+			forms[k.Op] |= dst
+		default:
+			forms[k.Op] |= two
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	buf.WriteString("// Code generated by go test -run TestZBuilder -update. DO NOT EDIT.\n\npackage i64\n")
+	for op := LABEL + 1; op < lastOp; op++ {
+		form, ok := forms[op]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(buf, "\n// %v appends a %v instruction.\n", op, op)
+		switch {
+		case form&jump != 0:
+			fmt.Fprintf(buf, "func (b *Builder) %v(label string) { b.jump(%v, label) }\n", op, op)
+		case form == none:
+			fmt.Fprintf(buf, "func (b *Builder) %v() { b.add(%v, Addr{}, Addr{}) }\n", op, op)
+		case form == src:
+			fmt.Fprintf(buf, "func (b *Builder) %v(src Addr) { b.add(%v, src, Addr{}) }\n", op, op)
+		case form == dst:
+			fmt.Fprintf(buf, "func (b *Builder) %v(dst Addr) { b.add(%v, Addr{}, dst) }\n", op, op)
+		case form == two:
+			fmt.Fprintf(buf, "func (b *Builder) %v(src, dst Addr) { b.add(%v, src, dst) }\n", op, op)
+		default:
+			fmt.Fprintf(buf, "func (b *Builder) %v(args ...Addr) { b.addOps(%v, args) }\n", op, op)
+		}
+	}
+	want, err := format.Source(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := ioutil.WriteFile("zbuilder.go", want, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	got, err := ioutil.ReadFile("zbuilder.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("zbuilder.go is out of date, run go test -run TestZBuilder -update")
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	var b Builder
+	b.MOVQ(Imm(uint32(10)), CX.Addr())
+	b.XORQ(AX.Addr(), AX.Addr())
+	b.Label("loop")
+	b.ADDQ(CX.Addr(), AX.Addr())
+	b.SUBQ(Imm(uint8(1)), CX.Addr())
+	b.JNE("loop")
+	b.VPADDD(Z1.Addr(), Z2.Addr(), Z3.Addr())
+	b.Add(LOCK|XADDQ, AX.Addr(), BX.Ind(0))
+	b.RET()
+	got, err := b.Program()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Program{
+		{MOVQ, Imm(uint32(10)), CX.Addr()},
+		{XORQ, AX.Addr(), AX.Addr()},
+		{Op: LABEL, From: LabelAddr("loop")},
+		{ADDQ, CX.Addr(), AX.Addr()},
+		{SUBQ, Imm(uint8(1)), CX.Addr()},
+		{Op: JNE, To: LabelAddr("loop")},
+		{VPADDD, Args(Z1.Addr(), Z2.Addr()), Z3.Addr()},
+		{LOCK | XADDQ, AX.Addr(), BX.Ind(0)},
+		{Op: RET},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Program()=%v, want %v", got, want)
+	}
+	if _, err := got.Bytes(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	for _, test := range []struct {
+		build func(b *Builder)
+		want  string
+	}{
+		{func(b *Builder) { b.MOVQ(X1.Addr(), Imm(uint32(1))) }, "MOVQ"},
+		{func(b *Builder) { b.VPADDD(Z1.Addr(), Z2.Addr()) }, "VPADDD"},
+		{func(b *Builder) { b.JMP("nowhere") }, `undefined label "nowhere"`},
+		{func(b *Builder) { b.Label("x"); b.Label("x") }, `label "x" previously defined at`},
+	} {
+		var b Builder
+		test.build(&b)
+		_, err := b.Program()
+		if err == nil {
+			t.Errorf("%s: no error", test.want)
+			continue
+		}
+		// The error begins with the position of the call in this file.
+		if !strings.Contains(err.Error(), "builder_test.go:") || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("error %q does not report builder_test.go and %q", err, test.want)
+		}
+	}
+
+	// Calls after an error are ignored.
+	var b Builder
+	b.MOVQ(X1.Addr(), Imm(uint32(1)))
+	b.RET()
+	if b.Err() == nil || len(b.prog) != 0 {
+		t.Errorf("Err()=%v, len(prog)=%d, want error and 0", b.Err(), len(b.prog))
+	}
+}
+
+func TestBuilderPositions(t *testing.T) {
+	b := Builder{Positions: true}
+	b.MOVQ(Imm(uint32(10)), CX.Addr())
+	b.Label("loop")
+	for i := 0; i < 2; i++ {
+		b.SUBQ(Imm(uint8(1)), CX.Addr())
+	}
+	b.JNE("loop")
+	p, err := b.Program()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ops []Op
+	var lines []int
+	for i, pos := range p.Positions() {
+		ops = append(ops, p[i].Op)
+		if p[i].Op == POS {
+			if filepath.Base(pos.File) != "builder_test.go" {
+				t.Errorf("ins %d: position %v, want builder_test.go", i, pos)
+			}
+			lines = append(lines, pos.Line)
+		}
+	}
+	if want := []Op{POS, MOVQ, LABEL, POS, SUBQ, SUBQ, POS, JNE}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("ops %v, want %v", ops, want)
+	}
+	if len(lines) != 3 || lines[1] != lines[0]+3 || lines[2] != lines[1]+2 {
+		t.Errorf("lines %v, want the lines of the MOVQ, SUBQ and JNE calls", lines)
+	}
+}
@@ -0,0 +1,162 @@
+package i64
+
+// A CFG is the control flow graph of a Program.
+type CFG struct {
+	Blocks []*Block // in program order, the entry block first
+}
+
+// A Block is a basic block: a run of instructions, entered only at the
+// first and left only after the last. A block begins at the start of
+// the program, at a LABEL, or after a jump, CALL of a label, or RET.
+type Block struct {
+	Index int // index in CFG.Blocks
+	Start int // index of the first instruction in the Program
+	End   int // index after the last instruction
+
+	Succs []*Block // in order: jump or call target, then fall through
+	Preds []*Block // in program order
+}
+
+// CFG returns the control flow graph of p.
+//
+// A CALL of a label in p has both the label and the instruction after
+// it as successors, as the call returns there. An indirect jump, and a
+// CALL or JMP out of the program, leaves the graph.
+func (p Program) CFG() (*CFG, error) {
+	labels := labelIndex(p)
+	g := new(CFG)
+	block := make([]*Block, len(p)) // block of each instruction
+	var b *Block
+	code := false // b has instructions other than LABEL and POS
+	for i := range p {
+		if b == nil || p[i].Op == LABEL && code {
+			b = &Block{Index: len(g.Blocks), Start: i}
+			g.Blocks = append(g.Blocks, b)
+			code = false
+		}
+		block[i] = b
+		b.End = i + 1
+		if in := &p[i]; in.Op != LABEL && in.Op != POS {
+			code = true
+			if in.To.Type == Label {
+				if _, ok := labels[in.To.Name]; !ok && in.Op.Base() != CALL {
+					return nil, p.errorf(i, "undefined label %q", in.To.Name)
+				}
+			}
+			if endsBlock(in, labels) {
+				b = nil
+			}
+		}
+	}
+
+	for _, b := range g.Blocks {
+		in := &p[b.End-1]
+		fallsThrough := true
+		switch op := in.Op.Base(); {
+		case op == RET:
+			fallsThrough = false
+		case op == JMP:
+			fallsThrough = false
+			if j, ok := labels[in.To.Name]; ok && in.To.Type == Label {
+				g.addEdge(b, block[j])
+			}
+		case endsBlock(in, labels):
+			g.addEdge(b, block[labels[in.To.Name]])
+		}
+		if fallsThrough && b.Index+1 < len(g.Blocks) {
+			g.addEdge(b, g.Blocks[b.Index+1])
+		}
+	}
+	return g, nil
+}
+
+// endsBlock reports whether in transfers control, so that the
+// instruction after it begins a new block.
+func endsBlock(in *Instruction, labels map[string]int) bool {
+	switch op := in.Op.Base(); {
+	case op == RET, op == JMP:
+		return true
+	case op >= JO && op <= JG, op == CALL:
+		_, ok := labels[in.To.Name]
+		return ok && in.To.Type == Label
+	}
+	return false
+}
+
+func (g *CFG) addEdge(from, to *Block) {
+	for _, s := range from.Succs {
+		if s == to {
+			return
+		}
+	}
+	from.Succs = append(from.Succs, to)
+	to.Preds = append(to.Preds, from)
+}
+
+// Reachable reports, for each block, whether it is reachable from the
+// entry block.
+func (g *CFG) Reachable() []bool {
+	reached := make([]bool, len(g.Blocks))
+	if len(g.Blocks) == 0 {
+		return reached
+	}
+	stack := []*Block{g.Blocks[0]}
+	reached[0] = true
+	for len(stack) > 0 {
+		b := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, s := range b.Succs {
+			if !reached[s.Index] {
+				reached[s.Index] = true
+				stack = append(stack, s)
+			}
+		}
+	}
+	return reached
+}
+
+// RemoveDeadCode returns p without the blocks unreachable from its
+// entry, and without labels that no remaining instruction refers to.
+// Removed code does not change the source positions of the remaining
+// instructions. p is not modified.
+//
+// Labels are removed even if they are only used to name code, as by
+// Labels or the symbols of package jit.
+func (p Program) RemoveDeadCode() (Program, error) {
+	g, err := p.CFG()
+	if err != nil {
+		return nil, err
+	}
+	reached := g.Reachable()
+	used := make(map[string]bool)
+	for _, b := range g.Blocks {
+		if !reached[b.Index] {
+			continue
+		}
+		for i := b.Start; i < b.End; i++ {
+			if p[i].To.Type == Label {
+				used[p[i].To.Name] = true
+			}
+		}
+	}
+
+	var q Program
+	var dropped *Instruction // last POS removed since the last kept instruction
+	for _, b := range g.Blocks {
+		for i := b.Start; i < b.End; i++ {
+			in := &p[i]
+			if !reached[b.Index] || in.Op == LABEL && !used[in.From.Name] {
+				if in.Op == POS {
+					dropped = in
+				}
+				continue
+			}
+			if dropped != nil && in.Op != POS {
+				q = append(q, *dropped)
+			}
+			dropped = nil
+			q = append(q, *in)
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,84 @@
+package i64
+
+import "fmt"
+
+// Cond is a condition code, as tested by the conditional jump, SETcc and
+// CMOVcc instructions. The value of a Cond is the 4-bit code the amd64
+// manual embeds in the low bits of those opcodes.
+type Cond int
+
+const (
+	CondO  Cond = iota // overflow
+	CondNO             // not overflow
+	CondB              // below, unsigned <, carry set
+	CondAE             // above or equal, unsigned >=, carry clear
+	CondE              // equal, zero
+	CondNE             // not equal, not zero
+	CondBE             // below or equal, unsigned <=
+	CondA              // above, unsigned >
+	CondS              // sign
+	CondNS             // not sign
+	CondP              // parity even
+	CondNP             // parity odd
+	CondL              // less, signed <
+	CondGE             // greater or equal, signed >=
+	CondLE             // less or equal, signed <=
+	CondG              // greater, signed >
+)
+
+// Not returns the inverse condition.
+func (c Cond) Not() Cond { return c ^ 1 }
+
+// Jump returns the conditional jump Op for c.
+func (c Cond) Jump() Op { return JO + Op(c) }
+
+// Set returns the SETcc Op for c.
+func (c Cond) Set() Op { return SETO + Op(c) }
+
+// CMOVL returns the 32-bit CMOVcc Op for c.
+func (c Cond) CMOVL() Op { return CMOVLOS + Op(c) }
+
+// CMOVQ returns the 64-bit CMOVcc Op for c.
+func (c Cond) CMOVQ() Op { return CMOVQOS + Op(c) }
+
+// Cond returns the condition code tested by op, if op is a conditional
+// jump, SETcc or CMOVcc.
+func (op Op) Cond() (c Cond, ok bool) {
+	switch op = op.Base(); {
+	case op >= JO && op <= JG:
+		return Cond(op - JO), true
+	case op >= SETO && op <= SETG:
+		return Cond(op - SETO), true
+	case op >= CMOVLOS && op <= CMOVLGT:
+		return Cond(op - CMOVLOS), true
+	case op >= CMOVQOS && op <= CMOVQGT:
+		return Cond(op - CMOVQOS), true
+	}
+	return 0, false
+}
+
+var condName = [...]string{
+	CondO:  "O",
+	CondNO: "NO",
+	CondB:  "B",
+	CondAE: "AE",
+	CondE:  "E",
+	CondNE: "NE",
+	CondBE: "BE",
+	CondA:  "A",
+	CondS:  "S",
+	CondNS: "NS",
+	CondP:  "P",
+	CondNP: "NP",
+	CondL:  "L",
+	CondGE: "GE",
+	CondLE: "LE",
+	CondG:  "G",
+}
+
+func (c Cond) String() string {
+	if c < 0 || int(c) >= len(condName) {
+		return fmt.Sprintf("Cond(%d)", int(c))
+	}
+	return condName[c]
+}
@@ -0,0 +1,143 @@
+package i64
+
+import "fmt"
+
+// AVX-512 instruction modifiers. Like prefixes, a modifier is added to an
+// instruction by ORing it into the Op. They correspond to the Go assembler's
+// .Z and .BCST suffixes:
+//
+//	Instruction{VPADDD | ZERO, Args(Z1.Addr(), Z2.Addr(), K1.Addr()), Z3.Addr()}
+//	Instruction{VADDPS | BCST, Args(AX.Ind(0), Z1.Addr()), Z2.Addr()}
+const (
+	ZERO Op = SEGGS << (1 + iota) // zero, rather than merge, elements masked off
+	BCST                          // broadcast one memory element to all elements
+
+	evexMods = ZERO | BCST
+)
+
+var modifiers = []struct {
+	op   Op
+	name string
+}{
+	{BCST, ".BCST"},
+	{ZERO, ".Z"},
+}
+
+// makeMask looks up the optab entry of the instruction.
+//
+// An EVEX instruction may list an opmask register as the last source
+// operand, in which case only the elements selected by the mask are
+// written. makeMask removes it and returns the remaining source operand.
+func (c *ins) makeMask() (v opVal, from Addr, err error) {
+	p := c.ins
+	key, err := makeKey(p.Op, p.From, p.To)
+	if err != nil {
+		return v, p.From, err
+	}
+	v, ok := optab[key]
+	from = p.From
+	if list, isList := from.Value.([]Addr); from.Type == List && isList && (!ok || v.evex) {
+		if n := len(list); n > 1 && list[n-1].Type == Kreg {
+			k := list[n-1].Value.(Register)
+			if list = list[:n-1]; len(list) == 1 {
+				from = list[0]
+			} else {
+				from = Args(list...)
+			}
+			if key, err = makeKey(p.Op, from, p.To); err != nil {
+				return v, from, err
+			}
+			v, ok = optab[key]
+			if ok && !v.evex {
+				return v, from, fmt.Errorf("%v: opmask requires an EVEX instruction", p.Op)
+			}
+			if k == K0 {
+				return v, from, fmt.Errorf("K0 cannot be used as a write mask")
+			}
+			c.evexA = k.num()
+		}
+	}
+	if !ok {
+		return v, from, fmt.Errorf("unknown combination: %v", key)
+	}
+	return v, from, nil
+}
+
+// makeEVEX checks the AVX-512 modifiers of the instruction.
+func (c *ins) makeEVEX(v opVal) error {
+	mods := c.ins.Op.Prefix() & evexMods
+	if !v.evex {
+		if mods != 0 {
+			return fmt.Errorf("%v: modifier requires an EVEX instruction", c.ins.Op)
+		}
+		return nil
+	}
+	c.evex = true
+	c.vexL = 2
+	c.evexN = v.evexN
+	if mods&ZERO != 0 {
+		if c.evexA == 0 {
+			return fmt.Errorf("zeroing-masking requires an opmask")
+		}
+		if t := c.ins.To.Type; t == Ind || t == Kreg {
+			return fmt.Errorf("zeroing-masking not permitted with %v destination", t)
+		}
+		c.evexZ = true
+	}
+	if mods&BCST != 0 {
+		if v.bcst == 0 {
+			return fmt.Errorf("%v: broadcast not permitted", c.ins.Op.Base())
+		}
+		c.evexB = true
+		c.evexN = v.bcst
+	}
+	return nil
+}
+
+// appendEVEX appends an EVEX prefix and the final opcode byte to buf.
+//
+// EVEX extends VEX with a fifth register bit for each operand (EVEX.R',
+// EVEX.V', and EVEX.X for a direct ModRM.rm), a 512-bit vector length,
+// the opmask register (EVEX.aaa), zeroing-masking (EVEX.z), and
+// broadcast (EVEX.b).
+func (c *ins) appendEVEX(buf []byte) []byte {
+	var pp uint8
+	switch c.c0 {
+	case 0x66:
+		pp = 1
+	case 0xf3:
+		pp = 2
+	case 0xf2:
+		pp = 3
+	}
+	var mm, op uint8
+	switch c.c2 {
+	case 0x38:
+		mm, op = 2, c.c3
+	case 0x3a:
+		mm, op = 3, c.c3
+	default:
+		mm, op = 1, c.c2
+	}
+
+	// EVEX.R, EVEX.X, EVEX.B, EVEX.R', EVEX.vvvv, and EVEX.V' are inverted.
+	p0 := (^c.rex&(rexR|rexX|rexB))<<5 | mm
+	if !c.evexR {
+		p0 |= 0x10
+	}
+	p1 := (^c.vexV&0xf)<<3 | 0x04 | pp
+	if c.rex&rexW != 0 {
+		p1 |= 0x80
+	}
+	p2 := c.vexL<<5 | c.evexA
+	if c.evexZ {
+		p2 |= 0x80
+	}
+	if c.evexB {
+		p2 |= 0x10
+	}
+	if c.vexV&0x10 == 0 {
+		p2 |= 0x08
+	}
+	return append(buf, 0x62, p0, p1, p2, op)
+}
@@ -0,0 +1,39 @@
+package i64
+
+import (
+	"fmt"
+
+	"github.com/crawshaw/asm/cpu"
+)
+
+// Features returns the CPU features required by the encoding of the
+// instruction, beyond the x86-64 baseline. Each optab entry is tagged
+// separately, so VPADDD on Xmm requires AVX while VPADDD on Ymm
+// requires AVX2.
+func (in Instruction) Features() (cpu.Feature, error) {
+	if in.To.Type == Label {
+		// Jumps are baseline, and cannot be laid out without their label.
+		return 0, nil
+	}
+	var c ins
+	if err := c.make(&in); err != nil {
+		return 0, fmt.Errorf("%v: %v", in, err)
+	}
+	return c.feature, nil
+}
+
+// A FeatureError reports that a program uses an instruction requiring
+// CPU features outside its target.
+type FeatureError struct {
+	Index       int         // index of the instruction in the Program, counting LABEL and POS
+	Pos         Pos         // source position of the instruction, if known
+	Instruction Instruction // the offending instruction
+	Missing     cpu.Feature // features required but not in the target
+}
+
+func (e *FeatureError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("i64: %v: ins %d: %v: target does not support %v", e.Pos, e.Index, e.Instruction, e.Missing)
+	}
+	return fmt.Sprintf("i64: ins %d: %v: target does not support %v", e.Index, e.Instruction, e.Missing)
+}
@@ -0,0 +1,146 @@
+package i64
+
+import (
+	"fmt"
+
+	"github.com/crawshaw/asm/cpu"
+)
+
+// A Form describes the encoding an instruction assembles to, and how it
+// uses its operands, as recorded in the opcode table.
+type Form struct {
+	VEX  bool // VEX or EVEX prefix
+	EVEX bool // EVEX prefix
+
+	// NonDestructive is set if the destination is not also a source
+	// of the encoding, as in the VEX three-operand forms. Whether an
+	// instruction in a two-operand form reads its destination depends
+	// on the instruction: ADDQ does, MOVQ does not.
+	NonDestructive bool
+
+	// Merge is set if an opmask selects the destination elements
+	// written, leaving the others as they were.
+	Merge bool
+
+	Feature cpu.Feature // CPU features required, as reported by Features
+
+	// Access holds how the instruction uses each of its operands, in
+	// the order of Instruction.Operands. The access of a memory
+	// operand is to memory, its base register is only read. An
+	// instruction that writes part of a register, such as MOVB, reads
+	// it too. A zeroing idiom, such as XORL AX, AX or VPXOR with the
+	// same two sources, only writes its destination and leaves its
+	// sources with no access.
+	Access []Access
+
+	Flags Access // use of the status flags
+
+	// ImplicitUse and ImplicitDef are the registers read and written
+	// without being named as operands, such as the AX and DX of IDIVQ.
+	ImplicitUse []Register
+	ImplicitDef []Register
+}
+
+// An Access is how an instruction uses an operand or the flags.
+type Access uint8
+
+const (
+	AccessRead  Access = 1 << iota // read
+	AccessWrite                    // written
+
+	AccessReadWrite = AccessRead | AccessWrite
+)
+
+// Form returns the form of the encoding of the instruction.
+func (in Instruction) Form() (Form, error) {
+	if in.Op == LABEL || in.Op == POS {
+		return Form{}, nil
+	}
+	if in.To.Type == Label {
+		// A jump is not laid out without its label, so take its
+		// long form, which has the same use of operands.
+		in.To = Addr{Type: Rel32, Value: int32(0)}
+	}
+	var c ins
+	if err := c.make(&in); err != nil {
+		return Form{}, fmt.Errorf("%v: %v", in, err)
+	}
+	v := &c.val
+	f := Form{
+		VEX:            c.vex,
+		EVEX:           c.evex,
+		NonDestructive: v.layout != argsDefault && v.layout != argsImmRegRM,
+		Merge:          c.evexA != 0 && !c.evexZ && in.To.Type != Kreg,
+		Feature:        c.feature,
+		Flags:          v.flags,
+		ImplicitUse:    append([]Register(nil), v.iuse...),
+		ImplicitDef:    append([]Register(nil), v.idef...),
+	}
+
+	ops := in.Operands()
+	if len(ops) > 0 {
+		f.Access = make([]Access, len(ops))
+	}
+	if v.access != nil {
+		copy(f.Access, v.access)
+	} else {
+		for i := range ops {
+			f.Access[i] = AccessRead
+		}
+		if in.To.Type != None {
+			dst := v.dst
+			if dst == 0 {
+				dst = AccessReadWrite
+				if f.NonDestructive {
+					dst = AccessWrite
+				}
+			}
+			f.Access[len(ops)-1] = dst
+		}
+	}
+	if v.zeroIdiom && sameSources(ops, f.Access) {
+		// The result does not depend on the sources, only on the
+		// opmask.
+		for i := range f.Access {
+			if ops[i].Type != Kreg {
+				f.Access[i] = 0
+			}
+		}
+		f.Access[len(ops)-1] = AccessWrite
+	}
+	if f.Merge {
+		f.Access[len(ops)-1] |= AccessRead
+	}
+
+	if in.Op&(REP|REPE|REPNE) != 0 {
+		f.ImplicitUse = append(f.ImplicitUse, CX)
+		f.ImplicitDef = append(f.ImplicitDef, CX)
+	}
+	if in.Op&(REPE|REPNE) != 0 {
+		// With CX zero, CMPS and SCAS leave the flags as they were.
+		f.Flags |= AccessRead
+	}
+	return f, nil
+}
+
+// sameSources reports whether the operands read, other than an
+// opmask, are one register.
+func sameSources(ops []Addr, access []Access) bool {
+	var src *Addr
+	for i := range ops {
+		if access[i]&AccessRead == 0 || ops[i].Type == Kreg {
+			continue
+		}
+		switch ops[i].Type {
+		case Reg, Xmm, Ymm, Zmm:
+		default:
+			return false
+		}
+		if src == nil {
+			src = &ops[i]
+		} else if ops[i].Value != src.Value {
+			return false
+		}
+	}
+	return src != nil
+}
@@ -1,18 +1,62 @@
 package i64
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
+
+	"github.com/crawshaw/asm/cpu"
 )
 
 // Instruction is an amd64 asssembly instruction.
+//
+// An instruction with more than two operands lists its source operands
+// in From with Args, so it has up to four source operands and a
+// destination:
+//
+//	Instruction{VPBLENDVB, Args(Y4.Addr(), Y3.Addr(), Y2.Addr()), Y1.Addr()}
 type Instruction struct {
 	Op   Op   // instruction opcode
 	From Addr // source address
 	To   Addr // destination address
 }
 
+// Operands returns the operands of the instruction in Go assembler order,
+// with any Args list expanded and the destination last.
+func (p *Instruction) Operands() []Addr {
+	var a []Addr
+	if p.From.Type == List {
+		a = append(a, p.From.Value.([]Addr)...)
+	} else if p.From.Type != None {
+		a = append(a, p.From)
+	}
+	if p.To.Type != None {
+		a = append(a, p.To)
+	}
+	return a
+}
+
+// String returns the instruction in the syntax of PrintText, with jump
+// targets unresolved.
+func (p Instruction) String() string {
+	if p.Op == POS {
+		return fmt.Sprintf("POS %v", p.pos())
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(p.Op.String())
+	for i, a := range p.Operands() {
+		if i == 0 {
+			buf.WriteString(" ")
+		} else {
+			buf.WriteString(",")
+		}
+		a.printText(buf, 0)
+	}
+	return buf.String()
+}
+
 const (
 	rexW = 0x08
 	rexR = 0x04
@@ -29,9 +73,20 @@ type ins struct {
 	codeblockEnd int
 
 	rex       uint8 // just the lower four bits.
+	rexForce  bool  // emit REX even if rex==0, to reach SPL, BPL, SIL, DIL.
 	c0        uint8 // only used if 66, f3, or f2.
 	c1        uint8
 	c2        uint8 // only used if code==0x0f.
+	c3        uint8 // only used if c2==0x38 or c2==0x3a.
+	vex       bool  // VEX prefix replaces REX, c0, c1 and c2.
+	vexV      uint8 // VEX.vvvv register number, 4 bits.
+	vexL      uint8 // VEX.L, 1 for 256-bit vectors, 2 for 512-bit with EVEX.
+	evex      bool  // EVEX prefix replaces REX, c0, c1 and c2.
+	evexR     bool  // EVEX.R', fifth bit of ModRM.reg.
+	evexA     uint8 // EVEX.aaa, opmask register number.
+	evexZ     bool  // EVEX.z, zeroing-masking.
+	evexB     bool  // EVEX.b, broadcast.
+	evexN     uint8 // scale of a compressed 8-bit displacement.
 	modRM     bool
 	modRMmod  uint8 // 2 bits, 11b for direct addressing.
 	modRMreg  uint8 // 3 bits, fourth bit is in REX.R
@@ -44,21 +99,41 @@ type ins struct {
 	disp      uint64
 	immWidth  int // num bytes, 8, 16, 32, 64.
 	imm       uint64
+
+	feature cpu.Feature // CPU features required by the encoding.
+	val     opVal       // the optab entry of the encoding.
 }
 
 func (c *ins) make(p *Instruction) error {
 	c.ins = p
-	if p.Op == LABEL {
+	if p.Op == LABEL || p.Op == POS {
 		return nil
 	}
-	optabKey := opKey{p.Op, p.From.Type, p.To.Type}
-	optabVal, ok := optab[optabKey]
-	if !ok {
-		return fmt.Errorf("unknown combination: %v", optabKey)
+	sized, err := sizeImm(p)
+	if err != nil {
+		return err
+	}
+	c.ins = sized
+	optabVal, from, err := c.makeMask()
+	if err != nil {
+		return err
+	}
+	if err := c.makePrefix(optabVal); err != nil {
+		return err
+	}
+	if err := c.makeEVEX(optabVal); err != nil {
+		return err
 	}
 	c.c0 = optabVal.c0
 	c.c1 = optabVal.c1
 	c.c2 = optabVal.c2
+	c.c3 = optabVal.c3
+	c.vex = optabVal.vex || optabVal.evex
+	c.feature = optabVal.feature
+	c.val = optabVal
+	if optabVal.vexL {
+		c.vexL = 1
+	}
 	if optabVal.addReg {
 		if err := c.addRegToOp(); err != nil {
 			return err
@@ -67,14 +142,78 @@ func (c *ins) make(p *Instruction) error {
 	if optabVal.rex {
 		c.rex |= rexW
 	}
-	if err := c.makeMod(optabVal.mod); err != nil {
+	if optabVal.byteReg {
+		c.makeByteReg()
+	}
+	from, to, err := c.makeArgs(optabVal, from)
+	if err != nil {
+		return err
+	}
+	if c.evexB && from.Type != Ind {
+		return fmt.Errorf("broadcast requires a memory source operand")
+	}
+	if err := c.makeMod(from, to, optabVal.mod, optabVal.regTo); err != nil {
 		return err
 	}
-	c.makeImm(p.From)
-	c.makeImm(p.To)
+	c.makeImm(from)
+	c.makeImm(to)
 	return nil
 }
 
+// makeArgs sorts the operands of the instruction according to the
+// optab layout. It fills in VEX.vvvv and any immediate in an Args list,
+// and returns the remaining operands in the From/To form used by makeMod.
+func (c *ins) makeArgs(v opVal, from Addr) (_, to Addr, err error) {
+	to = c.ins.To
+	var vvvv Addr
+	if from.Type == List {
+		list := from.Value.([]Addr)
+		if len(list) != len(v.args) {
+			return from, to, fmt.Errorf("want %d source operands, have %d", len(v.args), len(list))
+		}
+		switch v.layout {
+		case argsRMVReg:
+			from, vvvv = list[0], list[1]
+		case argsVRMReg:
+			vvvv, from = list[0], list[1]
+		case argsImmRMReg:
+			c.makeImm(list[0])
+			from = list[1]
+		case argsImmRMVReg:
+			c.makeImm(list[0])
+			from, vvvv = list[1], list[2]
+		case argsImmRegRM:
+			if list[0].Type == Reg {
+				if reg := list[0].Value.(Register); reg != CX {
+					return from, to, fmt.Errorf("shift count register must be CX, not %v", reg)
+				}
+			} else {
+				c.makeImm(list[0])
+			}
+			from = list[1]
+		case argsIs4RMVReg:
+			c.immWidth = 8
+			c.imm = uint64(list[0].Value.(Register).num()) << 4
+			from, vvvv = list[1], list[2]
+		default:
+			return from, to, fmt.Errorf("operand list unsupported")
+		}
+	} else if v.layout == argsRMV {
+		from, to, vvvv = Addr{}, from, to
+	}
+	if vvvv.Type != None {
+		if !c.vex {
+			panic("VEX.vvvv operand without VEX prefix")
+		}
+		reg, ok := vvvv.Value.(Register)
+		if !ok || vvvv.Type == Ind {
+			return from, to, fmt.Errorf("VEX.vvvv operand must be a register")
+		}
+		c.vexV = reg.num()
+	}
+	return from, to, nil
+}
+
 func (c *ins) addRegToOp() error {
 	var r Addr
 	if c.ins.To.Type == Reg {
@@ -86,17 +225,54 @@ func (c *ins) addRegToOp() error {
 	if !ok {
 		return fmt.Errorf("address must be register")
 	}
-	if reg >= R8 && reg <= R15 {
-		return fmt.Errorf("R8-15 unsupported for addReg")
+	if reg >= X0 {
+		return fmt.Errorf("%v unsupported for addReg", reg)
 	}
-	if reg >= X0 && reg <= X15 {
-		return fmt.Errorf("Xmm unsupported for addReg")
+	if n := reg.num(); n&8 != 0 {
+		c.rex |= rexB
 	}
-	c.c1 += uint8(reg - AX)
+	c.c1 += reg.num() & 7
 	return nil
 }
 
-func (c *ins) makeMod(bits modBits) error {
+// makePrefix checks the prefixes of the instruction are permitted.
+func (c *ins) makePrefix(v opVal) error {
+	p := c.ins.Op.Prefix() &^ evexMods
+	if seg := p & (SEGFS | SEGGS); seg != 0 {
+		if seg == SEGFS|SEGGS {
+			return fmt.Errorf("more than one segment override")
+		}
+		if c.ins.From.Type != Ind && c.ins.To.Type != Ind {
+			return fmt.Errorf("segment override without memory operand")
+		}
+		p &^= seg
+	}
+	if p&^v.prefix != 0 {
+		return fmt.Errorf("prefix %v not permitted", p&^v.prefix)
+	}
+	if p&LOCK != 0 && c.ins.To.Type != Ind {
+		return fmt.Errorf("LOCK requires a memory destination")
+	}
+	if rep := p & (REP | REPE | REPNE); rep != 0 && rep != REP && rep != REPE && rep != REPNE {
+		return fmt.Errorf("more than one repeat prefix")
+	}
+	return nil
+}
+
+// makeByteReg forces a REX prefix if any register operand is one of the
+// low bytes of SP, BP, SI, or DI. Without REX, those encodings mean AH-BH.
+func (c *ins) makeByteReg() {
+	for _, r := range []Addr{c.ins.From, c.ins.To} {
+		if r.Type != Reg {
+			continue
+		}
+		if reg := r.Value.(Register); reg >= SP && reg <= DI {
+			c.rexForce = true
+		}
+	}
+}
+
+func (c *ins) makeMod(from, to Addr, bits modBits, regTo bool) error {
 	if bits == modNone {
 		return nil
 	}
@@ -104,12 +280,12 @@ func (c *ins) makeMod(bits modBits) error {
 
 	// r1 is direct address, r2 may be indirect address
 	var r1, r2 Addr
-	if c.ins.From.Type == Ind {
-		r1 = c.ins.To
-		r2 = c.ins.From
+	if from.Type == Ind || regTo {
+		r1 = to
+		r2 = from
 	} else {
-		r1 = c.ins.From
-		r2 = c.ins.To
+		r1 = from
+		r2 = to
 	}
 	if r1.Type == Ind {
 		return fmt.Errorf("only one register can be indirect")
@@ -118,18 +294,12 @@ func (c *ins) makeMod(bits modBits) error {
 	switch bits {
 	case modDefault:
 		if reg, ok := r1.Value.(Register); ok {
-			switch {
-			case reg >= AX && reg <= DI:
-				c.modRMreg = uint8(reg - AX)
-			case reg >= R8 && reg <= R15:
+			n := reg.num()
+			if n&8 != 0 {
 				c.rex |= rexR
-				c.modRMreg = uint8(reg - R8)
-			case reg >= X0 && reg <= X7:
-				c.modRMreg = uint8(reg - X0)
-			case reg >= X8 && reg <= X15:
-				c.rex |= rexR // TODO is this right?
-				c.modRMreg = uint8(reg - X8)
 			}
+			c.evexR = n&16 != 0
+			c.modRMreg = n & 7
 		}
 	case mod0, mod1, mod2, mod3, mod4, mod5, mod6, mod7, mod8:
 		c.modRMreg = uint8(bits - mod0)
@@ -152,68 +322,58 @@ func (c *ins) makeMod(bits modBits) error {
 func (c *ins) indirectAddress(r2 Addr) error {
 	// TODO handle a displacement with no register. (i.e. mod=00, r/m=100b)
 
-	if r2.Disp == 0 {
+	// The displacement is signed. With EVEX, an 8-bit displacement is
+	// scaled by the size of the memory operand, N.
+	reg := r2.Value.(Register)
+	d, n := int64(r2.Disp), int64(1)
+	if c.evexN != 0 {
+		n = int64(c.evexN)
+	}
+	switch {
+	case d == 0 && reg.num()&7 != 5:
+		// mod=00 with BP or R13 means RIP-relative, so they need a disp8.
 		c.modRMmod = 0
-	} else if r2.Disp <= 0xff {
+	case d%n == 0 && d/n == int64(int8(d/n)):
 		c.modRMmod = 0x01
 		c.dispWidth = 8
-		c.disp = r2.Disp
-	} else if r2.Disp <= 0xffffffff {
+		c.disp = uint64(d / n)
+	case d == int64(int32(d)):
 		c.modRMmod = 0x02
 		c.dispWidth = 32
-		c.disp = r2.Disp
-	} else {
+		c.disp = uint64(d)
+	default:
 		return fmt.Errorf("TODO handle disp with SIB scaling: %x", r2.Disp)
 	}
 	// Assuming we are not using the scale and index, (TODO above) let's proceed.
 
-	// Skip SIB if rm != 100b.
-	reg := r2.Value.(Register)
-	switch {
-	case reg >= R8 && reg <= R15 && reg-R8 != 0x4:
-		c.rex |= rexB
-		c.modRMrm = uint8(reg - R8)
-		return nil
-	case reg >= X0 && reg <= X7 && reg-X0 != 0x4:
+	num := reg.num()
+	if num&8 != 0 {
 		c.rex |= rexB
-		c.modRMrm = uint8(reg - X0)
-		return nil
-	case reg != 0x4:
-		c.modRMrm = uint8(reg)
+	}
+	if num&7 != 0x4 {
+		c.modRMrm = num & 7
 		return nil
 	}
 
-	// Add a SIB.
+	// rm=100b, SP or R12, means a SIB follows.
 	c.sib = true
 	c.modRMrm = 0x4
-
-	if reg >= R8 && reg <= R15 {
-		c.rex |= rexB
-		reg -= R8
-	} else if reg >= X0 && reg <= X7 {
-		reg -= X0
-	} else if reg >= X8 && reg <= X15 {
-		c.rex |= rexB
-		reg -= X8
-	}
 	c.sibIndex = 0x4 // no index register
-	c.sibBase = uint8(reg)
+	c.sibBase = num & 7
 	return nil
 }
 
 func (c *ins) directAddress(r2 Addr) error {
 	c.modRMmod = 0x3
 	if reg, ok := r2.Value.(Register); ok {
-		if reg >= R8 && reg <= R15 {
-			c.rex |= rexB
-			reg -= R8
-		} else if reg >= X0 && reg <= X7 {
-			reg -= X0
-		} else if reg >= X8 && reg <= X15 {
+		n := reg.num()
+		if n&8 != 0 {
 			c.rex |= rexB
-			reg -= X8
 		}
-		c.modRMrm = uint8(reg)
+		if n&16 != 0 {
+			c.rex |= rexX // EVEX.X is the fifth bit of a direct ModRM.rm
+		}
+		c.modRMrm = n & 7
 	}
 	return nil
 }
@@ -235,21 +395,106 @@ func (c *ins) makeImm(r Addr) {
 	}
 }
 
-func (c *ins) writeTo(w io.Writer) (n int64, err error) {
-	if c.ins.Op == LABEL {
-		return
+// sizeImm returns p with an ImmAny operand replaced by the shortest
+// immediate that encodes its value, or p if it has no ImmAny operand.
+//
+// An 8-bit immediate is sign-extended by most ops, and a 32-bit
+// immediate by 64-bit ops, so they are tried for values in the signed
+// range. A MOVQ of a value that fits in 32 bits unsigned is encoded as
+// MOVL, which zero-extends into the 64-bit register.
+func sizeImm(p *Instruction) (*Instruction, error) {
+	var v int64
+	var set func(in *Instruction, a Addr)
+	if p.From.Type == ImmAny {
+		v = p.From.Value.(int64)
+		set = func(in *Instruction, a Addr) { in.From = a }
+	} else if p.From.Type == List {
+		list := p.From.Value.([]Addr)
+		for i, a := range list {
+			if a.Type != ImmAny {
+				continue
+			}
+			v = a.Value.(int64)
+			set = func(in *Instruction, a Addr) {
+				l := append([]Addr(nil), list...)
+				l[i] = a
+				in.From = Args(l...)
+			}
+		}
 	}
-	var bufArray [6]byte
-	buf := bufArray[:0]
-	if c.rex != 0 {
-		buf = append(buf, 0x48|c.rex)
+	if set == nil {
+		return p, nil
 	}
-	if c.c0 != 0 {
-		buf = append(buf, c.c0)
+
+	type candidate struct {
+		op     Op
+		imm    Addr
+		narrow bool // only valid for ops with 32-bit operands
+	}
+	var cands []candidate
+	if v == int64(int8(v)) {
+		cands = append(cands, candidate{p.Op, Imm(uint8(v)), false})
+	}
+	if p.Op.Base() == MOVQ && v >= 0 && v <= math.MaxUint32 {
+		cands = append(cands, candidate{p.Op.Prefix() | MOVL, Imm(uint32(v)), false})
+	}
+	if v == int64(int32(v)) {
+		cands = append(cands, candidate{p.Op, Imm(uint32(v)), false})
+	}
+	cands = append(cands, candidate{p.Op, Imm(uint64(v)), false})
+	if v > math.MaxInt32 && v <= math.MaxUint32 {
+		// A 32-bit op computes the same result from the value as a
+		// signed 32-bit immediate.
+		if w := int32(v); w == int32(int8(w)) {
+			cands = append(cands, candidate{p.Op, Imm(uint8(w)), true})
+		}
+		cands = append(cands, candidate{p.Op, Imm(uint32(v)), true})
+	}
+	if v > math.MaxInt8 && v <= math.MaxUint8 {
+		// Ops such as PSHUFD only take an unsigned 8-bit immediate.
+		cands = append(cands, candidate{p.Op, Imm(uint8(v)), false})
+	}
+
+	for _, cand := range cands {
+		in := *p
+		in.Op = cand.op
+		set(&in, cand.imm)
+		var c ins
+		if err := c.make(&in); err != nil {
+			continue
+		}
+		if cand.narrow && c.rex&rexW != 0 {
+			continue
+		}
+		return &in, nil
 	}
-	buf = append(buf, c.c1)
-	if c.c1 == 0x0f {
-		buf = append(buf, c.c2)
+	return nil, fmt.Errorf("no encoding for immediate %#x", v)
+}
+
+func (c *ins) writeTo(w io.Writer) (n int64, err error) {
+	if c.ins.Op == LABEL || c.ins.Op == POS {
+		return
+	}
+	var bufArray [15]byte
+	buf := appendPrefix(bufArray[:0], c.ins.Op)
+	if c.evex {
+		buf = c.appendEVEX(buf)
+	} else if c.vex {
+		buf = c.appendVEX(buf)
+	} else {
+		if c.c0 != 0 {
+			buf = append(buf, c.c0)
+		}
+		if c.rex != 0 || c.rexForce {
+			buf = append(buf, 0x40|c.rex)
+		}
+		buf = append(buf, c.c1)
+		if c.c1 == 0x0f {
+			buf = append(buf, c.c2)
+			if c.c2 == 0x38 || c.c2 == 0x3a {
+				buf = append(buf, c.c3)
+			}
+		}
 	}
 	if c.modRM {
 		buf = append(buf, c.modRMmod<<6|c.modRMreg<<3|c.modRMrm)
@@ -277,11 +522,13 @@ func (c *ins) writeTo(w io.Writer) (n int64, err error) {
 }
 
 func (c *ins) printText(w io.Writer) {
-	name := opName[c.ins.Op]
+	name := c.ins.Op.String()
 	fmt.Fprint(w, name)
 	const namePad = "      "
 	if len(name) < len(namePad) {
 		fmt.Fprint(w, namePad[len(name):])
+	} else {
+		fmt.Fprint(w, " ")
 	}
 
 	c.ins.From.printText(w, c.codeblockEnd)
@@ -2,9 +2,14 @@ package i64
 
 import (
 	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"reflect"
+	"strings"
 
 	"testing"
+
+	"github.com/crawshaw/asm/cpu"
 )
 
 var i64tests = []struct {
@@ -42,6 +47,31 @@ var i64tests = []struct {
 		"MOVQ  8+(SP),BX",
 		[]byte{0x48, 0x8b, 0x5c, 0x24, 0x08},
 	},
+	{
+		Instruction{MOVQ, R12.Ind(0), AX.Addr()},
+		"MOVQ  (R12),AX",
+		[]byte{0x49, 0x8b, 0x04, 0x24},
+	},
+	{
+		Instruction{MOVQ, R12.Ind(8), AX.Addr()},
+		"MOVQ  8+(R12),AX",
+		[]byte{0x49, 0x8b, 0x44, 0x24, 0x08},
+	},
+	{
+		Instruction{MOVQ, R9.Ind(8), AX.Addr()},
+		"MOVQ  8+(R9),AX",
+		[]byte{0x49, 0x8b, 0x41, 0x08},
+	},
+	{
+		Instruction{MOVQ, SP.Off(-8), AX.Addr()},
+		"MOVQ  -8+(SP),AX",
+		[]byte{0x48, 0x8b, 0x44, 0x24, 0xf8},
+	},
+	{
+		Instruction{MOVQ, AX.Addr(), BP.Off(-0x200)},
+		"MOVQ  AX,-200+(BP)",
+		[]byte{0x48, 0x89, 0x85, 0x00, 0xfe, 0xff, 0xff},
+	},
 	{
 		Instruction{MOVQ, Imm(uint32(1)), SP.Ind(0)},
 		"MOVQ  0x1,(SP)",
@@ -95,7 +125,7 @@ var i64tests = []struct {
 	{
 		Instruction{ADDSD, X0.Addr(), X1.Addr()},
 		"ADDSD X0,X1",
-		[]byte{0xf2, 0x0f, 0x58, 0xc1},
+		[]byte{0xf2, 0x0f, 0x58, 0xc8},
 	},
 	{
 		Instruction{Op: IDIVL, To: BX.Addr()},
@@ -107,43 +137,1733 @@ var i64tests = []struct {
 		"IDIVQ ,BX",
 		[]byte{0x48, 0xf7, 0xfb},
 	},
-}
-
-func TestI64(t *testing.T) {
-	for _, test := range i64tests {
-		c := new(ins)
-		if err := c.make(&test.ins); err != nil {
-			t.Errorf("%v: %v", test.ins, err)
-			continue
-		}
-		buf := new(bytes.Buffer)
-		if _, err := c.writeTo(buf); err != nil {
-			t.Errorf("%v: %v", test.ins, err)
-			continue
-		}
-		if got := buf.Bytes(); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("%v.Bytes()=%x, want %x", test.ins, got, test.want)
-			continue // no point seeing text error too
-		}
-		buf = new(bytes.Buffer)
-		c.printText(buf)
-		if got := buf.String(); got != test.text {
-			t.Errorf("%v.printText()=%q, want %q", test.ins, got, test.text)
-		}
-	}
-}
-
-func TestOpName(t *testing.T) {
-	names := make(map[string]Op)
-	for i := LABEL; i < lastOp; i++ {
-		name, ok := opName[i]
-		if !ok {
-			t.Errorf("op %d has no name", i)
-			continue
-		}
-		if other, ok := names[name]; ok {
-			t.Errorf("op %d and op %d share name %q", i, other, name)
-		}
-		names[name] = i
+	{
+		Instruction{MOVBLZX, SI.Addr(), AX.Addr()},
+		"MOVBLZX SI,AX",
+		[]byte{0x40, 0x0f, 0xb6, 0xc6},
+	},
+	{
+		Instruction{MOVBLSX, AX.Addr(), CX.Addr()},
+		"MOVBLSX AX,CX",
+		[]byte{0x0f, 0xbe, 0xc8},
+	},
+	{
+		Instruction{MOVBQSX, SP.Ind(0), BX.Addr()},
+		"MOVBQSX (SP),BX",
+		[]byte{0x48, 0x0f, 0xbe, 0x1c, 0x24},
+	},
+	{
+		Instruction{MOVWQZX, CX.Addr(), R9.Addr()},
+		"MOVWQZX CX,R9",
+		[]byte{0x4c, 0x0f, 0xb7, 0xc9},
+	},
+	{
+		Instruction{MOVLQSX, SP.Ind(8), AX.Addr()},
+		"MOVLQSX 8+(SP),AX",
+		[]byte{0x48, 0x63, 0x44, 0x24, 0x08},
+	},
+	{
+		Instruction{MOVB, SI.Addr(), AX.Ind(0)},
+		"MOVB  SI,(AX)",
+		[]byte{0x40, 0x88, 0x30},
+	},
+	{
+		Instruction{Op: SETL, To: SI.Addr()},
+		"SETL  ,SI",
+		[]byte{0x40, 0x0f, 0x9c, 0xc6},
+	},
+	{
+		Instruction{Op: SETE, To: BX.Ind(0)},
+		"SETE  ,(BX)",
+		[]byte{0x0f, 0x94, 0x03},
+	},
+	{
+		Instruction{CMOVQLT, CX.Addr(), AX.Addr()},
+		"CMOVQLT CX,AX",
+		[]byte{0x48, 0x0f, 0x4c, 0xc1},
+	},
+	{
+		Instruction{CMOVQGT, R8.Addr(), BX.Addr()},
+		"CMOVQGT R8,BX",
+		[]byte{0x49, 0x0f, 0x4f, 0xd8},
+	},
+	{
+		Instruction{CMOVLLS, SP.Ind(8), DX.Addr()},
+		"CMOVLLS 8+(SP),DX",
+		[]byte{0x0f, 0x46, 0x54, 0x24, 0x08},
+	},
+	{
+		Instruction{Op: JG, To: Rel(int32(0x100))},
+		"JG    ,:(100)",
+		[]byte{0x0f, 0x8f, 0x00, 0x01, 0x00, 0x00},
+	},
+	{
+		Instruction{TESTQ, AX.Addr(), AX.Addr()},
+		"TESTQ AX,AX",
+		[]byte{0x48, 0x85, 0xc0},
+	},
+	{
+		Instruction{TESTB, Imm(uint8(0x80)), BX.Ind(0)},
+		"TESTB 0x80,(BX)",
+		[]byte{0xf6, 0x03, 0x80},
+	},
+	{
+		Instruction{TESTL, Imm(uint32(0x10000)), CX.Addr()},
+		"TESTL 0x10000,CX",
+		[]byte{0xf7, 0xc1, 0x00, 0x00, 0x01, 0x00},
+	},
+	{
+		Instruction{TESTQ, CX.Addr(), SP.Ind(8)},
+		"TESTQ CX,8+(SP)",
+		[]byte{0x48, 0x85, 0x4c, 0x24, 0x08},
+	},
+	{
+		Instruction{BTQ, CX.Addr(), AX.Addr()},
+		"BTQ   CX,AX",
+		[]byte{0x48, 0x0f, 0xa3, 0xc8},
+	},
+	{
+		Instruction{BTSQ, Imm(uint8(5)), DI.Ind(0)},
+		"BTSQ  0x5,(DI)",
+		[]byte{0x48, 0x0f, 0xba, 0x2f, 0x05},
+	},
+	{
+		Instruction{BTRL, Imm(uint8(3)), DX.Addr()},
+		"BTRL  0x3,DX",
+		[]byte{0x0f, 0xba, 0xf2, 0x03},
+	},
+	{
+		Instruction{BTCQ, R9.Addr(), BX.Addr()},
+		"BTCQ  R9,BX",
+		[]byte{0x4c, 0x0f, 0xbb, 0xcb},
+	},
+	{
+		Instruction{BSFQ, CX.Addr(), AX.Addr()},
+		"BSFQ  CX,AX",
+		[]byte{0x48, 0x0f, 0xbc, 0xc1},
+	},
+	{
+		Instruction{BSRL, SI.Ind(0), DX.Addr()},
+		"BSRL  (SI),DX",
+		[]byte{0x0f, 0xbd, 0x16},
+	},
+	{
+		Instruction{POPCNTQ, CX.Addr(), AX.Addr()},
+		"POPCNTQ CX,AX",
+		[]byte{0xf3, 0x48, 0x0f, 0xb8, 0xc1},
+	},
+	{
+		Instruction{LZCNTL, SI.Ind(0), DX.Addr()},
+		"LZCNTL (SI),DX",
+		[]byte{0xf3, 0x0f, 0xbd, 0x16},
+	},
+	{
+		Instruction{TZCNTQ, R8.Addr(), R9.Addr()},
+		"TZCNTQ R8,R9",
+		[]byte{0xf3, 0x4d, 0x0f, 0xbc, 0xc8},
+	},
+	{
+		Instruction{ANDNQ, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+		"ANDNQ CX,BX,AX",
+		[]byte{0xc4, 0xe2, 0xe0, 0xf2, 0xc1},
+	},
+	{
+		Instruction{ANDNL, Args(DI.Ind(0), R10.Addr()), AX.Addr()},
+		"ANDNL (DI),R10,AX",
+		[]byte{0xc4, 0xe2, 0x28, 0xf2, 0x07},
+	},
+	{
+		Instruction{BEXTRQ, Args(DX.Addr(), CX.Addr()), AX.Addr()},
+		"BEXTRQ DX,CX,AX",
+		[]byte{0xc4, 0xe2, 0xe8, 0xf7, 0xc1},
+	},
+	{
+		Instruction{BZHIQ, Args(R8.Addr(), SI.Ind(0)), R11.Addr()},
+		"BZHIQ R8,(SI),R11",
+		[]byte{0xc4, 0x62, 0xb8, 0xf5, 0x1e},
+	},
+	{
+		Instruction{MULXQ, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+		"MULXQ CX,BX,AX",
+		[]byte{0xc4, 0xe2, 0xe3, 0xf6, 0xc1},
+	},
+	{
+		Instruction{PDEPQ, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+		"PDEPQ CX,BX,AX",
+		[]byte{0xc4, 0xe2, 0xe3, 0xf5, 0xc1},
+	},
+	{
+		Instruction{PEXTL, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+		"PEXTL CX,BX,AX",
+		[]byte{0xc4, 0xe2, 0x62, 0xf5, 0xc1},
+	},
+	{
+		Instruction{RORXQ, Args(Imm(uint8(7)), CX.Addr()), AX.Addr()},
+		"RORXQ 0x7,CX,AX",
+		[]byte{0xc4, 0xe3, 0xfb, 0xf0, 0xc1, 0x07},
+	},
+	{
+		Instruction{SARXQ, Args(DX.Addr(), CX.Addr()), AX.Addr()},
+		"SARXQ DX,CX,AX",
+		[]byte{0xc4, 0xe2, 0xea, 0xf7, 0xc1},
+	},
+	{
+		Instruction{SHLXL, Args(DX.Addr(), BX.Ind(0)), AX.Addr()},
+		"SHLXL DX,(BX),AX",
+		[]byte{0xc4, 0xe2, 0x69, 0xf7, 0x03},
+	},
+	{
+		Instruction{SHRXQ, Args(R15.Addr(), CX.Addr()), AX.Addr()},
+		"SHRXQ R15,CX,AX",
+		[]byte{0xc4, 0xe2, 0x83, 0xf7, 0xc1},
+	},
+	{
+		Instruction{BLSIQ, CX.Addr(), AX.Addr()},
+		"BLSIQ CX,AX",
+		[]byte{0xc4, 0xe2, 0xf8, 0xf3, 0xd9},
+	},
+	{
+		Instruction{BLSMSKL, BX.Ind(0), R12.Addr()},
+		"BLSMSKL (BX),R12",
+		[]byte{0xc4, 0xe2, 0x18, 0xf3, 0x13},
+	},
+	{
+		Instruction{BLSRQ, R9.Addr(), DX.Addr()},
+		"BLSRQ R9,DX",
+		[]byte{0xc4, 0xc2, 0xe8, 0xf3, 0xc9},
+	},
+	{
+		Instruction{XADDQ | LOCK, AX.Addr(), BX.Ind(0)},
+		"LOCK XADDQ AX,(BX)",
+		[]byte{0xf0, 0x48, 0x0f, 0xc1, 0x03},
+	},
+	{
+		Instruction{XADDL, CX.Addr(), DX.Addr()},
+		"XADDL CX,DX",
+		[]byte{0x0f, 0xc1, 0xca},
+	},
+	{
+		Instruction{CMPXCHGQ | LOCK, CX.Addr(), DI.Ind(8)},
+		"LOCK CMPXCHGQ CX,8+(DI)",
+		[]byte{0xf0, 0x48, 0x0f, 0xb1, 0x4f, 0x08},
+	},
+	{
+		Instruction{Op: CMPXCHG16B | LOCK, To: SI.Ind(0)},
+		"LOCK CMPXCHG16B ,(SI)",
+		[]byte{0xf0, 0x48, 0x0f, 0xc7, 0x0e},
+	},
+	{
+		Instruction{XCHGQ, AX.Addr(), BX.Ind(0)},
+		"XCHGQ AX,(BX)",
+		[]byte{0x48, 0x87, 0x03},
+	},
+	{
+		Instruction{Op: MFENCE},
+		"MFENCE ,",
+		[]byte{0x0f, 0xae, 0xf0},
+	},
+	{
+		Instruction{Op: LFENCE},
+		"LFENCE ,",
+		[]byte{0x0f, 0xae, 0xe8},
+	},
+	{
+		Instruction{Op: SFENCE},
+		"SFENCE ,",
+		[]byte{0x0f, 0xae, 0xf8},
+	},
+	{
+		Instruction{ADDQ | LOCK, Imm(uint8(1)), BX.Ind(0)},
+		"LOCK ADDQ 0x1,(BX)",
+		[]byte{0xf0, 0x48, 0x83, 0x03, 0x01},
+	},
+	{
+		Instruction{ADDQ | LOCK, AX.Addr(), BX.Ind(0)},
+		"LOCK ADDQ AX,(BX)",
+		[]byte{0xf0, 0x48, 0x01, 0x03},
+	},
+	{
+		Instruction{ADDQ, BX.Ind(0), AX.Addr()},
+		"ADDQ  (BX),AX",
+		[]byte{0x48, 0x03, 0x03},
+	},
+	{
+		Instruction{ADDL, CX.Addr(), AX.Addr()},
+		"ADDL  CX,AX",
+		[]byte{0x01, 0xc8},
+	},
+	{
+		Instruction{CMPQ, BX.Ind(0), AX.Addr()},
+		"CMPQ  (BX),AX",
+		[]byte{0x48, 0x39, 0x03},
+	},
+	{
+		Instruction{CMPQ, CX.Addr(), AX.Addr()},
+		"CMPQ  CX,AX",
+		[]byte{0x48, 0x3b, 0xc8},
+	},
+	{
+		Instruction{BTSQ | LOCK, Imm(uint8(3)), DI.Ind(0)},
+		"LOCK BTSQ 0x3,(DI)",
+		[]byte{0xf0, 0x48, 0x0f, 0xba, 0x2f, 0x03},
+	},
+	{
+		Instruction{MOVQ | SEGFS, AX.Ind(8), CX.Addr()},
+		"SEGFS MOVQ 8+(AX),CX",
+		[]byte{0x64, 0x48, 0x8b, 0x48, 0x08},
+	},
+	{
+		Instruction{Op: MOVSB | REP},
+		"REP MOVSB ,",
+		[]byte{0xf3, 0xa4},
+	},
+	{
+		Instruction{Op: MOVSQ | REP},
+		"REP MOVSQ ,",
+		[]byte{0xf3, 0x48, 0xa5},
+	},
+	{
+		Instruction{Op: MOVSW | REP},
+		"REP MOVSW ,",
+		[]byte{0xf3, 0x66, 0xa5},
+	},
+	{
+		Instruction{Op: STOSB | REP},
+		"REP STOSB ,",
+		[]byte{0xf3, 0xaa},
+	},
+	{
+		Instruction{Op: STOSQ | REP},
+		"REP STOSQ ,",
+		[]byte{0xf3, 0x48, 0xab},
+	},
+	{
+		Instruction{Op: CMPSB | REPE},
+		"REPE CMPSB ,",
+		[]byte{0xf3, 0xa6},
+	},
+	{
+		Instruction{Op: SCASB | REPNE},
+		"REPNE SCASB ,",
+		[]byte{0xf2, 0xae},
+	},
+	{
+		Instruction{Op: LODSL},
+		"LODSL ,",
+		[]byte{0xad},
+	},
+	{
+		Instruction{Op: CLD},
+		"CLD   ,",
+		[]byte{0xfc},
+	},
+	{
+		Instruction{SQRTSD, SP.Ind(0), X2.Addr()},
+		"SQRTSD (SP),X2",
+		[]byte{0xf2, 0x0f, 0x51, 0x14, 0x24},
+	},
+	{
+		Instruction{SQRTSS, X9.Addr(), X0.Addr()},
+		"SQRTSS X9,X0",
+		[]byte{0xf3, 0x41, 0x0f, 0x51, 0xc1},
+	},
+	{
+		Instruction{SUBSD, SP.Ind(8), X0.Addr()},
+		"SUBSD 8+(SP),X0",
+		[]byte{0xf2, 0x0f, 0x5c, 0x44, 0x24, 0x08},
+	},
+	{
+		Instruction{UCOMISD, X1.Addr(), X0.Addr()},
+		"UCOMISD X1,X0",
+		[]byte{0x66, 0x0f, 0x2e, 0xc1},
+	},
+	{
+		Instruction{COMISS, BX.Ind(8), X3.Addr()},
+		"COMISS 8+(BX),X3",
+		[]byte{0x0f, 0x2f, 0x5b, 0x08},
+	},
+	{
+		Instruction{CVTSQ2SD, AX.Addr(), X1.Addr()},
+		"CVTSQ2SD AX,X1",
+		[]byte{0xf2, 0x48, 0x0f, 0x2a, 0xc8},
+	},
+	{
+		Instruction{CVTSL2SS, BX.Ind(0), X0.Addr()},
+		"CVTSL2SS (BX),X0",
+		[]byte{0xf3, 0x0f, 0x2a, 0x03},
+	},
+	{
+		Instruction{CVTTSD2SQ, X1.Addr(), AX.Addr()},
+		"CVTTSD2SQ X1,AX",
+		[]byte{0xf2, 0x48, 0x0f, 0x2c, 0xc1},
+	},
+	{
+		Instruction{CVTTSS2SL, X2.Addr(), R9.Addr()},
+		"CVTTSS2SL X2,R9",
+		[]byte{0xf3, 0x44, 0x0f, 0x2c, 0xca},
+	},
+	{
+		Instruction{CVTSD2SL, X0.Addr(), AX.Addr()},
+		"CVTSD2SL X0,AX",
+		[]byte{0xf2, 0x0f, 0x2d, 0xc0},
+	},
+	{
+		Instruction{CVTSS2SD, X1.Addr(), X2.Addr()},
+		"CVTSS2SD X1,X2",
+		[]byte{0xf3, 0x0f, 0x5a, 0xd1},
+	},
+	{
+		Instruction{CVTSD2SS, AX.Ind(0), X0.Addr()},
+		"CVTSD2SS (AX),X0",
+		[]byte{0xf2, 0x0f, 0x5a, 0x00},
+	},
+	{
+		Instruction{MOVQ, AX.Addr(), X1.Addr()},
+		"MOVQ  AX,X1",
+		[]byte{0x66, 0x48, 0x0f, 0x6e, 0xc8},
+	},
+	{
+		Instruction{MOVQ, X1.Addr(), R10.Addr()},
+		"MOVQ  X1,R10",
+		[]byte{0x66, 0x49, 0x0f, 0x7e, 0xca},
+	},
+	{
+		Instruction{MOVQ, AX.Ind(0), X1.Addr()},
+		"MOVQ  (AX),X1",
+		[]byte{0xf3, 0x0f, 0x7e, 0x08},
+	},
+	{
+		Instruction{MOVQ, X1.Addr(), AX.Ind(0)},
+		"MOVQ  X1,(AX)",
+		[]byte{0x66, 0x0f, 0xd6, 0x08},
+	},
+	{
+		Instruction{MOVQ, X1.Addr(), X2.Addr()},
+		"MOVQ  X1,X2",
+		[]byte{0xf3, 0x0f, 0x7e, 0xd1},
+	},
+	{
+		Instruction{MOVL, AX.Addr(), X0.Addr()},
+		"MOVL  AX,X0",
+		[]byte{0x66, 0x0f, 0x6e, 0xc0},
+	},
+	{
+		Instruction{MOVL, X0.Addr(), BX.Ind(0)},
+		"MOVL  X0,(BX)",
+		[]byte{0x66, 0x0f, 0x7e, 0x03},
+	},
+	{
+		Instruction{MOVL, BX.Ind(0), X8.Addr()},
+		"MOVL  (BX),X8",
+		[]byte{0x66, 0x44, 0x0f, 0x6e, 0x03},
+	},
+	{
+		Instruction{MOVSS, X1.Addr(), X2.Addr()},
+		"MOVSS X1,X2",
+		[]byte{0xf3, 0x0f, 0x10, 0xd1},
+	},
+	{
+		Instruction{MOVSD, X3.Addr(), X0.Addr()},
+		"MOVSD X3,X0",
+		[]byte{0xf2, 0x0f, 0x10, 0xc3},
+	},
+	{
+		Instruction{MOVDQU, SI.Ind(0), X0.Addr()},
+		"MOVDQU (SI),X0",
+		[]byte{0xf3, 0x0f, 0x6f, 0x06},
+	},
+	{
+		Instruction{MOVDQU, X1.Addr(), DI.Ind(16)},
+		"MOVDQU X1,10+(DI)",
+		[]byte{0xf3, 0x0f, 0x7f, 0x4f, 0x10},
+	},
+	{
+		Instruction{MOVDQA, X1.Addr(), X2.Addr()},
+		"MOVDQA X1,X2",
+		[]byte{0x66, 0x0f, 0x6f, 0xd1},
+	},
+	{
+		Instruction{MOVAPS, AX.Ind(0), X9.Addr()},
+		"MOVAPS (AX),X9",
+		[]byte{0x44, 0x0f, 0x28, 0x08},
+	},
+	{
+		Instruction{MOVUPS, X3.Addr(), AX.Ind(0)},
+		"MOVUPS X3,(AX)",
+		[]byte{0x0f, 0x11, 0x18},
+	},
+	{
+		Instruction{PADDD, X1.Addr(), X0.Addr()},
+		"PADDD X1,X0",
+		[]byte{0x66, 0x0f, 0xfe, 0xc1},
+	},
+	{
+		Instruction{PMULLD, AX.Ind(0), X2.Addr()},
+		"PMULLD (AX),X2",
+		[]byte{0x66, 0x0f, 0x38, 0x40, 0x10},
+	},
+	{
+		Instruction{PXOR, X0.Addr(), X0.Addr()},
+		"PXOR  X0,X0",
+		[]byte{0x66, 0x0f, 0xef, 0xc0},
+	},
+	{
+		Instruction{PCMPEQB, SI.Ind(0), X1.Addr()},
+		"PCMPEQB (SI),X1",
+		[]byte{0x66, 0x0f, 0x74, 0x0e},
+	},
+	{
+		Instruction{PMOVMSKB, X1.Addr(), AX.Addr()},
+		"PMOVMSKB X1,AX",
+		[]byte{0x66, 0x0f, 0xd7, 0xc1},
+	},
+	{
+		Instruction{PSHUFB, X2.Addr(), X3.Addr()},
+		"PSHUFB X2,X3",
+		[]byte{0x66, 0x0f, 0x38, 0x00, 0xda},
+	},
+	{
+		Instruction{PSHUFD, Args(Imm(uint8(0x1b)), X1.Addr()), X0.Addr()},
+		"PSHUFD 0x1b,X1,X0",
+		[]byte{0x66, 0x0f, 0x70, 0xc1, 0x1b},
+	},
+	{
+		Instruction{SHUFPS, Args(Imm(uint8(0x44)), AX.Ind(0)), X1.Addr()},
+		"SHUFPS 0x44,(AX),X1",
+		[]byte{0x0f, 0xc6, 0x08, 0x44},
+	},
+	{
+		Instruction{PUNPCKLBW, X1.Addr(), X0.Addr()},
+		"PUNPCKLBW X1,X0",
+		[]byte{0x66, 0x0f, 0x60, 0xc1},
+	},
+	{
+		Instruction{ADDPS, X1.Addr(), X0.Addr()},
+		"ADDPS X1,X0",
+		[]byte{0x0f, 0x58, 0xc1},
+	},
+	{
+		Instruction{MULPD, X10.Addr(), X11.Addr()},
+		"MULPD X10,X11",
+		[]byte{0x66, 0x45, 0x0f, 0x59, 0xda},
+	},
+	{
+		Instruction{HADDPS, X1.Addr(), X0.Addr()},
+		"HADDPS X1,X0",
+		[]byte{0xf2, 0x0f, 0x7c, 0xc1},
+	},
+	{
+		Instruction{PMINSB, X1.Addr(), X0.Addr()},
+		"PMINSB X1,X0",
+		[]byte{0x66, 0x0f, 0x38, 0x38, 0xc1},
+	},
+	{
+		Instruction{PMAXSD, AX.Ind(0), X0.Addr()},
+		"PMAXSD (AX),X0",
+		[]byte{0x66, 0x0f, 0x38, 0x3d, 0x00},
+	},
+	{
+		Instruction{PTEST, X1.Addr(), X0.Addr()},
+		"PTEST X1,X0",
+		[]byte{0x66, 0x0f, 0x38, 0x17, 0xc1},
+	},
+	{
+		Instruction{PBLENDVB, X2.Addr(), X1.Addr()},
+		"PBLENDVB X2,X1",
+		[]byte{0x66, 0x0f, 0x38, 0x10, 0xca},
+	},
+	{
+		Instruction{PSRLDQ, Imm(uint8(8)), X1.Addr()},
+		"PSRLDQ 0x8,X1",
+		[]byte{0x66, 0x0f, 0x73, 0xd9, 0x08},
+	},
+	{
+		Instruction{PSLLD, Imm(uint8(3)), X12.Addr()},
+		"PSLLD 0x3,X12",
+		[]byte{0x66, 0x41, 0x0f, 0x72, 0xf4, 0x03},
+	},
+	{
+		Instruction{VADDPS, Args(Y3.Addr(), Y2.Addr()), Y1.Addr()},
+		"VADDPS Y3,Y2,Y1",
+		[]byte{0xc5, 0xec, 0x58, 0xcb},
+	},
+	{
+		Instruction{VADDPS, Args(X3.Addr(), X2.Addr()), X1.Addr()},
+		"VADDPS X3,X2,X1",
+		[]byte{0xc5, 0xe8, 0x58, 0xcb},
+	},
+	{
+		Instruction{VADDPD, Args(AX.Ind(0), Y12.Addr()), Y9.Addr()},
+		"VADDPD (AX),Y12,Y9",
+		[]byte{0xc5, 0x1d, 0x58, 0x08},
+	},
+	{
+		Instruction{VFMADD231PS, Args(Y2.Addr(), Y1.Addr()), Y0.Addr()},
+		"VFMADD231PS Y2,Y1,Y0",
+		[]byte{0xc4, 0xe2, 0x75, 0xb8, 0xc2},
+	},
+	{
+		Instruction{VFMADD231PD, Args(X10.Addr(), X1.Addr()), X0.Addr()},
+		"VFMADD231PD X10,X1,X0",
+		[]byte{0xc4, 0xc2, 0xf1, 0xb8, 0xc2},
+	},
+	{
+		Instruction{VPADDD, Args(Y2.Addr(), Y1.Addr()), Y0.Addr()},
+		"VPADDD Y2,Y1,Y0",
+		[]byte{0xc5, 0xf5, 0xfe, 0xc2},
+	},
+	{
+		Instruction{VPXOR, Args(Y0.Addr(), Y0.Addr()), Y0.Addr()},
+		"VPXOR Y0,Y0,Y0",
+		[]byte{0xc5, 0xfd, 0xef, 0xc0},
+	},
+	{
+		Instruction{VPSHUFB, Args(Y2.Addr(), Y1.Addr()), Y0.Addr()},
+		"VPSHUFB Y2,Y1,Y0",
+		[]byte{0xc4, 0xe2, 0x75, 0x00, 0xc2},
+	},
+	{
+		Instruction{VPCMPEQB, Args(AX.Ind(0), Y1.Addr()), Y15.Addr()},
+		"VPCMPEQB (AX),Y1,Y15",
+		[]byte{0xc5, 0x75, 0x74, 0x38},
+	},
+	{
+		Instruction{VPERMD, Args(Y2.Addr(), Y1.Addr()), Y0.Addr()},
+		"VPERMD Y2,Y1,Y0",
+		[]byte{0xc4, 0xe2, 0x75, 0x36, 0xc2},
+	},
+	{
+		Instruction{VPERMQ, Args(Imm(uint8(0x1b)), Y1.Addr()), Y0.Addr()},
+		"VPERMQ 0x1b,Y1,Y0",
+		[]byte{0xc4, 0xe3, 0xfd, 0x00, 0xc1, 0x1b},
+	},
+	{
+		Instruction{VPBROADCASTB, X1.Addr(), Y0.Addr()},
+		"VPBROADCASTB X1,Y0",
+		[]byte{0xc4, 0xe2, 0x7d, 0x78, 0xc1},
+	},
+	{
+		Instruction{VPBROADCASTD, DI.Ind(0), Y0.Addr()},
+		"VPBROADCASTD (DI),Y0",
+		[]byte{0xc4, 0xe2, 0x7d, 0x58, 0x07},
+	},
+	{
+		Instruction{VPBROADCASTQ, X1.Addr(), X8.Addr()},
+		"VPBROADCASTQ X1,X8",
+		[]byte{0xc4, 0x62, 0x79, 0x59, 0xc1},
+	},
+	{
+		Instruction{VBROADCASTSS, X1.Addr(), Y0.Addr()},
+		"VBROADCASTSS X1,Y0",
+		[]byte{0xc4, 0xe2, 0x7d, 0x18, 0xc1},
+	},
+	{
+		Instruction{VMOVDQU, SI.Ind(0), Y0.Addr()},
+		"VMOVDQU (SI),Y0",
+		[]byte{0xc5, 0xfe, 0x6f, 0x06},
+	},
+	{
+		Instruction{VMOVDQU, Y0.Addr(), DI.Ind(0)},
+		"VMOVDQU Y0,(DI)",
+		[]byte{0xc5, 0xfe, 0x7f, 0x07},
+	},
+	{
+		Instruction{VMOVDQU, Y8.Addr(), Y9.Addr()},
+		"VMOVDQU Y8,Y9",
+		[]byte{0xc4, 0x41, 0x7e, 0x6f, 0xc8},
+	},
+	{
+		Instruction{VPMOVMSKB, Y1.Addr(), AX.Addr()},
+		"VPMOVMSKB Y1,AX",
+		[]byte{0xc5, 0xfd, 0xd7, 0xc1},
+	},
+	{
+		Instruction{VPTEST, Y1.Addr(), Y0.Addr()},
+		"VPTEST Y1,Y0",
+		[]byte{0xc4, 0xe2, 0x7d, 0x17, 0xc1},
+	},
+	{
+		Instruction{Op: VZEROUPPER},
+		"VZEROUPPER ,",
+		[]byte{0xc5, 0xf8, 0x77},
+	},
+	{
+		Instruction{Op: VZEROALL},
+		"VZEROALL ,",
+		[]byte{0xc5, 0xfc, 0x77},
+	},
+	{
+		Instruction{VPADDD, Args(Z1.Addr(), Z2.Addr()), Z3.Addr()},
+		"VPADDD Z1,Z2,Z3",
+		[]byte{0x62, 0xf1, 0x6d, 0x48, 0xfe, 0xd9},
+	},
+	{
+		Instruction{VPADDD, Args(Z1.Addr(), Z2.Addr(), K1.Addr()), Z3.Addr()},
+		"VPADDD Z1,Z2,K1,Z3",
+		[]byte{0x62, 0xf1, 0x6d, 0x49, 0xfe, 0xd9},
+	},
+	{
+		Instruction{VPADDD | ZERO, Args(Z1.Addr(), Z2.Addr(), K1.Addr()), Z3.Addr()},
+		"VPADDD.Z Z1,Z2,K1,Z3",
+		[]byte{0x62, 0xf1, 0x6d, 0xc9, 0xfe, 0xd9},
+	},
+	{
+		Instruction{VPADDD, Args(Z17.Addr(), Z18.Addr()), Z19.Addr()},
+		"VPADDD Z17,Z18,Z19",
+		[]byte{0x62, 0xa1, 0x6d, 0x40, 0xfe, 0xd9},
+	},
+	{
+		Instruction{VPADDD, Args(Z9.Addr(), Z26.Addr()), Z11.Addr()},
+		"VPADDD Z9,Z26,Z11",
+		[]byte{0x62, 0x51, 0x2d, 0x40, 0xfe, 0xd9},
+	},
+	{
+		Instruction{VPADDD, Args(AX.Ind(0x40), Z1.Addr()), Z2.Addr()},
+		"VPADDD 40+(AX),Z1,Z2",
+		[]byte{0x62, 0xf1, 0x75, 0x48, 0xfe, 0x50, 0x01},
+	},
+	{
+		Instruction{VPADDD, Args(AX.Ind(0x44), Z1.Addr()), Z2.Addr()},
+		"VPADDD 44+(AX),Z1,Z2",
+		[]byte{0x62, 0xf1, 0x75, 0x48, 0xfe, 0x90, 0x44, 0x00, 0x00, 0x00},
+	},
+	{
+		Instruction{VPADDD, Args(AX.Off(-0x80), Z1.Addr()), Z2.Addr()},
+		"VPADDD -80+(AX),Z1,Z2",
+		[]byte{0x62, 0xf1, 0x75, 0x48, 0xfe, 0x50, 0xfe},
+	},
+	{
+		Instruction{VADDPS | BCST, Args(AX.Ind(8), Z1.Addr()), Z2.Addr()},
+		"VADDPS.BCST 8+(AX),Z1,Z2",
+		[]byte{0x62, 0xf1, 0x74, 0x58, 0x58, 0x50, 0x02},
+	},
+	{
+		Instruction{VADDPD | BCST | ZERO, Args(BP.Ind(0), Z1.Addr(), K2.Addr()), Z2.Addr()},
+		"VADDPD.BCST.Z (BP),Z1,K2,Z2",
+		[]byte{0x62, 0xf1, 0xf5, 0xda, 0x58, 0x55, 0x00},
+	},
+	{
+		Instruction{VFMADD231PS, Args(Z2.Addr(), Z1.Addr()), Z0.Addr()},
+		"VFMADD231PS Z2,Z1,Z0",
+		[]byte{0x62, 0xf2, 0x75, 0x48, 0xb8, 0xc2},
+	},
+	{
+		Instruction{VPCMPEQB, Args(Z1.Addr(), Z2.Addr()), K1.Addr()},
+		"VPCMPEQB Z1,Z2,K1",
+		[]byte{0x62, 0xf1, 0x6d, 0x48, 0x74, 0xc9},
+	},
+	{
+		Instruction{VPCMPEQD, Args(DI.Ind(0), Z2.Addr(), K1.Addr()), K3.Addr()},
+		"VPCMPEQD (DI),Z2,K1,K3",
+		[]byte{0x62, 0xf1, 0x6d, 0x49, 0x76, 0x1f},
+	},
+	{
+		Instruction{VPTESTMD, Args(Z1.Addr(), Z1.Addr()), K2.Addr()},
+		"VPTESTMD Z1,Z1,K2",
+		[]byte{0x62, 0xf2, 0x75, 0x48, 0x27, 0xd1},
+	},
+	{
+		Instruction{VPTESTNMB, Args(Z8.Addr(), Z9.Addr()), K0.Addr()},
+		"VPTESTNMB Z8,Z9,K0",
+		[]byte{0x62, 0xd2, 0x36, 0x48, 0x26, 0xc0},
+	},
+	{
+		Instruction{VPXORQ, Args(Z0.Addr(), Z0.Addr()), Z0.Addr()},
+		"VPXORQ Z0,Z0,Z0",
+		[]byte{0x62, 0xf1, 0xfd, 0x48, 0xef, 0xc0},
+	},
+	{
+		Instruction{VPERMD, Args(Z2.Addr(), Z1.Addr()), Z0.Addr()},
+		"VPERMD Z2,Z1,Z0",
+		[]byte{0x62, 0xf2, 0x75, 0x48, 0x36, 0xc2},
+	},
+	{
+		Instruction{VPSHUFB, Args(Z2.Addr(), Z1.Addr()), Z0.Addr()},
+		"VPSHUFB Z2,Z1,Z0",
+		[]byte{0x62, 0xf2, 0x75, 0x48, 0x00, 0xc2},
+	},
+	{
+		Instruction{VMOVDQU8, SI.Ind(0), Z0.Addr()},
+		"VMOVDQU8 (SI),Z0",
+		[]byte{0x62, 0xf1, 0x7f, 0x48, 0x6f, 0x06},
+	},
+	{
+		Instruction{VMOVDQU64, Z0.Addr(), DI.Ind(0x80)},
+		"VMOVDQU64 Z0,80+(DI)",
+		[]byte{0x62, 0xf1, 0xfe, 0x48, 0x7f, 0x47, 0x02},
+	},
+	{
+		Instruction{VMOVDQU32 | ZERO, Args(Z1.Addr(), K1.Addr()), Z2.Addr()},
+		"VMOVDQU32.Z Z1,K1,Z2",
+		[]byte{0x62, 0xf1, 0x7e, 0xc9, 0x6f, 0xd1},
+	},
+	{
+		Instruction{VMOVUPS, R13.Ind(0), Z0.Addr()},
+		"VMOVUPS (R13),Z0",
+		[]byte{0x62, 0xd1, 0x7c, 0x48, 0x10, 0x45, 0x00},
+	},
+	{
+		Instruction{VPBROADCASTD, DI.Ind(0), Z0.Addr()},
+		"VPBROADCASTD (DI),Z0",
+		[]byte{0x62, 0xf2, 0x7d, 0x48, 0x58, 0x07},
+	},
+	{
+		Instruction{VPBROADCASTD, DI.Ind(8), Z0.Addr()},
+		"VPBROADCASTD 8+(DI),Z0",
+		[]byte{0x62, 0xf2, 0x7d, 0x48, 0x58, 0x47, 0x02},
+	},
+	{
+		Instruction{VPBROADCASTB, AX.Addr(), Z0.Addr()},
+		"VPBROADCASTB AX,Z0",
+		[]byte{0x62, 0xf2, 0x7d, 0x48, 0x7a, 0xc0},
+	},
+	{
+		Instruction{VPBROADCASTQ, AX.Addr(), Z31.Addr()},
+		"VPBROADCASTQ AX,Z31",
+		[]byte{0x62, 0x62, 0xfd, 0x48, 0x7c, 0xf8},
+	},
+	{
+		Instruction{VBROADCASTSS, X1.Addr(), Z0.Addr()},
+		"VBROADCASTSS X1,Z0",
+		[]byte{0x62, 0xf2, 0x7d, 0x48, 0x18, 0xc1},
+	},
+	{
+		Instruction{VPCOMPRESSD, Args(Z1.Addr(), K1.Addr()), DI.Ind(0)},
+		"VPCOMPRESSD Z1,K1,(DI)",
+		[]byte{0x62, 0xf2, 0x7d, 0x49, 0x8b, 0x0f},
+	},
+	{
+		Instruction{VPCOMPRESSD | ZERO, Args(Z1.Addr(), K1.Addr()), Z2.Addr()},
+		"VPCOMPRESSD.Z Z1,K1,Z2",
+		[]byte{0x62, 0xf2, 0x7d, 0xc9, 0x8b, 0xca},
+	},
+	{
+		Instruction{KMOVW, AX.Addr(), K1.Addr()},
+		"KMOVW AX,K1",
+		[]byte{0xc5, 0xf8, 0x92, 0xc8},
+	},
+	{
+		Instruction{KMOVW, K2.Addr(), AX.Addr()},
+		"KMOVW K2,AX",
+		[]byte{0xc5, 0xf8, 0x93, 0xc2},
+	},
+	{
+		Instruction{KMOVQ, AX.Addr(), K1.Addr()},
+		"KMOVQ AX,K1",
+		[]byte{0xc4, 0xe1, 0xfb, 0x92, 0xc8},
+	},
+	{
+		Instruction{KMOVQ, K1.Addr(), AX.Addr()},
+		"KMOVQ K1,AX",
+		[]byte{0xc4, 0xe1, 0xfb, 0x93, 0xc1},
+	},
+	{
+		Instruction{KMOVW, K1.Addr(), K2.Addr()},
+		"KMOVW K1,K2",
+		[]byte{0xc5, 0xf8, 0x90, 0xd1},
+	},
+	{
+		Instruction{KMOVW, AX.Ind(0), K1.Addr()},
+		"KMOVW (AX),K1",
+		[]byte{0xc5, 0xf8, 0x90, 0x08},
+	},
+	{
+		Instruction{KMOVW, K1.Addr(), AX.Ind(0)},
+		"KMOVW K1,(AX)",
+		[]byte{0xc5, 0xf8, 0x91, 0x08},
+	},
+	{
+		Instruction{KORTESTW, K2.Addr(), K1.Addr()},
+		"KORTESTW K2,K1",
+		[]byte{0xc5, 0xf8, 0x98, 0xca},
+	},
+	{
+		Instruction{KORTESTQ, K2.Addr(), K1.Addr()},
+		"KORTESTQ K2,K1",
+		[]byte{0xc4, 0xe1, 0xf8, 0x98, 0xca},
+	},
+	{
+		Instruction{MOVQ, BP.Ind(0), AX.Addr()},
+		"MOVQ  (BP),AX",
+		[]byte{0x48, 0x8b, 0x45, 0x00},
+	},
+	{
+		Instruction{IMULQ, Args(Imm(uint8(0x10)), BX.Addr()), AX.Addr()},
+		"IMULQ 0x10,BX,AX",
+		[]byte{0x48, 0x6b, 0xc3, 0x10},
+	},
+	{
+		Instruction{IMULL, Args(Imm(uint32(0x1000)), SI.Ind(0)), AX.Addr()},
+		"IMULL 0x1000,(SI),AX",
+		[]byte{0x69, 0x06, 0x00, 0x10, 0x00, 0x00},
+	},
+	{
+		Instruction{IMULQ, Args(Imm(uint8(0x7f)), R9.Addr()), R10.Addr()},
+		"IMULQ 0x7f,R9,R10",
+		[]byte{0x4d, 0x6b, 0xd1, 0x7f},
+	},
+	{
+		Instruction{SHLDQ, Args(Imm(uint8(8)), BX.Addr()), AX.Addr()},
+		"SHLDQ 0x8,BX,AX",
+		[]byte{0x48, 0x0f, 0xa4, 0xd8, 0x08},
+	},
+	{
+		Instruction{SHLDL, Args(CX.Addr(), BX.Addr()), DI.Ind(0)},
+		"SHLDL CX,BX,(DI)",
+		[]byte{0x0f, 0xa5, 0x1f},
+	},
+	{
+		Instruction{SHRDQ, Args(Imm(uint8(3)), R8.Addr()), R9.Addr()},
+		"SHRDQ 0x3,R8,R9",
+		[]byte{0x4d, 0x0f, 0xac, 0xc1, 0x03},
+	},
+	{
+		Instruction{SHRDQ, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+		"SHRDQ CX,BX,AX",
+		[]byte{0x48, 0x0f, 0xad, 0xd8},
+	},
+	{
+		Instruction{VPBLENDVB, Args(Y4.Addr(), Y3.Addr(), Y2.Addr()), Y1.Addr()},
+		"VPBLENDVB Y4,Y3,Y2,Y1",
+		[]byte{0xc4, 0xe3, 0x6d, 0x4c, 0xcb, 0x40},
+	},
+	{
+		Instruction{VPBLENDVB, Args(X12.Addr(), AX.Ind(0), X2.Addr()), X1.Addr()},
+		"VPBLENDVB X12,(AX),X2,X1",
+		[]byte{0xc4, 0xe3, 0x69, 0x4c, 0x08, 0xc0},
+	},
+	{
+		Instruction{VBLENDVPS, Args(Y4.Addr(), Y3.Addr(), Y2.Addr()), Y1.Addr()},
+		"VBLENDVPS Y4,Y3,Y2,Y1",
+		[]byte{0xc4, 0xe3, 0x6d, 0x4a, 0xcb, 0x40},
+	},
+	{
+		Instruction{VINSERTI128, Args(Imm(uint8(1)), X3.Addr(), Y2.Addr()), Y1.Addr()},
+		"VINSERTI128 0x1,X3,Y2,Y1",
+		[]byte{0xc4, 0xe3, 0x6d, 0x38, 0xcb, 0x01},
+	},
+	{
+		Instruction{VEXTRACTI128, Args(Imm(uint8(1)), Y2.Addr()), X1.Addr()},
+		"VEXTRACTI128 0x1,Y2,X1",
+		[]byte{0xc4, 0xe3, 0x7d, 0x39, 0xd1, 0x01},
+	},
+	{
+		Instruction{VEXTRACTI128, Args(Imm(uint8(1)), Y2.Addr()), DI.Ind(0)},
+		"VEXTRACTI128 0x1,Y2,(DI)",
+		[]byte{0xc4, 0xe3, 0x7d, 0x39, 0x17, 0x01},
+	},
+	{
+		Instruction{VPERM2I128, Args(Imm(uint8(0x21)), Y3.Addr(), Y2.Addr()), Y1.Addr()},
+		"VPERM2I128 0x21,Y3,Y2,Y1",
+		[]byte{0xc4, 0xe3, 0x6d, 0x46, 0xcb, 0x21},
+	},
+	{
+		Instruction{VPTERNLOGD, Args(Imm(uint8(0x96)), Z3.Addr(), Z2.Addr()), Z1.Addr()},
+		"VPTERNLOGD 0x96,Z3,Z2,Z1",
+		[]byte{0x62, 0xf3, 0x6d, 0x48, 0x25, 0xcb, 0x96},
+	},
+	{
+		Instruction{VPTERNLOGQ | BCST, Args(Imm(uint8(0xca)), AX.Ind(0), Z2.Addr(), K1.Addr()), Z1.Addr()},
+		"VPTERNLOGQ.BCST 0xca,(AX),Z2,K1,Z1",
+		[]byte{0x62, 0xf3, 0xed, 0x59, 0x25, 0x08, 0xca},
+	},
+	{
+		Instruction{VPCMPUD, Args(Imm(uint8(1)), Z3.Addr(), Z2.Addr()), K1.Addr()},
+		"VPCMPUD 0x1,Z3,Z2,K1",
+		[]byte{0x62, 0xf3, 0x6d, 0x48, 0x1e, 0xcb, 0x01},
+	},
+	{
+		Instruction{VPCMPB, Args(Imm(uint8(4)), AX.Ind(0x40), Z2.Addr(), K2.Addr()), K1.Addr()},
+		"VPCMPB 0x4,40+(AX),Z2,K2,K1",
+		[]byte{0x62, 0xf3, 0x6d, 0x4a, 0x3f, 0x48, 0x01, 0x04},
+	},
+	{
+		Instruction{MOVQ, AX.Addr(), BX.Addr()},
+		"MOVQ  AX,BX",
+		[]byte{0x48, 0x89, 0xc3},
+	},
+	{
+		Instruction{MOVL, AX.Addr(), BX.Addr()},
+		"MOVL  AX,BX",
+		[]byte{0x89, 0xc3},
+	},
+	{
+		Instruction{MOVB, AX.Addr(), SI.Addr()},
+		"MOVB  AX,SI",
+		[]byte{0x40, 0x88, 0xc6},
+	},
+	{
+		Instruction{MOVB, Imm(-1), AX.Ind(0)},
+		"MOVB  0xff,(AX)",
+		[]byte{0xc6, 0x00, 0xff},
+	},
+	{
+		Instruction{ADDQ, Imm(-2), AX.Addr()},
+		"ADDQ  0xfe,AX",
+		[]byte{0x48, 0x83, 0xc0, 0xfe},
+	},
+	{
+		Instruction{ADDQ, Imm(300), AX.Addr()},
+		"ADDQ  0x12c,AX",
+		[]byte{0x48, 0x81, 0xc0, 0x2c, 0x01, 0x00, 0x00},
+	},
+	{
+		Instruction{ADDL, Imm(0xffffffff), AX.Addr()},
+		"ADDL  0xff,AX",
+		[]byte{0x83, 0xc0, 0xff},
+	},
+	{
+		Instruction{ADD, Imm(200), AX.Addr()},
+		"ADD   0xc8,AX",
+		[]byte{0x80, 0xc0, 0xc8},
+	},
+	{
+		Instruction{ADD, Imm(200), SI.Addr()},
+		"ADD   0xc8,SI",
+		[]byte{0x40, 0x80, 0xc6, 0xc8},
+	},
+	{
+		Instruction{CMP, Imm(uint8(7)), DI.Addr()},
+		"CMP   0x7,DI",
+		[]byte{0x40, 0x80, 0xff, 0x07},
+	},
+	{
+		Instruction{MOVQ, Imm(5), AX.Addr()},
+		"MOVL  0x5,AX",
+		[]byte{0xb8, 0x05, 0x00, 0x00, 0x00},
+	},
+	{
+		Instruction{MOVQ, Imm(0xffffffff), AX.Addr()},
+		"MOVL  0xffffffff,AX",
+		[]byte{0xb8, 0xff, 0xff, 0xff, 0xff},
+	},
+	{
+		Instruction{MOVQ, Imm(-1), AX.Addr()},
+		"MOVQ  0xffffffff,AX",
+		[]byte{0x48, 0xc7, 0xc0, 0xff, 0xff, 0xff, 0xff},
+	},
+	{
+		Instruction{MOVQ, Imm(0x123456789), AX.Addr()},
+		"MOVQ  0x123456789,AX",
+		[]byte{0x48, 0xb8, 0x89, 0x67, 0x45, 0x23, 0x01, 0x00, 0x00, 0x00},
+	},
+	{
+		Instruction{MOVQ, Imm(-1), AX.Ind(0)},
+		"MOVQ  0xffffffff,(AX)",
+		[]byte{0x48, 0xc7, 0x00, 0xff, 0xff, 0xff, 0xff},
+	},
+	{
+		Instruction{PSHUFD, Args(Imm(200), X1.Addr()), X0.Addr()},
+		"PSHUFD 0xc8,X1,X0",
+		[]byte{0x66, 0x0f, 0x70, 0xc1, 0xc8},
+	},
+	{
+		Instruction{IMULQ, Args(Imm(1000), BX.Addr()), AX.Addr()},
+		"IMULQ 0x3e8,BX,AX",
+		[]byte{0x48, 0x69, 0xc3, 0xe8, 0x03, 0x00, 0x00},
+	},
+	{
+		Instruction{IMULQ, Args(Imm(3), BX.Addr()), AX.Addr()},
+		"IMULQ 0x3,BX,AX",
+		[]byte{0x48, 0x6b, 0xc3, 0x03},
+	},
+	{
+		Instruction{Op: CALL, To: R11.Addr()},
+		"CALL  ,R11",
+		[]byte{0x41, 0xff, 0xd3},
+	},
+	{
+		Instruction{Op: JMP, To: R11.Addr()},
+		"JMP   ,R11",
+		[]byte{0x41, 0xff, 0xe3},
+	},
+	{
+		Instruction{Op: JMP, To: AX.Ind(0)},
+		"JMP   ,(AX)",
+		[]byte{0xff, 0x20},
+	},
+	{
+		Instruction{MOVQ, Imm(uint64(0x1122334455667788)), R11.Addr()},
+		"MOVQ  0x1122334455667788,R11",
+		[]byte{0x49, 0xbb, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11},
+	},
+	{
+		Instruction{Op: PUSHQ, From: R12.Addr()},
+		"PUSHQ R12,",
+		[]byte{0x41, 0x54},
+	},
+	{
+		Instruction{MOVL, Imm(5), R9.Addr()},
+		"MOVL  0x5,R9",
+		[]byte{0x41, 0xb9, 0x05, 0x00, 0x00, 0x00},
+	},
+	{
+		Instruction{Op: CPUID},
+		"CPUID ,",
+		[]byte{0x0f, 0xa2},
+	},
+	{
+		Instruction{Op: XGETBV},
+		"XGETBV ,",
+		[]byte{0x0f, 0x01, 0xd0},
+	},
+}
+
+func TestI64(t *testing.T) {
+	for _, test := range i64tests {
+		c := new(ins)
+		if err := c.make(&test.ins); err != nil {
+			t.Errorf("%v: %v", test.ins, err)
+			continue
+		}
+		buf := new(bytes.Buffer)
+		if _, err := c.writeTo(buf); err != nil {
+			t.Errorf("%v: %v", test.ins, err)
+			continue
+		}
+		if got := buf.Bytes(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v.Bytes()=%x, want %x", test.ins, got, test.want)
+			continue // no point seeing text error too
+		}
+		buf = new(bytes.Buffer)
+		c.printText(buf)
+		if got := buf.String(); got != test.text {
+			t.Errorf("%v.printText()=%q, want %q", test.ins, got, test.text)
+		}
+	}
+}
+
+var i64errtests = []Instruction{
+	{VPADDD, Args(Z1.Addr(), Z2.Addr(), K0.Addr()), Z3.Addr()},
+	{VPADDD | ZERO, Args(Z1.Addr(), Z2.Addr()), Z3.Addr()},
+	{VPADDB | BCST, Args(AX.Ind(0), Z2.Addr()), Z3.Addr()},
+	{VPADDD | BCST, Args(Z1.Addr(), Z2.Addr()), Z3.Addr()},
+	{VMOVDQU32 | ZERO, Args(Z1.Addr(), K1.Addr()), DI.Ind(0)},
+	{VPCOMPRESSD | ZERO, Args(Z1.Addr(), K1.Addr()), DI.Ind(0)},
+	{VPCMPEQD | ZERO, Args(Z1.Addr(), Z2.Addr(), K1.Addr()), K2.Addr()},
+	{VPADDD | ZERO, Args(Y1.Addr(), Y2.Addr(), K1.Addr()), Y3.Addr()},
+	{ADDQ | BCST, AX.Addr(), BX.Addr()},
+	{SHLDQ, Args(DX.Addr(), BX.Addr()), AX.Addr()},
+	{IMULQ, Args(Imm(uint64(1)), BX.Addr()), AX.Addr()},
+	{VPBLENDVB, Args(Y4.Addr(), Y3.Addr(), X2.Addr()), Y1.Addr()},
+	{VPTERNLOGD, Args(Imm(uint8(1)), Z1.Addr(), Z2.Addr(), K1.Addr(), K2.Addr()), Z3.Addr()},
+	{ANDNQ, Args(Args(CX.Addr()), BX.Addr()), AX.Addr()},
+	{ANDNQ, Args(CX.Addr()), AX.Addr()},
+	{ANDNQ, Args(CX.Addr(), BX.Addr(), DX.Addr()), AX.Addr()},
+	{ANDNQ, Args(CX.Addr(), BX.Ind(0)), AX.Addr()},
+	{RORXQ, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+	{MOVQ, Args(CX.Addr(), BX.Addr()), AX.Addr()},
+	{XADDQ | LOCK, AX.Addr(), BX.Addr()},
+	{CMPQ | LOCK, AX.Addr(), BX.Ind(0)},
+	{MOVQ | LOCK, AX.Addr(), BX.Ind(0)},
+	{MOVQ | SEGFS, AX.Addr(), BX.Addr()},
+	{MOVQ | SEGFS | SEGGS, AX.Addr(), BX.Ind(0)},
+	{Op: MOVSB | REPE},
+	{Op: SCASB | REP},
+	{Op: CMPSB | REPE | REPNE},
+	{MOVQ | REP, AX.Addr(), BX.Addr()},
+	{MOVQ, Imm(uint8(1)), AX.Addr()},
+	{MOVQ, Imm(0xffffffff), AX.Ind(0)},
+	{ADDQ, Imm(0x100000000), AX.Addr()},
+	{ADDL, Imm(-0x80000001), AX.Addr()},
+	{PSHUFD, Args(Imm(256), X1.Addr()), X0.Addr()},
+}
+
+func TestI64Err(t *testing.T) {
+	for _, test := range i64errtests {
+		c := new(ins)
+		if err := c.make(&test); err == nil {
+			t.Errorf("%v: no error", test)
+		}
+	}
+}
+
+func TestOperands(t *testing.T) {
+	for _, test := range []struct {
+		ins  Instruction
+		want []Addr
+	}{
+		{Instruction{Op: RET}, nil},
+		{Instruction{Op: JMP, To: LabelAddr("a")}, []Addr{LabelAddr("a")}},
+		{Instruction{MOVQ, AX.Addr(), BX.Addr()}, []Addr{AX.Addr(), BX.Addr()}},
+		{
+			Instruction{VPBLENDVB, Args(Y4.Addr(), Y3.Addr(), Y2.Addr()), Y1.Addr()},
+			[]Addr{Y4.Addr(), Y3.Addr(), Y2.Addr(), Y1.Addr()},
+		},
+	} {
+		if got := test.ins.Operands(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v.Operands()=%v, want %v", test.ins, got, test.want)
+		}
+	}
+}
+
+func TestFeatures(t *testing.T) {
+	for _, test := range []struct {
+		program Program
+		want    cpu.Feature
+	}{
+		{Program{{Op: RET}}, 0},
+		{Program{{ADDSD, X1.Addr(), X0.Addr()}, {Op: RET}}, 0},
+		{Program{{POPCNTQ, AX.Addr(), BX.Addr()}, {PTEST, X1.Addr(), X0.Addr()}}, cpu.POPCNT | cpu.SSE41},
+		{Program{{PDEPQ, Args(CX.Addr(), BX.Addr()), AX.Addr()}}, cpu.BMI2},
+		{Program{{VFMADD231PS, Args(Y2.Addr(), Y1.Addr()), Y0.Addr()}, {Op: VZEROUPPER}}, cpu.FMA | cpu.AVX},
+		{Program{{VPADDD, Args(X1.Addr(), X2.Addr()), X3.Addr()}}, cpu.AVX},
+		{Program{{VPADDD, Args(Y1.Addr(), Y2.Addr()), Y3.Addr()}}, cpu.AVX2},
+		{Program{{VADDPS, Args(Y1.Addr(), Y2.Addr()), Y3.Addr()}}, cpu.AVX},
+		{Program{{VBROADCASTSS, AX.Ind(0), Y0.Addr()}}, cpu.AVX},
+		{Program{{VBROADCASTSS, X1.Addr(), Y0.Addr()}}, cpu.AVX2},
+		{Program{{VPADDD, Args(Z1.Addr(), Z2.Addr()), Z3.Addr()}}, cpu.AVX512F},
+		{Program{{VPADDB, Args(Z1.Addr(), Z2.Addr()), Z3.Addr()}}, cpu.AVX512F | cpu.AVX512BW},
+		{Program{{KMOVQ, AX.Addr(), K1.Addr()}}, cpu.AVX512F | cpu.AVX512BW},
+	} {
+		got, err := test.program.Features()
+		if err != nil {
+			t.Errorf("%v: %v", test.program, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%v: Features()=%v, want %v", test.program, got, test.want)
+		}
+	}
+}
+
+func TestForm(t *testing.T) {
+	r, w, rw := AccessRead, AccessWrite, AccessReadWrite
+	for _, test := range []struct {
+		in   Instruction
+		want Form
+	}{
+		{Instruction{ADDQ, BX.Addr(), AX.Addr()}, Form{Access: []Access{r, rw}, Flags: w}},
+		{Instruction{MOVQ, BX.Addr(), AX.Addr()}, Form{Access: []Access{r, w}}},
+		{Instruction{CMPQ, BX.Addr(), AX.Addr()}, Form{Access: []Access{r, r}, Flags: w}},
+		{Instruction{XORL, AX.Addr(), AX.Addr()}, Form{Access: []Access{0, w}, Flags: w}},
+		{Instruction{VPXOR, Args(X1.Addr(), X1.Addr()), X2.Addr()}, Form{VEX: true, NonDestructive: true, Feature: cpu.AVX, Access: []Access{0, 0, w}}},
+		{Instruction{LEAQ, AX.Addr(), BX.Ind(8)}, Form{Access: []Access{w, r}}},
+		{Instruction{Op: JNE, To: LabelAddr("x")}, Form{Access: []Access{r}, Flags: r}},
+		{Instruction{Op: IDIVQ, To: CX.Addr()}, Form{Access: []Access{r}, Flags: w, ImplicitUse: []Register{AX, DX}, ImplicitDef: []Register{AX, DX}}},
+		{Instruction{Op: MOVSB | REP}, Form{ImplicitUse: []Register{SI, DI, CX}, ImplicitDef: []Register{SI, DI, CX}}},
+		{Instruction{IMULQ, Args(Imm(3), BX.Addr()), AX.Addr()}, Form{NonDestructive: true, Access: []Access{r, r, w}, Flags: w}},
+		{Instruction{SHLDQ, Args(Imm(uint8(3)), BX.Addr()), AX.Addr()}, Form{Access: []Access{r, r, rw}, Flags: rw}},
+		{Instruction{ANDNQ, Args(BX.Addr(), CX.Addr()), AX.Addr()}, Form{VEX: true, NonDestructive: true, Feature: cpu.BMI1, Access: []Access{r, r, w}, Flags: w}},
+		{Instruction{VPADDD, Args(Y1.Addr(), Y2.Addr()), Y3.Addr()}, Form{VEX: true, NonDestructive: true, Feature: cpu.AVX2, Access: []Access{r, r, w}}},
+		{Instruction{VPADDD, Args(Z1.Addr(), Z2.Addr(), K1.Addr()), Z3.Addr()}, Form{VEX: true, EVEX: true, NonDestructive: true, Merge: true, Feature: cpu.AVX512F, Access: []Access{r, r, r, rw}}},
+		{Instruction{VPADDD | ZERO, Args(Z1.Addr(), Z2.Addr(), K1.Addr()), Z3.Addr()}, Form{VEX: true, EVEX: true, NonDestructive: true, Feature: cpu.AVX512F, Access: []Access{r, r, r, w}}},
+	} {
+		got, err := test.in.Form()
+		if err != nil {
+			t.Errorf("%v: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: Form()=%+v, want %+v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(uint32(1)), AX.Addr()},
+		{POPCNTQ, AX.Addr(), BX.Addr()},
+		{VPADDD, Args(Y1.Addr(), Y2.Addr()), Y3.Addr()},
+		{Op: RET},
+	}
+	if _, err := p[:2].Assemble(Options{Target: cpu.V2}); err != nil {
+		t.Errorf("v2: %v", err)
+	}
+	if _, err := p.Assemble(Options{Target: cpu.V3}); err != nil {
+		t.Errorf("v3: %v", err)
+	}
+	_, err := p.Assemble(Options{Target: cpu.V2})
+	ferr, ok := err.(*FeatureError)
+	if !ok {
+		t.Fatalf("v2: got %v, want *FeatureError", err)
+	}
+	if ferr.Index != 2 || ferr.Missing != cpu.AVX2 {
+		t.Errorf("v2: Index=%d, Missing=%v, want 2, AVX2", ferr.Index, ferr.Missing)
+	}
+	if got, err := p[2].Features(); err != nil || got != cpu.AVX2 {
+		t.Errorf("%v.Features()=%v, %v, want AVX2", p[2], got, err)
+	}
+}
+
+func TestOpName(t *testing.T) {
+	names := make(map[string]Op)
+	for i := LABEL; i < lastOp; i++ {
+		name, ok := opName[i]
+		if !ok {
+			t.Errorf("op %d has no name", i)
+			continue
+		}
+		if other, ok := names[name]; ok {
+			t.Errorf("op %d and op %d share name %q", i, other, name)
+		}
+		names[name] = i
+	}
+}
+
+func TestCond(t *testing.T) {
+	goCond := []string{"OS", "OC", "CS", "CC", "EQ", "NE", "LS", "HI", "MI", "PL", "PS", "PC", "LT", "GE", "LE", "GT"}
+	for c := CondO; c <= CondG; c++ {
+		for _, op := range []Op{c.Jump(), c.Set(), c.CMOVL(), c.CMOVQ()} {
+			got, ok := op.Cond()
+			if !ok || got != c {
+				t.Errorf("%v.Cond()=%v, %v, want %v", op, got, ok, c)
+			}
+		}
+		// Jcc and SETcc use the manual's condition names, but for JHI.
+		for _, op := range []Op{c.Jump(), c.Set()} {
+			if name := op.String(); name[len(name)-len(c.String()):] != c.String() && op != JHI {
+				t.Errorf("%v does not end in condition name %q", op, c)
+			}
+		}
+		if got, want := c.CMOVL().String(), "CMOVL"+goCond[c]; got != want {
+			t.Errorf("%v.CMOVL()=%s, want %s", c, got, want)
+		}
+		if got, want := c.CMOVQ().String(), "CMOVQ"+goCond[c]; got != want {
+			t.Errorf("%v.CMOVQ()=%s, want %s", c, got, want)
+		}
+		if c.Not().Not() != c || c.Not() == c {
+			t.Errorf("%v.Not()=%v", c, c.Not())
+		}
+	}
+	if _, ok := MOVQ.Cond(); ok {
+		t.Errorf("MOVQ has a condition code")
+	}
+}
+
+func TestPatchPoints(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(uint64(0x1122334455667788)), AX.Addr()},
+		{MOVQ, AX.Addr(), BX.Ind(0x100)},
+		{ADDQ, Imm(1), AX.Addr()},
+		{Op: JMP, To: LabelAddr("end")},
+		{VPADDD, Args(AX.Ind(0x40), Z1.Addr()), Z2.Addr()},
+		{Op: LABEL, From: LabelAddr("end")},
+		{Op: RET},
+	}
+	got, err := p.PatchPoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PatchPoint{
+		{Index: 0, Kind: PatchImm, Offset: 2, Width: 8},
+		{Index: 1, Kind: PatchDisp, Offset: 13, Width: 4},
+		{Index: 2, Kind: PatchImm, Offset: 20, Width: 1},
+		{Index: 3, Kind: PatchRel, Offset: 22, Width: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PatchPoints()=%+v, want %+v", got, want)
+	}
+	code, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code[2] != 0x88 || code[13] != 0x00 || code[14] != 0x01 || code[20] != 0x01 {
+		t.Errorf("patch points do not locate their fields in %x", code)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	p := Program{
+		{Op: LABEL, From: LabelAddr("start")},
+		{MOVQ, Imm(uint32(10)), CX.Addr()},
+		{Op: LABEL, From: LabelAddr("loop")},
+		{SUBQ, Imm(1), CX.Addr()},
+		{Op: JNE, To: LabelAddr("loop")},
+		{Op: LABEL, From: LabelAddr("done")},
+		{Op: RET},
+	}
+	got, err := p.Labels()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"start": 0, "loop": 7, "done": 13}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels()=%v, want %v", got, want)
+	}
+	offsets, err := p.Offsets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 0, 7, 7, 11, 13, 13}; !reflect.DeepEqual(offsets, want) {
+		t.Errorf("Offsets()=%v, want %v", offsets, want)
+	}
+	if p[4].To != LabelAddr("loop") {
+		t.Errorf("layout rewrote the jump in the Program to %v", p[4])
+	}
+}
+
+func TestAssembleKeepsProgram(t *testing.T) {
+	p := Program{
+		{Op: LABEL, From: LabelAddr("top")},
+		{Op: CALL, To: LabelAddr("f")},
+		{Op: JNE, To: LabelAddr("top")},
+		{Op: RET},
+		{Op: LABEL, From: LabelAddr("f")},
+		{Op: RET},
+	}
+	want := append(Program(nil), p...)
+	if _, err := p.Bytes(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.WriteTo(new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Listing(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("assembling modified the Program:\n%v\nwant\n%v", p, want)
+	}
+}
+
+func TestJumpSize(t *testing.T) {
+	movs := func(n int) Program {
+		var p Program
+		for i := 0; i < n; i++ {
+			p = append(p, Instruction{MOVQ, Imm(uint64(1 << 40)), AX.Addr()}) // 10 bytes
+		}
+		return p
+	}
+	label := func(name string) Instruction { return Instruction{Op: LABEL, From: LabelAddr(name)} }
+	for _, test := range []struct {
+		name string
+		p    Program
+		jump int    // offset of the jump
+		want string // encoding of the jump
+	}{
+		{"short forward", append(append(Program{{Op: JE, To: LabelAddr("x")}}, movs(12)...), label("x")), 0, "7478"},
+		{"long forward", append(append(Program{{Op: JE, To: LabelAddr("x")}}, movs(20)...), label("x")), 0, "0f84c8000000"},
+		{"short backward", append(append(Program{label("x")}, movs(12)...), Instruction{Op: JMP, To: LabelAddr("x")}), 120, "eb86"},
+		{"long backward", append(append(Program{label("x")}, movs(15)...), Instruction{Op: JMP, To: LabelAddr("x")}), 150, "e965ffffff"},
+	} {
+		b, err := test.p.Assemble(Options{})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := hex.EncodeToString(b[test.jump : test.jump+len(test.want)/2]); got != test.want {
+			t.Errorf("%s: jump encoded as %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(uint32(10)), CX.Addr()},
+		Pos{"loop.s", 3}.Instruction(),
+		{Op: LABEL, From: LabelAddr("loop")},
+		{SUBQ, Imm(1), CX.Addr()},
+		{Op: JNE, To: LabelAddr("loop")},
+		Pos{"loop.s", 7}.Instruction(),
+		{Op: RET},
+	}
+	got := p.Positions()
+	want := []Pos{{}, {"loop.s", 3}, {"loop.s", 3}, {"loop.s", 3}, {"loop.s", 3}, {"loop.s", 7}, {"loop.s", 7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Positions()=%v, want %v", got, want)
+	}
+	code, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want2, err := Program{
+		{MOVQ, Imm(uint32(10)), CX.Addr()},
+		{Op: LABEL, From: LabelAddr("loop")},
+		{SUBQ, Imm(1), CX.Addr()},
+		{Op: JNE, To: LabelAddr("loop")},
+		{Op: RET},
+	}.Bytes()
+	if err != nil || !bytes.Equal(code, want2) {
+		t.Errorf("POS changed the code: %x", code)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := p.PrintText(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "// loop.s:3\nloop:\n") || !strings.Contains(buf.String(), "// loop.s:7\n") {
+		t.Errorf("PrintText does not list positions:\n%s", buf)
+	}
+
+	for _, test := range []struct {
+		p    Program
+		want string
+	}{
+		{Program{{MOVQ, X1.Addr(), Imm(uint32(1))}}, "ins 0: MOVQ X1,0x1: "},
+		{Program{Pos{"f.s", 2}.Instruction(), {MOVQ, X1.Addr(), Imm(uint32(1))}}, "f.s:2: ins 1: MOVQ X1,0x1: "},
+		{Program{Pos{"", 4}.Instruction(), {Op: JMP, To: LabelAddr("x")}}, `line 4: ins 1: undefined label "x"`},
+	} {
+		_, err := test.p.Bytes()
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%v: error %v, want prefix %q", test.p, err, test.want)
+		}
+	}
+
+	_, err = Program{Pos{"f.s", 9}.Instruction(), {POPCNTQ, AX.Addr(), BX.Addr()}}.Assemble(Options{Target: cpu.V1})
+	if ferr, ok := err.(*FeatureError); !ok || ferr.Pos != (Pos{"f.s", 9}) || !strings.HasPrefix(err.Error(), "i64: f.s:9: ins 1: POPCNTQ AX,BX: ") {
+		t.Errorf("FeatureError %v, want position f.s:9", err)
+	}
+}
+
+func TestListing(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(uint32(10)), CX.Addr()},
+		Pos{"loop.s", 3}.Instruction(),
+		{Op: LABEL, From: LabelAddr("loop")},
+		{LOCK | XADDQ, AX.Addr(), BX.Ind(8)},
+		{SUBQ, Imm(1), CX.Addr()},
+		{Op: JNE, To: LabelAddr("loop")},
+		{Op: RET},
+		{Op: LABEL, From: LabelAddr("end")},
+	}
+	l, err := p.Listing()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := p.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all []byte
+	for _, line := range l.Lines {
+		if line.Offset != len(all) {
+			t.Errorf("ins %d: offset %d, want %d", line.Index, line.Offset, len(all))
+		}
+		all = append(all, line.Bytes...)
+	}
+	if !bytes.Equal(all, code) {
+		t.Errorf("listing bytes %x, want %x", all, code)
+	}
+
+	got, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"lines":[` +
+		`{"index":0,"offset":0,"bytes":"48c7c10a000000","mnemonic":"MOVQ","operands":["0xa","CX"]},` +
+		`{"index":3,"offset":7,"bytes":"f0480fc14308","mnemonic":"LOCK XADDQ","operands":["AX","8+(BX)"],"labels":["loop"],"pos":"loop.s:3"},` +
+		`{"index":4,"offset":13,"bytes":"4883e901","mnemonic":"SUBQ","operands":["0x1","CX"],"pos":"loop.s:3"},` +
+		`{"index":5,"offset":17,"bytes":"75f4","mnemonic":"JNE","operands":["loop:(000007)"],"pos":"loop.s:3"},` +
+		`{"index":6,"offset":19,"bytes":"c3","mnemonic":"RET","operands":[],"pos":"loop.s:3"}],` +
+		`"labels":{"end":20,"loop":7}}`
+	if string(got) != want {
+		t.Errorf("JSON:\n%s\nwant:\n%s", got, want)
+	}
+	var l2 Listing
+	if err := json.Unmarshal(got, &l2); err != nil || !reflect.DeepEqual(&l2, l) {
+		t.Errorf("JSON round trip: %v, %+v", err, l2)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		in, out Program
+	}{
+		{
+			"self move",
+			Program{{MOVQ, AX.Addr(), AX.Addr()}, {MOVL, BX.Addr(), BX.Addr()}, {MOVQ, X1.Addr(), X1.Addr()}, {MOVQ, AX.Addr(), BX.Addr()}},
+			Program{{MOVL, BX.Addr(), BX.Addr()}, {MOVQ, X1.Addr(), X1.Addr()}, {MOVQ, AX.Addr(), BX.Addr()}},
+		},
+		{
+			"add zero",
+			Program{{ADDQ, Imm(0), AX.Addr()}, {SUBQ, Imm(uint8(0)), BX.Addr()}, {ADDL, Imm(0), CX.Addr()}, {Op: RET}},
+			Program{{ADDL, Imm(0), CX.Addr()}, {Op: RET}},
+		},
+		{
+			"add zero flags used",
+			Program{{ADDQ, Imm(0), AX.Addr()}, {MOVQ, Imm(1), BX.Addr()}, {Op: JE, To: LabelAddr("x")}, {Op: RET}, {Op: LABEL, From: LabelAddr("x")}, {Op: RET}},
+			Program{{ADDQ, Imm(0), AX.Addr()}, {MOVQ, Imm(1), BX.Addr()}, {Op: JE, To: LabelAddr("x")}, {Op: RET}, {Op: LABEL, From: LabelAddr("x")}, {Op: RET}},
+		},
+		{
+			"zero move",
+			Program{{MOVQ, Imm(0), AX.Addr()}, {MOVL, Imm(uint32(0)), R9.Addr()}, {MOVQ, Imm(0), BX.Ind(0)}, {CMPQ, AX.Addr(), BX.Addr()}},
+			Program{{XORL, AX.Addr(), AX.Addr()}, {XORL, R9.Addr(), R9.Addr()}, {MOVQ, Imm(0), BX.Ind(0)}, {CMPQ, AX.Addr(), BX.Addr()}},
+		},
+		{
+			"zero move flags used",
+			Program{{CMPQ, AX.Addr(), BX.Addr()}, {MOVQ, Imm(0), AX.Addr()}, {CMOVQEQ.Base(), BX.Addr(), AX.Addr()}, {Op: RET}},
+			Program{{CMPQ, AX.Addr(), BX.Addr()}, {MOVQ, Imm(0), AX.Addr()}, {CMOVQEQ.Base(), BX.Addr(), AX.Addr()}, {Op: RET}},
+		},
+		{
+			"zero move through jump",
+			Program{{MOVQ, Imm(0), AX.Addr()}, {Op: JMP, To: LabelAddr("x")}, {Op: LABEL, From: LabelAddr("y")}, {Op: JE, To: LabelAddr("y")}, {Op: LABEL, From: LabelAddr("x")}, {Op: RET}},
+			Program{{XORL, AX.Addr(), AX.Addr()}, {Op: JMP, To: LabelAddr("x")}, {Op: LABEL, From: LabelAddr("y")}, {Op: JE, To: LabelAddr("y")}, {Op: LABEL, From: LabelAddr("x")}, {Op: RET}},
+		},
+		{
+			"add zero before prefixed load",
+			Program{{ADDQ, Imm(0), AX.Addr()}, {MOVQ | SEGFS, BX.Ind(0), CX.Addr()}, {ADDQ, CX.Addr(), AX.Addr()}, {Op: RET}},
+			Program{{MOVQ | SEGFS, BX.Ind(0), CX.Addr()}, {ADDQ, CX.Addr(), AX.Addr()}, {Op: RET}},
+		},
+		{
+			"jump next",
+			Program{{Op: JMP, To: LabelAddr("a")}, Pos{"f.s", 1}.Instruction(), {Op: LABEL, From: LabelAddr("a")}, {Op: JNE, To: LabelAddr("b")}, {Op: LABEL, From: LabelAddr("b")}, {Op: RET}},
+			Program{Pos{"f.s", 1}.Instruction(), {Op: LABEL, From: LabelAddr("a")}, {Op: LABEL, From: LabelAddr("b")}, {Op: RET}},
+		},
+		{
+			"jump chain",
+			Program{
+				{Op: JE, To: LabelAddr("a")},
+				{Op: JMP, To: LabelAddr("b")},
+				{Op: LABEL, From: LabelAddr("a")},
+				{Op: JMP, To: LabelAddr("b")},
+				{Op: LABEL, From: LabelAddr("loop")},
+				{Op: JMP, To: LabelAddr("loop")},
+				{Op: LABEL, From: LabelAddr("b")},
+				{Op: JMP, To: LabelAddr("c")},
+				{Op: RET},
+				{Op: LABEL, From: LabelAddr("c")},
+				{Op: RET},
+			},
+			Program{
+				{Op: JE, To: LabelAddr("c")},
+				{Op: JMP, To: LabelAddr("c")},
+				{Op: LABEL, From: LabelAddr("a")},
+				{Op: JMP, To: LabelAddr("c")},
+				{Op: LABEL, From: LabelAddr("loop")},
+				{Op: JMP, To: LabelAddr("loop")},
+				{Op: LABEL, From: LabelAddr("b")},
+				{Op: JMP, To: LabelAddr("c")},
+				{Op: RET},
+				{Op: LABEL, From: LabelAddr("c")},
+				{Op: RET},
+			},
+		},
+	} {
+		in := append(Program(nil), test.in...)
+		got := test.in.Optimize(PeepholeRules)
+		if !reflect.DeepEqual(got, test.out) {
+			t.Errorf("%s: Optimize()=\n%v\nwant\n%v", test.name, got, test.out)
+		}
+		if !reflect.DeepEqual(test.in, in) {
+			t.Errorf("%s: Optimize modified its input", test.name)
+		}
+	}
+
+	// No rules, no change.
+	p := Program{{MOVQ, AX.Addr(), AX.Addr()}}
+	if got := p.Optimize(nil); !reflect.DeepEqual(got, p) {
+		t.Errorf("Optimize(nil)=%v", got)
+	}
+}
+
+func TestCFGPrefix(t *testing.T) {
+	p := Program{
+		{Op: JMP | SEGGS, To: AX.Ind(8)},
+		{MOVQ, Imm(1), AX.Addr()}, // unreachable
+		{Op: RET},
+	}
+	g, err := p.CFG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Blocks) != 2 || len(g.Blocks[0].Succs) != 0 {
+		t.Fatalf("SEGGS|JMP does not end its block without successors: %d blocks", len(g.Blocks))
+	}
+	if r := g.Reachable(); !reflect.DeepEqual(r, []bool{true, false}) {
+		t.Errorf("Reachable()=%v", r)
+	}
+}
+
+func TestCFG(t *testing.T) {
+	p := Program{
+		{MOVQ, Imm(10), CX.Addr()}, // 0: block 0
+		{Op: LABEL, From: LabelAddr("loop")},
+		{SUBQ, Imm(1), CX.Addr()}, // 1
+		{Op: JE, To: LabelAddr("done")},
+		{Op: CALL, To: LabelAddr("f")},   // 2
+		{Op: JMP, To: LabelAddr("loop")}, // 3
+		{MOVQ, Imm(1), AX.Addr()},        // 4: unreachable
+		{Op: LABEL, From: LabelAddr("done")},
+		Pos{"f.s", 1}.Instruction(),
+		{Op: LABEL, From: LabelAddr("unused")},
+		{Op: RET}, // 5
+		{Op: LABEL, From: LabelAddr("f")},
+		{ADDQ, Imm(1), AX.Addr()}, // 6
+		{Op: JMP, To: AX.Addr()},
+		{Op: RET}, // 7: unreachable
+	}
+	g, err := p.CFG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type block struct {
+		start, end   int
+		succs, preds []int
+	}
+	indexes := func(bs []*Block) []int {
+		var idx []int
+		for _, b := range bs {
+			idx = append(idx, b.Index)
+		}
+		return idx
+	}
+	var got []block
+	for i, b := range g.Blocks {
+		if b.Index != i {
+			t.Errorf("block %d has Index %d", i, b.Index)
+		}
+		got = append(got, block{b.Start, b.End, indexes(b.Succs), indexes(b.Preds)})
+	}
+	want := []block{
+		{0, 1, []int{1}, nil},
+		{1, 4, []int{5, 2}, []int{0, 3}},
+		{4, 5, []int{6, 3}, []int{1}},
+		{5, 6, []int{1}, []int{2}},
+		{6, 7, []int{5}, nil},
+		{7, 11, nil, []int{1, 4}},
+		{11, 14, nil, []int{2}},
+		{14, 15, nil, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CFG blocks:\n%v\nwant\n%v", got, want)
+	}
+	if r := g.Reachable(); !reflect.DeepEqual(r, []bool{true, true, true, true, false, true, true, false}) {
+		t.Errorf("Reachable()=%v", r)
+	}
+
+	q, err := p.RemoveDeadCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantProg := Program{
+		{MOVQ, Imm(10), CX.Addr()},
+		{Op: LABEL, From: LabelAddr("loop")},
+		{SUBQ, Imm(1), CX.Addr()},
+		{Op: JE, To: LabelAddr("done")},
+		{Op: CALL, To: LabelAddr("f")},
+		{Op: JMP, To: LabelAddr("loop")},
+		{Op: LABEL, From: LabelAddr("done")},
+		Pos{"f.s", 1}.Instruction(),
+		{Op: RET},
+		{Op: LABEL, From: LabelAddr("f")},
+		{ADDQ, Imm(1), AX.Addr()},
+		{Op: JMP, To: AX.Addr()},
+	}
+	if !reflect.DeepEqual(q, wantProg) {
+		t.Errorf("RemoveDeadCode()=\n%v\nwant\n%v", q, wantProg)
+	}
+
+	// The position of code after a removed block is kept.
+	p = Program{
+		Pos{"f.s", 1}.Instruction(),
+		{Op: JE, To: LabelAddr("x")},
+		{Op: RET},
+		Pos{"f.s", 2}.Instruction(),
+		{Op: RET},
+		{Op: LABEL, From: LabelAddr("x")},
+		{Op: RET},
+	}
+	q, err = p.RemoveDeadCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Program{p[0], p[1], p[2], p[3], p[5], p[6]}); !reflect.DeepEqual(q, want) {
+		t.Errorf("RemoveDeadCode()=%v, want %v", q, want)
+	}
+
+	if _, err := (Program{{Op: JMP, To: LabelAddr("x")}}).CFG(); err == nil || !strings.Contains(err.Error(), `undefined label "x"`) {
+		t.Errorf("CFG of undefined label: %v", err)
 	}
 }
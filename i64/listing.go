@@ -0,0 +1,83 @@
+package i64
+
+import (
+	"bytes"
+	"encoding/hex"
+)
+
+// A Listing is the layout of an assembled Program, for tools and golden
+// tests. It holds the information PrintText writes, and encodes as JSON.
+type Listing struct {
+	Lines  []Line         `json:"lines"`
+	Labels map[string]int `json:"labels"` // offset of each label
+}
+
+// A Line is an instruction in a Listing. The LABEL and POS
+// pseudo-instructions have no Line of their own, they annotate the
+// instruction that follows them.
+type Line struct {
+	Index    int      `json:"index"` // index of the instruction in the Program, counting LABEL and POS
+	Offset   int      `json:"offset"`
+	Bytes    HexBytes `json:"bytes"`
+	Mnemonic string   `json:"mnemonic"`         // with any prefixes, such as "LOCK XADDQ"
+	Operands []string `json:"operands"`         // in Go assembler order, destination last
+	Labels   []string `json:"labels,omitempty"` // labels defined at the instruction
+	Pos      string   `json:"pos,omitempty"`    // source position, if known
+}
+
+// HexBytes is a byte slice that encodes as a hexadecimal string.
+type HexBytes []byte
+
+func (b HexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(b)), nil
+}
+
+func (b *HexBytes) UnmarshalText(text []byte) error {
+	v, err := hex.DecodeString(string(text))
+	*b = v
+	return err
+}
+
+// Listing returns the layout of the assembled program.
+func (p Program) Listing() (*Listing, error) {
+	laidOut, err := p.layOut()
+	if err != nil {
+		return nil, err
+	}
+	positions := p.Positions()
+	l := &Listing{Labels: make(map[string]int)}
+	var labels []string
+	buf := new(bytes.Buffer)
+	for i := range laidOut {
+		c := &laidOut[i]
+		switch p[i].Op {
+		case LABEL:
+			l.Labels[p[i].From.Name] = c.codeblock
+			labels = append(labels, p[i].From.Name)
+			continue
+		case POS:
+			continue
+		}
+		buf.Reset()
+		c.writeTo(buf)
+		line := Line{
+			Index:    i,
+			Offset:   c.codeblock,
+			Bytes:    HexBytes(append([]byte(nil), buf.Bytes()...)),
+			Mnemonic: c.ins.Op.String(),
+			Operands: []string{},
+			Labels:   labels,
+		}
+		for _, a := range c.ins.Operands() {
+			buf.Reset()
+			a.printText(buf, c.codeblockEnd)
+			line.Operands = append(line.Operands, buf.String())
+		}
+		if pos := positions[i]; pos.IsValid() {
+			line.Pos = pos.String()
+		}
+		l.Lines = append(l.Lines, line)
+		labels = nil
+	}
+	return l, nil
+}
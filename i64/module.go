@@ -0,0 +1,192 @@
+package i64
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// A Module is a set of named Programs assembled into one block of code.
+//
+// A CALL to a label not defined in the calling Program calls the
+// Program of that name, or an external function registered with Extern.
+// External functions are called through a stub placed after the
+// Programs, which loads the absolute address into R11 and jumps to it,
+// so the assembled code does not depend on where it is loaded.
+type Module struct {
+	progs   []namedProgram
+	externs map[string]uintptr
+}
+
+type namedProgram struct {
+	name string
+	prog Program
+}
+
+// funcAlign is the alignment of each function in an assembled Module.
+const funcAlign = 16
+
+// Add adds the program p to the module under name.
+func (m *Module) Add(name string, p Program) error {
+	if m.defined(name) {
+		return fmt.Errorf("i64: %q already defined", name)
+	}
+	m.progs = append(m.progs, namedProgram{name, p})
+	return nil
+}
+
+// Extern registers the address of an external function, such as a C
+// function or Go assembly routine, that Programs in m may CALL by name.
+func (m *Module) Extern(name string, addr uintptr) error {
+	if m.defined(name) {
+		return fmt.Errorf("i64: %q already defined", name)
+	}
+	if m.externs == nil {
+		m.externs = make(map[string]uintptr)
+	}
+	m.externs[name] = addr
+	return nil
+}
+
+func (m *Module) defined(name string) bool {
+	if _, ok := m.externs[name]; ok {
+		return true
+	}
+	for _, np := range m.progs {
+		if np.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// An Object is the assembled code of a Module.
+type Object struct {
+	Code    []byte
+	Symbols []Symbol // in order of Offset
+}
+
+// A Symbol is a function in an Object.
+type Symbol struct {
+	Name   string
+	Offset int // offset of the entry point in Code
+	Size   int
+	Extern bool // the symbol is the stub calling an external function
+}
+
+// Lookup returns the symbol of the function name.
+func (o *Object) Lookup(name string) (Symbol, bool) {
+	for _, s := range o.Symbols {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Symbol{}, false
+}
+
+// Assemble assembles the programs of m, in the order they were added,
+// followed by the stubs of the external functions they call.
+func (m *Module) Assemble(opts Options) (*Object, error) {
+	type call struct {
+		prog, ins int
+		name      string
+	}
+	var calls []call
+	progs := make([]Program, len(m.progs))
+	laidOut := make([][]ins, len(m.progs))
+	externs := make(map[string]bool)
+	for pi, np := range m.progs {
+		// Copy the program, as its calls to other functions are rewritten.
+		p := append(Program(nil), np.prog...)
+		labels := make(map[string]bool)
+		for _, in := range p {
+			if in.Op == LABEL {
+				labels[in.From.Name] = true
+			}
+		}
+		for i, in := range p {
+			if in.Op.Base() != CALL || in.To.Type != Label || labels[in.To.Name] {
+				continue
+			}
+			name := in.To.Name
+			if _, ok := m.externs[name]; ok {
+				externs[name] = true
+			} else if !m.defined(name) {
+				return nil, fmt.Errorf("%s: %w", np.name, p.errorf(i, "undefined function %q", name))
+			}
+			calls = append(calls, call{pi, i, name})
+			p[i].To = Rel(int32(0))
+		}
+		l, err := p.layOut()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", np.name, err)
+		}
+		if err := checkTarget(p, l, opts.Target); err != nil {
+			return nil, fmt.Errorf("%s: %w", np.name, err)
+		}
+		progs[pi], laidOut[pi] = p, l
+	}
+
+	// Lay out the functions, then the stubs.
+	obj := new(Object)
+	offset := make(map[string]int)
+	off := 0
+	for pi, np := range m.progs {
+		size := 0
+		if l := laidOut[pi]; len(l) > 0 {
+			size = l[len(l)-1].codeblockEnd
+		}
+		obj.Symbols = append(obj.Symbols, Symbol{Name: np.name, Offset: off, Size: size})
+		offset[np.name] = off
+		off = (off + size + funcAlign - 1) &^ (funcAlign - 1)
+	}
+	var names []string
+	for name := range externs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var stubs [][]byte
+	for _, name := range names {
+		stub, err := Program{
+			{MOVQ, Imm(uint64(m.externs[name])), R11.Addr()},
+			{Op: JMP, To: R11.Addr()},
+		}.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		obj.Symbols = append(obj.Symbols, Symbol{Name: name, Offset: off, Size: len(stub), Extern: true})
+		offset[name] = off
+		off = (off + len(stub) + funcAlign - 1) &^ (funcAlign - 1)
+		stubs = append(stubs, stub)
+	}
+
+	// Resolve the calls between functions.
+	for _, c := range calls {
+		from := offset[m.progs[c.prog].name] + laidOut[c.prog][c.ins].codeblockEnd
+		progs[c.prog][c.ins].To = Rel(int32(offset[c.name] - from))
+		if err := laidOut[c.prog][c.ins].make(&progs[c.prog][c.ins]); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	pad := func(off int) {
+		for buf.Len() < off {
+			buf.WriteByte(0xcc) // INT3
+		}
+	}
+	for pi := range progs {
+		pad(obj.Symbols[pi].Offset)
+		for _, c := range laidOut[pi] {
+			if _, err := c.writeTo(buf); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i, stub := range stubs {
+		pad(obj.Symbols[len(progs)+i].Offset)
+		buf.Write(stub)
+	}
+	obj.Code = buf.Bytes()
+	return obj, nil
+}
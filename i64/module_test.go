@@ -0,0 +1,93 @@
+package i64
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestModule(t *testing.T) {
+	var m Module
+	if err := m.Add("main", Program{
+		{Op: CALL, To: LabelAddr("helper")},
+		{Op: CALL, To: LabelAddr("local")},
+		{Op: CALL, To: LabelAddr("ext")},
+		{Op: RET},
+		{Op: LABEL, From: LabelAddr("local")},
+		{Op: RET},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("helper", Program{{Op: RET}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Extern("ext", 0x1122334455667788); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("helper", Program{{Op: RET}}); err == nil {
+		t.Error("duplicate Add succeeded")
+	}
+	if err := m.Extern("main", 1); err == nil {
+		t.Error("Extern of a program name succeeded")
+	}
+
+	obj, err := m.Assemble(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Symbol{
+		{Name: "main", Offset: 0, Size: 17},
+		{Name: "helper", Offset: 32, Size: 1},
+		{Name: "ext", Offset: 48, Size: 13, Extern: true},
+	}
+	if len(obj.Symbols) != len(want) {
+		t.Fatalf("Symbols=%+v, want %+v", obj.Symbols, want)
+	}
+	for i, s := range obj.Symbols {
+		if s != want[i] {
+			t.Errorf("Symbols[%d]=%+v, want %+v", i, s, want[i])
+		}
+	}
+	if s, ok := obj.Lookup("helper"); !ok || s.Offset != 32 {
+		t.Errorf("Lookup(helper)=%+v, %v", s, ok)
+	}
+
+	// Each CALL is E8 with a rel32 from the end of the instruction.
+	rel := func(off int) int {
+		if obj.Code[off] != 0xe8 {
+			t.Fatalf("byte %d is %#x, not CALL", off, obj.Code[off])
+		}
+		return off + 5 + int(int32(binary.LittleEndian.Uint32(obj.Code[off+1:])))
+	}
+	if got := rel(0); got != 32 {
+		t.Errorf("CALL helper goes to %d, want 32", got)
+	}
+	if got := rel(5); got != 16 {
+		t.Errorf("CALL local goes to %d, want 16", got)
+	}
+	if got := rel(10); got != 48 {
+		t.Errorf("CALL ext goes to %d, want 48", got)
+	}
+	stub := []byte{0x49, 0xbb, 0x88, 0x77, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x41, 0xff, 0xe3}
+	if got := obj.Code[48:]; !bytes.Equal(got, stub) {
+		t.Errorf("stub=%x, want %x", got, stub)
+	}
+	if got := obj.Code[17:32]; !bytes.Equal(got, bytes.Repeat([]byte{0xcc}, 15)) {
+		t.Errorf("padding=%x, want INT3", got)
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	var m Module
+	m.Add("main", Program{{Op: CALL, To: LabelAddr("missing")}, {Op: RET}})
+	if _, err := m.Assemble(Options{}); err == nil || !strings.Contains(err.Error(), `undefined function "missing"`) {
+		t.Errorf("Assemble: %v, want undefined function", err)
+	}
+
+	var m2 Module
+	m2.Add("main", Program{{Op: JMP, To: LabelAddr("nowhere")}})
+	if _, err := m2.Assemble(Options{}); err == nil || !strings.Contains(err.Error(), `main: ins 0: undefined label "nowhere"`) {
+		t.Errorf("Assemble: %v, want undefined label", err)
+	}
+}
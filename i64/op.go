@@ -1,10 +1,19 @@
 package i64
 
+import "strings"
+
 // Op is an amd64 operation. Mnemonics closely follow the amd64 manual.
+//
+// Only the CMOVcc ops spell their condition as the Go assembler does,
+// as in CMOVQEQ: after the operand size suffix, the manual's condition
+// names are ambiguous, CMOVLE being either a 32-bit CMOVE or CMOVLE.
+// Jcc and SETcc keep the manual's condition names, such as JE and
+// SETA, except for JHI (JA).
 type Op int
 
 const (
 	LABEL Op = iota // not a real Op
+	POS             // not a real Op, see Pos.Instruction
 
 	ADD
 	OR
@@ -39,19 +48,204 @@ const (
 	IDIVL
 	IDIVQ
 
+	// Double precision shifts.
+	SHLDL
+	SHLDQ
+	SHRDL
+	SHRDQ
+
+	TESTB
+	TESTL
+	TESTQ
+
+	BTL
+	BTSL
+	BTRL
+	BTCL
+
+	BTQ
+	BTSQ
+	BTRQ
+	BTCQ
+
+	BSFL
+	BSRL
+	BSFQ
+	BSRQ
+
+	XADDL
+	XADDQ
+	XCHGL
+	XCHGQ
+	CMPXCHGL
+	CMPXCHGQ
+	CMPXCHG8B
+	CMPXCHG16B
+	LFENCE
+	MFENCE
+	SFENCE
+
+	CPUID
+	XGETBV
+
+	// String ops. MOVS, STOS, and LODS take a REP prefix, CMPS and SCAS
+	// take REPE or REPNE. CLD and STD set the direction they run in.
+	MOVSB
+	MOVSW
+	MOVSL
+	MOVSQ
+	STOSB
+	STOSW
+	STOSL
+	STOSQ
+	LODSB
+	LODSW
+	LODSL
+	LODSQ
+	CMPSB
+	CMPSW
+	CMPSL
+	CMPSQ
+	SCASB
+	SCASW
+	SCASL
+	SCASQ
+	CLD
+	STD
+
+	POPCNTL
+	POPCNTQ
+	LZCNTL
+	LZCNTQ
+	TZCNTL
+	TZCNTQ
+
+	// BMI1 and BMI2. The L and Q forms differ only in VEX.W.
+	ANDNL
+	ANDNQ
+	BEXTRL
+	BEXTRQ
+	BLSIL
+	BLSIQ
+	BLSMSKL
+	BLSMSKQ
+	BLSRL
+	BLSRQ
+	BZHIL
+	BZHIQ
+	MULXL
+	MULXQ
+	PDEPL
+	PDEPQ
+	PEXTL
+	PEXTQ
+	RORXL
+	RORXQ
+	SARXL
+	SARXQ
+	SHLXL
+	SHLXQ
+	SHRXL
+	SHRXQ
+
 	MOVB
 	MOVL
 	MOVQ
 
+	MOVBLSX
+	MOVBLZX
+	MOVWLSX
+	MOVWLZX
+	MOVBQSX
+	MOVBQZX
+	MOVWQSX
+	MOVWQZX
+	MOVLQSX
+
 	LEAL
 	LEAQ
 
 	CALL
 	RET
 	JMP
+
+	// Conditional jumps, in Cond order. See Cond.Jump.
+	JO
+	JNO
+	JB
+	JAE
 	JE
 	JNE
+	JBE
 	JHI
+	JS
+	JNS
+	JP
+	JNP
+	JL
+	JGE
+	JLE
+	JG
+
+	// SETcc, in Cond order. See Cond.Set.
+	SETO
+	SETNO
+	SETB
+	SETAE
+	SETE
+	SETNE
+	SETBE
+	SETA
+	SETS
+	SETNS
+	SETP
+	SETNP
+	SETL
+	SETGE
+	SETLE
+	SETG
+
+	// 32-bit CMOVcc, in Cond order. As in the Go assembler, the name is
+	// CMOVL followed by a two-letter condition, so that no name can be
+	// read as an amd64 manual mnemonic: CMOVLEQ moves if equal, CMOVLLE
+	// if less or equal. The conditions are OS, OC, CS (B), CC (AE), EQ,
+	// NE, LS (BE), HI (A), MI (S), PL (NS), PS (P), PC (NP), LT, GE, LE
+	// and GT.
+	CMOVLOS
+	CMOVLOC
+	CMOVLCS
+	CMOVLCC
+	CMOVLEQ
+	CMOVLNE
+	CMOVLLS
+	CMOVLHI
+	CMOVLMI
+	CMOVLPL
+	CMOVLPS
+	CMOVLPC
+	CMOVLLT
+	CMOVLGE
+	CMOVLLE
+	CMOVLGT
+
+	// 64-bit CMOVcc, in Cond order.
+	CMOVQOS
+	CMOVQOC
+	CMOVQCS
+	CMOVQCC
+	CMOVQEQ
+	CMOVQNE
+	CMOVQLS
+	CMOVQHI
+	CMOVQMI
+	CMOVQPL
+	CMOVQPS
+	CMOVQPC
+	CMOVQLT
+	CMOVQGE
+	CMOVQLE
+	CMOVQGT
+
 	PUSHL
 	PUSHQ
 	POPL
@@ -65,6 +259,7 @@ const (
 	MINSS
 	DIVSS
 	MAXSS
+	SQRTSS
 
 	MOVSD
 	ADDSD
@@ -73,12 +268,246 @@ const (
 	MINSD
 	DIVSD
 	MAXSD
+	SQRTSD
+
+	UCOMISS
+	UCOMISD
+	COMISS
+	COMISD
+
+	CVTSL2SS
+	CVTSQ2SS
+	CVTSL2SD
+	CVTSQ2SD
+	CVTSS2SL
+	CVTSS2SQ
+	CVTSD2SL
+	CVTSD2SQ
+	CVTTSS2SL
+	CVTTSS2SQ
+	CVTTSD2SL
+	CVTTSD2SQ
+	CVTSS2SD
+	CVTSD2SS
+
+	// Packed SSE, SSE2, SSE3, and SSE4.1 ops.
+	MOVDQU
+	MOVDQA
+	MOVUPS
+	MOVAPS
+	MOVUPD
+	MOVAPD
+
+	PADDB
+	PADDW
+	PADDD
+	PADDQ
+	PSUBB
+	PSUBW
+	PSUBD
+	PSUBQ
+	PMULLW
+	PMULLD
+	PMULUDQ
+	PMADDWD
+
+	PAND
+	PANDN
+	POR
+	PXOR
+
+	PCMPEQB
+	PCMPEQW
+	PCMPEQD
+	PCMPGTB
+	PCMPGTW
+	PCMPGTD
+	PMOVMSKB
+
+	PSHUFB
+	PSHUFD
+	SHUFPS
+
+	PSLLW
+	PSLLD
+	PSLLQ
+	PSRLW
+	PSRLD
+	PSRLQ
+	PSRAW
+	PSRAD
+	PSLLDQ
+	PSRLDQ
+
+	PUNPCKLBW
+	PUNPCKLWD
+	PUNPCKLDQ
+	PUNPCKLQDQ
+	PUNPCKHBW
+	PUNPCKHWD
+	PUNPCKHDQ
+	PUNPCKHQDQ
+	UNPCKLPS
+	UNPCKHPS
+	UNPCKLPD
+	UNPCKHPD
+
+	ADDPS
+	MULPS
+	SUBPS
+	MINPS
+	DIVPS
+	MAXPS
+	SQRTPS
+	ADDPD
+	MULPD
+	SUBPD
+	MINPD
+	DIVPD
+	MAXPD
+	SQRTPD
+	ANDPS
+	ANDNPS
+	ORPS
+	XORPS
+	ANDPD
+	ANDNPD
+	ORPD
+	XORPD
+	HADDPS
+	HADDPD
+
+	PMINSB
+	PMINSW
+	PMINSD
+	PMAXSB
+	PMAXSW
+	PMAXSD
+	PMINUB
+	PMINUW
+	PMINUD
+	PMAXUB
+	PMAXUW
+	PMAXUD
+	PTEST
+	PBLENDVB
+	BLENDVPS
+	BLENDVPD
+
+	// AVX and AVX2 ops. Those that take a Ymm operand are the 256-bit form.
+	VMOVDQU
+	VMOVDQA
+	VMOVUPS
+	VMOVAPS
+
+	VADDPS
+	VMULPS
+	VSUBPS
+	VMINPS
+	VDIVPS
+	VMAXPS
+	VADDPD
+	VMULPD
+	VSUBPD
+	VMINPD
+	VDIVPD
+	VMAXPD
+	VANDPS
+	VANDNPS
+	VORPS
+	VXORPS
+
+	VFMADD132PS
+	VFMADD213PS
+	VFMADD231PS
+	VFMADD132PD
+	VFMADD213PD
+	VFMADD231PD
+
+	VPADDB
+	VPADDW
+	VPADDD
+	VPADDQ
+	VPSUBB
+	VPSUBW
+	VPSUBD
+	VPSUBQ
+	VPMULLD
+	VPAND
+	VPANDN
+	VPOR
+	VPXOR
+	VPCMPEQB
+	VPCMPEQD
+	VPSHUFB
+	VPMINUB
+	VPMAXUB
+
+	VPERMD
+	VPERMPS
+	VPERMQ
+	VPERMPD
+
+	VPBROADCASTB
+	VPBROADCASTW
+	VPBROADCASTD
+	VPBROADCASTQ
+	VBROADCASTSS
+
+	VPMOVMSKB
+	VPTEST
+	VZEROUPPER
+	VZEROALL
+
+	VPBLENDVB
+	VBLENDVPS
+	VBLENDVPD
+	VINSERTI128
+	VEXTRACTI128
+	VPERM2I128
+
+	// AVX-512 ops. The AVX ops above have a 512-bit form taking Zmm
+	// operands, and these are AVX-512 only.
+	VMOVDQU8
+	VMOVDQU16
+	VMOVDQU32
+	VMOVDQU64
+	VMOVDQA32
+	VMOVDQA64
+
+	VPANDD
+	VPANDQ
+	VPORD
+	VPORQ
+	VPXORD
+	VPXORQ
+
+	VPTESTMB
+	VPTESTMD
+	VPTESTNMB
+	VPTESTNMD
+
+	VPCOMPRESSD
+	VPCOMPRESSQ
+
+	VPTERNLOGD
+	VPTERNLOGQ
+	VPCMPB
+	VPCMPUB
+	VPCMPD
+	VPCMPUD
+
+	KMOVW
+	KMOVQ
+	KORTESTW
+	KORTESTQ
 
 	lastOp
 )
 
 var opName = map[Op]string{
 	LABEL: "LABEL",
+	POS:   "POS",
 
 	ADD: "ADD",
 	OR:  "OR",
@@ -113,42 +542,459 @@ var opName = map[Op]string{
 	IDIVL: "IDIVL",
 	IDIVQ: "IDIVQ",
 
+	SHLDL: "SHLDL",
+	SHLDQ: "SHLDQ",
+	SHRDL: "SHRDL",
+	SHRDQ: "SHRDQ",
+
+	TESTB: "TESTB",
+	TESTL: "TESTL",
+	TESTQ: "TESTQ",
+
+	BTL:  "BTL",
+	BTSL: "BTSL",
+	BTRL: "BTRL",
+	BTCL: "BTCL",
+
+	BTQ:  "BTQ",
+	BTSQ: "BTSQ",
+	BTRQ: "BTRQ",
+	BTCQ: "BTCQ",
+
+	BSFL: "BSFL",
+	BSRL: "BSRL",
+	BSFQ: "BSFQ",
+	BSRQ: "BSRQ",
+
+	XADDL:      "XADDL",
+	XADDQ:      "XADDQ",
+	XCHGL:      "XCHGL",
+	XCHGQ:      "XCHGQ",
+	CMPXCHGL:   "CMPXCHGL",
+	CMPXCHGQ:   "CMPXCHGQ",
+	CMPXCHG8B:  "CMPXCHG8B",
+	CMPXCHG16B: "CMPXCHG16B",
+	LFENCE:     "LFENCE",
+	MFENCE:     "MFENCE",
+	SFENCE:     "SFENCE",
+
+	CPUID:  "CPUID",
+	XGETBV: "XGETBV",
+
+	MOVSB: "MOVSB",
+	MOVSW: "MOVSW",
+	MOVSL: "MOVSL",
+	MOVSQ: "MOVSQ",
+	STOSB: "STOSB",
+	STOSW: "STOSW",
+	STOSL: "STOSL",
+	STOSQ: "STOSQ",
+	LODSB: "LODSB",
+	LODSW: "LODSW",
+	LODSL: "LODSL",
+	LODSQ: "LODSQ",
+	CMPSB: "CMPSB",
+	CMPSW: "CMPSW",
+	CMPSL: "CMPSL",
+	CMPSQ: "CMPSQ",
+	SCASB: "SCASB",
+	SCASW: "SCASW",
+	SCASL: "SCASL",
+	SCASQ: "SCASQ",
+	CLD:   "CLD",
+	STD:   "STD",
+
+	POPCNTL: "POPCNTL",
+	POPCNTQ: "POPCNTQ",
+	LZCNTL:  "LZCNTL",
+	LZCNTQ:  "LZCNTQ",
+	TZCNTL:  "TZCNTL",
+	TZCNTQ:  "TZCNTQ",
+
+	ANDNL:   "ANDNL",
+	ANDNQ:   "ANDNQ",
+	BEXTRL:  "BEXTRL",
+	BEXTRQ:  "BEXTRQ",
+	BLSIL:   "BLSIL",
+	BLSIQ:   "BLSIQ",
+	BLSMSKL: "BLSMSKL",
+	BLSMSKQ: "BLSMSKQ",
+	BLSRL:   "BLSRL",
+	BLSRQ:   "BLSRQ",
+	BZHIL:   "BZHIL",
+	BZHIQ:   "BZHIQ",
+	MULXL:   "MULXL",
+	MULXQ:   "MULXQ",
+	PDEPL:   "PDEPL",
+	PDEPQ:   "PDEPQ",
+	PEXTL:   "PEXTL",
+	PEXTQ:   "PEXTQ",
+	RORXL:   "RORXL",
+	RORXQ:   "RORXQ",
+	SARXL:   "SARXL",
+	SARXQ:   "SARXQ",
+	SHLXL:   "SHLXL",
+	SHLXQ:   "SHLXQ",
+	SHRXL:   "SHRXL",
+	SHRXQ:   "SHRXQ",
+
 	MOVB: "MOVB",
 	MOVL: "MOVL",
 	MOVQ: "MOVQ",
 
+	MOVBLSX: "MOVBLSX",
+	MOVBLZX: "MOVBLZX",
+	MOVWLSX: "MOVWLSX",
+	MOVWLZX: "MOVWLZX",
+	MOVBQSX: "MOVBQSX",
+	MOVBQZX: "MOVBQZX",
+	MOVWQSX: "MOVWQSX",
+	MOVWQZX: "MOVWQZX",
+	MOVLQSX: "MOVLQSX",
+
 	LEAL: "LEAL",
 	LEAQ: "LEAQ",
 
-	CALL:  "CALL",
-	RET:   "RET",
-	JMP:   "JMP",
-	JE:    "JE",
-	JNE:   "JNE",
-	JHI:   "JHI",
+	CALL: "CALL",
+	RET:  "RET",
+	JMP:  "JMP",
+
+	JO:  "JO",
+	JNO: "JNO",
+	JB:  "JB",
+	JAE: "JAE",
+	JE:  "JE",
+	JNE: "JNE",
+	JBE: "JBE",
+	JHI: "JHI",
+	JS:  "JS",
+	JNS: "JNS",
+	JP:  "JP",
+	JNP: "JNP",
+	JL:  "JL",
+	JGE: "JGE",
+	JLE: "JLE",
+	JG:  "JG",
+
+	SETO:  "SETO",
+	SETNO: "SETNO",
+	SETB:  "SETB",
+	SETAE: "SETAE",
+	SETE:  "SETE",
+	SETNE: "SETNE",
+	SETBE: "SETBE",
+	SETA:  "SETA",
+	SETS:  "SETS",
+	SETNS: "SETNS",
+	SETP:  "SETP",
+	SETNP: "SETNP",
+	SETL:  "SETL",
+	SETGE: "SETGE",
+	SETLE: "SETLE",
+	SETG:  "SETG",
+
+	CMOVLOS: "CMOVLOS",
+	CMOVLOC: "CMOVLOC",
+	CMOVLCS: "CMOVLCS",
+	CMOVLCC: "CMOVLCC",
+	CMOVLEQ: "CMOVLEQ",
+	CMOVLNE: "CMOVLNE",
+	CMOVLLS: "CMOVLLS",
+	CMOVLHI: "CMOVLHI",
+	CMOVLMI: "CMOVLMI",
+	CMOVLPL: "CMOVLPL",
+	CMOVLPS: "CMOVLPS",
+	CMOVLPC: "CMOVLPC",
+	CMOVLLT: "CMOVLLT",
+	CMOVLGE: "CMOVLGE",
+	CMOVLLE: "CMOVLLE",
+	CMOVLGT: "CMOVLGT",
+
+	CMOVQOS: "CMOVQOS",
+	CMOVQOC: "CMOVQOC",
+	CMOVQCS: "CMOVQCS",
+	CMOVQCC: "CMOVQCC",
+	CMOVQEQ: "CMOVQEQ",
+	CMOVQNE: "CMOVQNE",
+	CMOVQLS: "CMOVQLS",
+	CMOVQHI: "CMOVQHI",
+	CMOVQMI: "CMOVQMI",
+	CMOVQPL: "CMOVQPL",
+	CMOVQPS: "CMOVQPS",
+	CMOVQPC: "CMOVQPC",
+	CMOVQLT: "CMOVQLT",
+	CMOVQGE: "CMOVQGE",
+	CMOVQLE: "CMOVQLE",
+	CMOVQGT: "CMOVQGT",
+
 	PUSHL: "PUSHL",
 	PUSHQ: "PUSHQ",
 	POPL:  "POPL",
 	POPQ:  "POPQ",
 	LEA:   "LEA",
 
-	MOVSS: "MOVSS",
-	ADDSS: "ADDSS",
-	MULSS: "MULSS",
-	SUBSS: "SUBSS",
-	MINSS: "MINSS",
-	DIVSS: "DIVSS",
-	MAXSS: "MAXSS",
-
-	MOVSD: "MOVSD",
-	ADDSD: "ADDSD",
-	MULSD: "MULSD",
-	SUBSD: "SUBSD",
-	MINSD: "MINSD",
-	DIVSD: "DIVSD",
-	MAXSD: "MAXSD",
+	MOVSS:  "MOVSS",
+	ADDSS:  "ADDSS",
+	MULSS:  "MULSS",
+	SUBSS:  "SUBSS",
+	MINSS:  "MINSS",
+	DIVSS:  "DIVSS",
+	MAXSS:  "MAXSS",
+	SQRTSS: "SQRTSS",
+
+	MOVSD:  "MOVSD",
+	ADDSD:  "ADDSD",
+	MULSD:  "MULSD",
+	SUBSD:  "SUBSD",
+	MINSD:  "MINSD",
+	DIVSD:  "DIVSD",
+	MAXSD:  "MAXSD",
+	SQRTSD: "SQRTSD",
+
+	UCOMISS: "UCOMISS",
+	UCOMISD: "UCOMISD",
+	COMISS:  "COMISS",
+	COMISD:  "COMISD",
+
+	CVTSL2SS:  "CVTSL2SS",
+	CVTSQ2SS:  "CVTSQ2SS",
+	CVTSL2SD:  "CVTSL2SD",
+	CVTSQ2SD:  "CVTSQ2SD",
+	CVTSS2SL:  "CVTSS2SL",
+	CVTSS2SQ:  "CVTSS2SQ",
+	CVTSD2SL:  "CVTSD2SL",
+	CVTSD2SQ:  "CVTSD2SQ",
+	CVTTSS2SL: "CVTTSS2SL",
+	CVTTSS2SQ: "CVTTSS2SQ",
+	CVTTSD2SL: "CVTTSD2SL",
+	CVTTSD2SQ: "CVTTSD2SQ",
+	CVTSS2SD:  "CVTSS2SD",
+	CVTSD2SS:  "CVTSD2SS",
+
+	MOVDQU: "MOVDQU",
+	MOVDQA: "MOVDQA",
+	MOVUPS: "MOVUPS",
+	MOVAPS: "MOVAPS",
+	MOVUPD: "MOVUPD",
+	MOVAPD: "MOVAPD",
+
+	PADDB:   "PADDB",
+	PADDW:   "PADDW",
+	PADDD:   "PADDD",
+	PADDQ:   "PADDQ",
+	PSUBB:   "PSUBB",
+	PSUBW:   "PSUBW",
+	PSUBD:   "PSUBD",
+	PSUBQ:   "PSUBQ",
+	PMULLW:  "PMULLW",
+	PMULLD:  "PMULLD",
+	PMULUDQ: "PMULUDQ",
+	PMADDWD: "PMADDWD",
+
+	PAND:  "PAND",
+	PANDN: "PANDN",
+	POR:   "POR",
+	PXOR:  "PXOR",
+
+	PCMPEQB:  "PCMPEQB",
+	PCMPEQW:  "PCMPEQW",
+	PCMPEQD:  "PCMPEQD",
+	PCMPGTB:  "PCMPGTB",
+	PCMPGTW:  "PCMPGTW",
+	PCMPGTD:  "PCMPGTD",
+	PMOVMSKB: "PMOVMSKB",
+
+	PSHUFB: "PSHUFB",
+	PSHUFD: "PSHUFD",
+	SHUFPS: "SHUFPS",
+
+	PSLLW:  "PSLLW",
+	PSLLD:  "PSLLD",
+	PSLLQ:  "PSLLQ",
+	PSRLW:  "PSRLW",
+	PSRLD:  "PSRLD",
+	PSRLQ:  "PSRLQ",
+	PSRAW:  "PSRAW",
+	PSRAD:  "PSRAD",
+	PSLLDQ: "PSLLDQ",
+	PSRLDQ: "PSRLDQ",
+
+	PUNPCKLBW:  "PUNPCKLBW",
+	PUNPCKLWD:  "PUNPCKLWD",
+	PUNPCKLDQ:  "PUNPCKLDQ",
+	PUNPCKLQDQ: "PUNPCKLQDQ",
+	PUNPCKHBW:  "PUNPCKHBW",
+	PUNPCKHWD:  "PUNPCKHWD",
+	PUNPCKHDQ:  "PUNPCKHDQ",
+	PUNPCKHQDQ: "PUNPCKHQDQ",
+	UNPCKLPS:   "UNPCKLPS",
+	UNPCKHPS:   "UNPCKHPS",
+	UNPCKLPD:   "UNPCKLPD",
+	UNPCKHPD:   "UNPCKHPD",
+
+	ADDPS:  "ADDPS",
+	MULPS:  "MULPS",
+	SUBPS:  "SUBPS",
+	MINPS:  "MINPS",
+	DIVPS:  "DIVPS",
+	MAXPS:  "MAXPS",
+	SQRTPS: "SQRTPS",
+	ADDPD:  "ADDPD",
+	MULPD:  "MULPD",
+	SUBPD:  "SUBPD",
+	MINPD:  "MINPD",
+	DIVPD:  "DIVPD",
+	MAXPD:  "MAXPD",
+	SQRTPD: "SQRTPD",
+	ANDPS:  "ANDPS",
+	ANDNPS: "ANDNPS",
+	ORPS:   "ORPS",
+	XORPS:  "XORPS",
+	ANDPD:  "ANDPD",
+	ANDNPD: "ANDNPD",
+	ORPD:   "ORPD",
+	XORPD:  "XORPD",
+	HADDPS: "HADDPS",
+	HADDPD: "HADDPD",
+
+	PMINSB:   "PMINSB",
+	PMINSW:   "PMINSW",
+	PMINSD:   "PMINSD",
+	PMAXSB:   "PMAXSB",
+	PMAXSW:   "PMAXSW",
+	PMAXSD:   "PMAXSD",
+	PMINUB:   "PMINUB",
+	PMINUW:   "PMINUW",
+	PMINUD:   "PMINUD",
+	PMAXUB:   "PMAXUB",
+	PMAXUW:   "PMAXUW",
+	PMAXUD:   "PMAXUD",
+	PTEST:    "PTEST",
+	PBLENDVB: "PBLENDVB",
+	BLENDVPS: "BLENDVPS",
+	BLENDVPD: "BLENDVPD",
+
+	VMOVDQU: "VMOVDQU",
+	VMOVDQA: "VMOVDQA",
+	VMOVUPS: "VMOVUPS",
+	VMOVAPS: "VMOVAPS",
+
+	VADDPS:  "VADDPS",
+	VMULPS:  "VMULPS",
+	VSUBPS:  "VSUBPS",
+	VMINPS:  "VMINPS",
+	VDIVPS:  "VDIVPS",
+	VMAXPS:  "VMAXPS",
+	VADDPD:  "VADDPD",
+	VMULPD:  "VMULPD",
+	VSUBPD:  "VSUBPD",
+	VMINPD:  "VMINPD",
+	VDIVPD:  "VDIVPD",
+	VMAXPD:  "VMAXPD",
+	VANDPS:  "VANDPS",
+	VANDNPS: "VANDNPS",
+	VORPS:   "VORPS",
+	VXORPS:  "VXORPS",
+
+	VFMADD132PS: "VFMADD132PS",
+	VFMADD213PS: "VFMADD213PS",
+	VFMADD231PS: "VFMADD231PS",
+	VFMADD132PD: "VFMADD132PD",
+	VFMADD213PD: "VFMADD213PD",
+	VFMADD231PD: "VFMADD231PD",
+
+	VPADDB:   "VPADDB",
+	VPADDW:   "VPADDW",
+	VPADDD:   "VPADDD",
+	VPADDQ:   "VPADDQ",
+	VPSUBB:   "VPSUBB",
+	VPSUBW:   "VPSUBW",
+	VPSUBD:   "VPSUBD",
+	VPSUBQ:   "VPSUBQ",
+	VPMULLD:  "VPMULLD",
+	VPAND:    "VPAND",
+	VPANDN:   "VPANDN",
+	VPOR:     "VPOR",
+	VPXOR:    "VPXOR",
+	VPCMPEQB: "VPCMPEQB",
+	VPCMPEQD: "VPCMPEQD",
+	VPSHUFB:  "VPSHUFB",
+	VPMINUB:  "VPMINUB",
+	VPMAXUB:  "VPMAXUB",
+
+	VPERMD:  "VPERMD",
+	VPERMPS: "VPERMPS",
+	VPERMQ:  "VPERMQ",
+	VPERMPD: "VPERMPD",
+
+	VPBROADCASTB: "VPBROADCASTB",
+	VPBROADCASTW: "VPBROADCASTW",
+	VPBROADCASTD: "VPBROADCASTD",
+	VPBROADCASTQ: "VPBROADCASTQ",
+	VBROADCASTSS: "VBROADCASTSS",
+
+	VPMOVMSKB:  "VPMOVMSKB",
+	VPTEST:     "VPTEST",
+	VZEROUPPER: "VZEROUPPER",
+	VZEROALL:   "VZEROALL",
+
+	VPBLENDVB:    "VPBLENDVB",
+	VBLENDVPS:    "VBLENDVPS",
+	VBLENDVPD:    "VBLENDVPD",
+	VINSERTI128:  "VINSERTI128",
+	VEXTRACTI128: "VEXTRACTI128",
+	VPERM2I128:   "VPERM2I128",
+
+	VMOVDQU8:  "VMOVDQU8",
+	VMOVDQU16: "VMOVDQU16",
+	VMOVDQU32: "VMOVDQU32",
+	VMOVDQU64: "VMOVDQU64",
+	VMOVDQA32: "VMOVDQA32",
+	VMOVDQA64: "VMOVDQA64",
+
+	VPANDD: "VPANDD",
+	VPANDQ: "VPANDQ",
+	VPORD:  "VPORD",
+	VPORQ:  "VPORQ",
+	VPXORD: "VPXORD",
+	VPXORQ: "VPXORQ",
+
+	VPTESTMB:  "VPTESTMB",
+	VPTESTMD:  "VPTESTMD",
+	VPTESTNMB: "VPTESTNMB",
+	VPTESTNMD: "VPTESTNMD",
+
+	VPCOMPRESSD: "VPCOMPRESSD",
+	VPCOMPRESSQ: "VPCOMPRESSQ",
+
+	VPTERNLOGD: "VPTERNLOGD",
+	VPTERNLOGQ: "VPTERNLOGQ",
+	VPCMPB:     "VPCMPB",
+	VPCMPUB:    "VPCMPUB",
+	VPCMPD:     "VPCMPD",
+	VPCMPUD:    "VPCMPUD",
+
+	KMOVW:    "KMOVW",
+	KMOVQ:    "KMOVQ",
+	KORTESTW: "KORTESTW",
+	KORTESTQ: "KORTESTQ",
 }
 
 func (op Op) String() string {
-	return opName[op]
+	var names []string
+	for _, p := range prefixes {
+		if op&p.op != 0 {
+			names = append(names, p.name)
+		}
+	}
+	if op.Base() != LABEL || len(names) == 0 {
+		name := opName[op.Base()]
+		for _, m := range modifiers {
+			if op&m.op != 0 {
+				name += m.name
+			}
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " ")
 }
@@ -1,64 +1,67 @@
 package i64
 
+import (
+	"fmt"
+
+	"github.com/crawshaw/asm/cpu"
+)
+
 // optab is a table of Op+Reg+Reg combinations matched against reprenstation data
 // (op code, prefix requirements, etc). Some of the table is listed directly, and
 // some of the more repetitive sections of the table are generated by init.
 var optab = map[opKey]opVal{
-	opKey{CMPQ, Reg, Reg}:     opVal{c1: 0x3b, rex: true},
-	opKey{CMPQ, Reg, Ind}:     opVal{c1: 0x3b, rex: true},
-	opKey{PUSHQ, Imm32, None}: opVal{c1: 0x68, mod: modNone},
-	opKey{PUSHQ, Imm8, None}:  opVal{c1: 0x6a, mod: modNone},
-	opKey{PUSHQ, Reg, None}:   opVal{c1: 0x50, addReg: true, mod: modNone},
-	opKey{POPQ, None, Reg}:    opVal{c1: 0x58, addReg: true, mod: modNone},
-
-	opKey{JE, None, Rel8}:   opVal{c1: 0x74, mod: modNone},
-	opKey{JE, None, Rel16}:  opVal{c1: 0x0f, c2: 0x84, mod: modNone},
-	opKey{JE, None, Rel32}:  opVal{c1: 0x0f, c2: 0x84, mod: modNone},
-	opKey{JNE, None, Rel8}:  opVal{c1: 0x75, mod: modNone},
-	opKey{JNE, None, Rel16}: opVal{c1: 0x0f, c2: 0x85, mod: modNone},
-	opKey{JNE, None, Rel32}: opVal{c1: 0x0f, c2: 0x85, mod: modNone},
-	opKey{JHI, None, Rel8}:  opVal{c1: 0x77, mod: modNone},
-	opKey{JHI, None, Rel16}: opVal{c1: 0x0f, c2: 0x87, mod: modNone},
-	opKey{JHI, None, Rel32}: opVal{c1: 0x0f, c2: 0x87, mod: modNone},
-
-	opKey{MOVB, Reg, Reg}: opVal{c1: 0x8a},
-	opKey{MOVB, Ind, Reg}: opVal{c1: 0x8a},
-	opKey{MOVB, Reg, Ind}: opVal{c1: 0x88},
-	opKey{MOVL, Reg, Reg}: opVal{c1: 0x8b},
-	opKey{MOVL, Ind, Reg}: opVal{c1: 0x8b},
-	opKey{MOVL, Reg, Ind}: opVal{c1: 0x89},
-	opKey{MOVQ, Reg, Reg}: opVal{c1: 0x8b, rex: true},
-	opKey{MOVQ, Ind, Reg}: opVal{c1: 0x8b, rex: true},
-	opKey{MOVQ, Reg, Ind}: opVal{c1: 0x89, rex: true},
-
-	opKey{LEAL, Reg, Ind}: opVal{c1: 0x8d},
-	opKey{LEAQ, Reg, Ind}: opVal{c1: 0x8d, rex: true},
-
-	opKey{RET, None, None}: opVal{c1: 0xc3, mod: modNone},
-	opKey{MOVQ, Imm8, Reg}: opVal{c1: 0xc6, rex: true, mod: mod0},
-	opKey{MOVQ, Imm8, Ind}: opVal{c1: 0xc6, rex: true, mod: mod0},
-
-	opKey{MOVSS, Ind, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x10},
-	opKey{MOVSS, Xmm, Ind}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x11},
-	opKey{ADDSS, Xmm, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x58},
-	opKey{MULSS, Xmm, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x59},
-	opKey{SUBSS, Xmm, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x5c},
-	opKey{MINSS, Xmm, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x5d},
-	opKey{DIVSS, Xmm, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x5e},
-	opKey{MAXSS, Xmm, Xmm}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x5f},
-
-	opKey{MOVSD, Ind, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x10},
-	opKey{MOVSD, Xmm, Ind}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x11},
-	opKey{ADDSD, Xmm, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x58},
-	opKey{MULSD, Xmm, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x59},
-	opKey{SUBSD, Xmm, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x5c},
-	opKey{MINSD, Xmm, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x5d},
-	opKey{DIVSD, Xmm, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x5e},
-	opKey{MAXSD, Xmm, Xmm}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x5f},
+	opKey{PUSHQ, Imm32, None, noArgs}: opVal{c1: 0x68, mod: modNone, iuse: regSP, idef: regSP},
+	opKey{PUSHQ, Imm8, None, noArgs}:  opVal{c1: 0x6a, mod: modNone, iuse: regSP, idef: regSP},
+	opKey{PUSHQ, Reg, None, noArgs}:   opVal{c1: 0x50, addReg: true, mod: modNone, iuse: regSP, idef: regSP},
+	opKey{POPQ, None, Reg, noArgs}:    opVal{c1: 0x58, addReg: true, mod: modNone, dst: AccessWrite, iuse: regSP, idef: regSP},
+
+	opKey{MOVB, Reg, Reg, noArgs}: opVal{c1: 0x88, byteReg: true},
+	opKey{MOVB, Ind, Reg, noArgs}: opVal{c1: 0x8a, byteReg: true},
+	opKey{MOVB, Reg, Ind, noArgs}: opVal{c1: 0x88, byteReg: true, dst: AccessWrite},
+	opKey{MOVL, Reg, Reg, noArgs}: opVal{c1: 0x89, dst: AccessWrite},
+	opKey{MOVL, Ind, Reg, noArgs}: opVal{c1: 0x8b, dst: AccessWrite},
+	opKey{MOVL, Reg, Ind, noArgs}: opVal{c1: 0x89, dst: AccessWrite},
+	opKey{MOVQ, Reg, Reg, noArgs}: opVal{c1: 0x89, rex: true, dst: AccessWrite},
+	opKey{MOVQ, Ind, Reg, noArgs}: opVal{c1: 0x8b, rex: true, dst: AccessWrite},
+	opKey{MOVQ, Reg, Ind, noArgs}: opVal{c1: 0x89, rex: true, dst: AccessWrite},
+
+	// LEA is encoded with the destination in From.
+	opKey{LEAL, Reg, Ind, noArgs}: opVal{c1: 0x8d, access: []Access{AccessWrite, AccessRead}},
+	opKey{LEAQ, Reg, Ind, noArgs}: opVal{c1: 0x8d, rex: true, access: []Access{AccessWrite, AccessRead}},
+
+	opKey{RET, None, None, noArgs}: opVal{c1: 0xc3, mod: modNone, iuse: regSP, idef: regSP},
+	opKey{MOVB, Imm8, Reg, noArgs}: opVal{c1: 0xc6, mod: mod0, byteReg: true},
+	opKey{MOVB, Imm8, Ind, noArgs}: opVal{c1: 0xc6, mod: mod0, dst: AccessWrite},
+
+	opKey{MOVSS, Ind, Xmm, noArgs}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x10, dst: AccessWrite}, // clears the upper elements
+	opKey{MOVSS, Xmm, Xmm, noArgs}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x10, regTo: true},
+	opKey{MOVSS, Xmm, Ind, noArgs}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x11, dst: AccessWrite},
+
+	opKey{MOVSD, Ind, Xmm, noArgs}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x10, dst: AccessWrite},
+	opKey{MOVSD, Xmm, Xmm, noArgs}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x10, regTo: true},
+	opKey{MOVSD, Xmm, Ind, noArgs}: opVal{c0: 0xf2, c1: 0x0f, c2: 0x11, dst: AccessWrite},
+
+	opKey{MOVL, Reg, Xmm, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0x6e, regTo: true, dst: AccessWrite},
+	opKey{MOVL, Ind, Xmm, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0x6e, dst: AccessWrite},
+	opKey{MOVL, Xmm, Reg, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0x7e, dst: AccessWrite},
+	opKey{MOVL, Xmm, Ind, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0x7e, dst: AccessWrite},
+	opKey{MOVQ, Reg, Xmm, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0x6e, rex: true, regTo: true, dst: AccessWrite},
+	opKey{MOVQ, Xmm, Reg, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0x7e, rex: true, dst: AccessWrite},
+	opKey{MOVQ, Ind, Xmm, noArgs}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x7e, dst: AccessWrite},
+	opKey{MOVQ, Xmm, Xmm, noArgs}: opVal{c0: 0xf3, c1: 0x0f, c2: 0x7e, regTo: true, dst: AccessWrite},
+	opKey{MOVQ, Xmm, Ind, noArgs}: opVal{c0: 0x66, c1: 0x0f, c2: 0xd6, dst: AccessWrite},
 }
 
+// Registers used implicitly.
+var (
+	regSP = []Register{SP}
+	regAX = []Register{AX}
+	regDX = []Register{DX}
+	regX0 = []Register{X0}
+)
+
 func init() {
-	regs := []AddrType{Reg, Ind, Xmm, Imm8, Imm16, Imm32, Imm64, Rel8, Rel16, Rel32}
+	regs := []AddrType{Reg, Ind, Xmm, Imm8, Imm16, Imm32, Imm64, Rel8, Rel16, Rel32, List, Ymm, Zmm, Kreg}
 	expand := func(r AddrType) (addrs []AddrType) {
 		if r == None {
 			return []AddrType{None}
@@ -70,60 +73,776 @@ func init() {
 		}
 		return addrs
 	}
+	// expandArgs returns every combination of the Args types in c.args.
+	expandArgs := func(c opVal) []argTypes {
+		list := []argTypes{noArgs}
+		for i, r := range c.args {
+			var next []argTypes
+			for _, args := range list {
+				for _, reg := range expand(r) {
+					args[i] = reg
+					next = append(next, args)
+				}
+			}
+			list = next
+		}
+		return list
+	}
 	add := func(op Op, r1, r2 AddrType, c opVal) {
 		regs2 := expand(r2)
 		for _, reg1 := range expand(r1) {
 			for _, reg2 := range regs2 {
-				optab[opKey{op, reg1, reg2}] = c
+				if reg1 != List {
+					optab[opKey{op, reg1, reg2, noArgs}] = c
+					continue
+				}
+				for _, args := range expandArgs(c) {
+					optab[opKey{op, reg1, reg2, args}] = c
+				}
 			}
 		}
 	}
+	// arithFlags returns the use of To and of the flags by ADD..CMP.
+	arithFlags := func(i Op) (dst, flags Access) {
+		switch i {
+		case CMP:
+			return AccessRead, AccessWrite
+		case ADC, SBB:
+			return 0, AccessReadWrite
+		}
+		return 0, AccessWrite
+	}
 	for i := ADD; i <= CMP; i++ {
 		m := mod0 + modBits(i-ADD)
-		add(i, Imm8, Reg|Ind, opVal{c1: 0x80, mod: m})
+		lock := LOCK
+		if i == CMP {
+			lock = 0
+		}
+		dst, flags := arithFlags(i)
+		add(i, Imm8, Reg|Ind, opVal{c1: 0x80, byteReg: true, mod: m, prefix: lock, dst: dst, flags: flags})
+	}
+	// makeMod puts From in ModRM.reg unless From is Ind. The arithmetic
+	// ops write to To, so they use the 01+opOff form (r/m op= reg) when
+	// To is in ModRM.rm, and 03+opOff (reg op= r/m) otherwise. CMP
+	// computes From-To, so it is the other way around.
+	arith := func(i, first Op, rex bool) {
+		m := mod0 + modBits(i-first)
+		lock := LOCK
+		rm, reg := uint8(0x01), uint8(0x03)
+		if i-first == CMP-ADD {
+			lock = 0
+			rm, reg = reg, rm
+		}
+		dst, flags := arithFlags(ADD + i - first)
+		add(i, Imm32, Reg|Ind, opVal{c1: 0x81, rex: rex, mod: m, prefix: lock, dst: dst, flags: flags})
+		add(i, Imm8, Reg|Ind, opVal{c1: 0x83, rex: rex, mod: m, prefix: lock, dst: dst, flags: flags})
+		opOff := uint8(i-first) * 8
+		zero := i-first == SUB-ADD || i-first == XOR-ADD
+		add(i, Reg, Reg|Ind, opVal{c1: opOff + rm, rex: rex, prefix: lock, dst: dst, flags: flags, zeroIdiom: zero})
+		add(i, Ind, Reg, opVal{c1: opOff + reg, rex: rex, dst: dst, flags: flags})
 	}
 	for i := ADDL; i <= CMPL; i++ {
-		m := mod0 + modBits(i-ADDL)
-		add(i, Imm16|Imm32, Reg|Ind, opVal{c1: 0x81, mod: m})
-		add(i, Imm8, Reg|Ind, opVal{c1: 0x83, mod: m})
-		opOff := uint8(i-ADDQ) * 8
-		add(i, Reg|Ind, Reg, opVal{c1: opOff + 0x01})
-		add(i, Reg, Ind, opVal{c1: opOff + 0x03})
+		arith(i, ADDL, false)
 	}
 	for i := ADDQ; i <= CMPQ; i++ {
-		m := mod0 + modBits(i-ADDQ)
-		add(i, Imm16|Imm32, Reg|Ind, opVal{c1: 0x81, rex: true, mod: m})
-		add(i, Imm8, Reg|Ind, opVal{c1: 0x83, rex: true, mod: m})
-		opOff := uint8(i-ADDQ) * 8
-		add(i, Reg|Ind, Reg, opVal{c1: opOff + 0x01, rex: true})
-		add(i, Reg, Ind, opVal{c1: opOff + 0x03, rex: true})
-	}
-	add(MOVL, Imm16|Imm32|Imm64, Reg, opVal{c1: 0xb8, addReg: true, mod: modNone})
-	add(MOVQ, Imm16|Imm32, Reg|Ind, opVal{c1: 0xc7, rex: true, mod: mod0})
-	add(MOVQ, Imm64, Reg, opVal{c1: 0xb8, addReg: true, rex: true, mod: modNone})
-	add(IMULL, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xaf})
-	add(IMULQ, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xaf, rex: true})
-	add(IDIVL, None, Reg|Ind, opVal{c1: 0xf7, mod: mod7})
-	add(IDIVQ, None, Reg|Ind, opVal{c1: 0xf7, rex: true, mod: mod7})
-	add(CALL, None, Rel16|Rel32, opVal{c1: 0xe8, mod: modNone})
-	add(JMP, None, Rel16|Rel32, opVal{c1: 0xe9, mod: modNone})
+		arith(i, ADDQ, true)
+	}
+	add(MOVL, Imm32, Reg, opVal{c1: 0xb8, addReg: true, mod: modNone, dst: AccessWrite})
+	add(MOVQ, Imm32, Reg|Ind, opVal{c1: 0xc7, rex: true, mod: mod0, dst: AccessWrite})
+	add(MOVQ, Imm64, Reg, opVal{c1: 0xb8, addReg: true, rex: true, mod: modNone, dst: AccessWrite})
+	add(MOVBLSX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbe, regTo: true, byteReg: true, dst: AccessWrite})
+	add(MOVBLZX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xb6, regTo: true, byteReg: true, dst: AccessWrite})
+	add(MOVWLSX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbf, regTo: true, dst: AccessWrite})
+	add(MOVWLZX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xb7, regTo: true, dst: AccessWrite})
+	add(MOVBQSX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbe, rex: true, regTo: true, byteReg: true, dst: AccessWrite})
+	add(MOVBQZX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xb6, rex: true, regTo: true, byteReg: true, dst: AccessWrite})
+	add(MOVWQSX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbf, rex: true, regTo: true, dst: AccessWrite})
+	add(MOVWQZX, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xb7, rex: true, regTo: true, dst: AccessWrite})
+	add(MOVLQSX, Reg|Ind, Reg, opVal{c1: 0x63, rex: true, regTo: true, dst: AccessWrite})
+	// The two-operand IMUL is encoded with the destination in From.
+	add(IMULL, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xaf, access: []Access{AccessReadWrite, AccessRead}, flags: AccessWrite})
+	add(IMULQ, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xaf, rex: true, access: []Access{AccessReadWrite, AccessRead}, flags: AccessWrite})
+	for i, rex := range []bool{false, true} {
+		// To = imm * From.
+		imul := IMULL + Op(i)
+		add(imul, List, Reg, opVal{c1: 0x6b, rex: rex, regTo: true, layout: argsImmRMReg, args: []AddrType{Imm8, Reg | Ind}, flags: AccessWrite})
+		add(imul, List, Reg, opVal{c1: 0x69, rex: rex, regTo: true, layout: argsImmRMReg, args: []AddrType{Imm32, Reg | Ind}, flags: AccessWrite})
+
+		// To is shifted by imm or CL, filling from the Reg operand.
+		// A zero count leaves the flags as they were.
+		for j, c2 := range []uint8{0xa4, 0xac} {
+			op := SHLDL + Op(2*j+i)
+			add(op, List, Reg|Ind, opVal{c1: 0x0f, c2: c2, rex: rex, layout: argsImmRegRM, args: []AddrType{Imm8, Reg}, flags: AccessReadWrite})
+			add(op, List, Reg|Ind, opVal{c1: 0x0f, c2: c2 + 1, rex: rex, layout: argsImmRegRM, args: []AddrType{Reg, Reg}, flags: AccessReadWrite})
+		}
+	}
+	for i, c2 := range []uint8{0x58, 0x59, 0x5c, 0x5d, 0x5e, 0x5f, 0x51} {
+		add(ADDSS+Op(i), Xmm|Ind, Xmm, opVal{c0: 0xf3, c1: 0x0f, c2: c2, regTo: true})
+		add(ADDSD+Op(i), Xmm|Ind, Xmm, opVal{c0: 0xf2, c1: 0x0f, c2: c2, regTo: true})
+	}
+	add(UCOMISS, Xmm|Ind, Xmm, opVal{c1: 0x0f, c2: 0x2e, regTo: true, dst: AccessRead, flags: AccessWrite})
+	add(UCOMISD, Xmm|Ind, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x2e, regTo: true, dst: AccessRead, flags: AccessWrite})
+	add(COMISS, Xmm|Ind, Xmm, opVal{c1: 0x0f, c2: 0x2f, regTo: true, dst: AccessRead, flags: AccessWrite})
+	add(COMISD, Xmm|Ind, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x2f, regTo: true, dst: AccessRead, flags: AccessWrite})
+	add(CVTSS2SD, Xmm|Ind, Xmm, opVal{c0: 0xf3, c1: 0x0f, c2: 0x5a, regTo: true})
+	add(CVTSD2SS, Xmm|Ind, Xmm, opVal{c0: 0xf2, c1: 0x0f, c2: 0x5a, regTo: true})
+	for i, c0 := range []uint8{0xf3, 0xf2} {
+		// Integer conversions, in SS then SD order.
+		add(CVTSL2SS+Op(i*2), Reg|Ind, Xmm, opVal{c0: c0, c1: 0x0f, c2: 0x2a, regTo: true})
+		add(CVTSQ2SS+Op(i*2), Reg|Ind, Xmm, opVal{c0: c0, c1: 0x0f, c2: 0x2a, rex: true, regTo: true})
+		add(CVTSS2SL+Op(i*2), Xmm|Ind, Reg, opVal{c0: c0, c1: 0x0f, c2: 0x2d, regTo: true, dst: AccessWrite})
+		add(CVTSS2SQ+Op(i*2), Xmm|Ind, Reg, opVal{c0: c0, c1: 0x0f, c2: 0x2d, rex: true, regTo: true, dst: AccessWrite})
+		add(CVTTSS2SL+Op(i*2), Xmm|Ind, Reg, opVal{c0: c0, c1: 0x0f, c2: 0x2c, regTo: true, dst: AccessWrite})
+		add(CVTTSS2SQ+Op(i*2), Xmm|Ind, Reg, opVal{c0: c0, c1: 0x0f, c2: 0x2c, rex: true, regTo: true, dst: AccessWrite})
+	}
+	// Packed ops beyond SSE2, which is part of the x86-64 baseline.
+	sse := map[Op]cpu.Feature{
+		HADDPS:   cpu.SSE3,
+		HADDPD:   cpu.SSE3,
+		PSHUFB:   cpu.SSSE3,
+		PMULLD:   cpu.SSE41,
+		PMINSB:   cpu.SSE41,
+		PMINSD:   cpu.SSE41,
+		PMAXSB:   cpu.SSE41,
+		PMAXSD:   cpu.SSE41,
+		PMINUW:   cpu.SSE41,
+		PMINUD:   cpu.SSE41,
+		PMAXUW:   cpu.SSE41,
+		PMAXUD:   cpu.SSE41,
+		PTEST:    cpu.SSE41,
+		PBLENDVB: cpu.SSE41,
+		BLENDVPS: cpu.SSE41,
+		BLENDVPD: cpu.SSE41,
+	}
+	// Packed ops, To op= From.
+	for _, x := range []struct {
+		op         Op
+		c0, c2, c3 uint8
+	}{
+		{PADDB, 0x66, 0xfc, 0x00},
+		{PADDW, 0x66, 0xfd, 0x00},
+		{PADDD, 0x66, 0xfe, 0x00},
+		{PADDQ, 0x66, 0xd4, 0x00},
+		{PSUBB, 0x66, 0xf8, 0x00},
+		{PSUBW, 0x66, 0xf9, 0x00},
+		{PSUBD, 0x66, 0xfa, 0x00},
+		{PSUBQ, 0x66, 0xfb, 0x00},
+		{PMULLW, 0x66, 0xd5, 0x00},
+		{PMULLD, 0x66, 0x38, 0x40},
+		{PMULUDQ, 0x66, 0xf4, 0x00},
+		{PMADDWD, 0x66, 0xf5, 0x00},
+		{PAND, 0x66, 0xdb, 0x00},
+		{PANDN, 0x66, 0xdf, 0x00},
+		{POR, 0x66, 0xeb, 0x00},
+		{PXOR, 0x66, 0xef, 0x00},
+		{PCMPEQB, 0x66, 0x74, 0x00},
+		{PCMPEQW, 0x66, 0x75, 0x00},
+		{PCMPEQD, 0x66, 0x76, 0x00},
+		{PCMPGTB, 0x66, 0x64, 0x00},
+		{PCMPGTW, 0x66, 0x65, 0x00},
+		{PCMPGTD, 0x66, 0x66, 0x00},
+		{PSHUFB, 0x66, 0x38, 0x00},
+		{PUNPCKLBW, 0x66, 0x60, 0x00},
+		{PUNPCKLWD, 0x66, 0x61, 0x00},
+		{PUNPCKLDQ, 0x66, 0x62, 0x00},
+		{PUNPCKLQDQ, 0x66, 0x6c, 0x00},
+		{PUNPCKHBW, 0x66, 0x68, 0x00},
+		{PUNPCKHWD, 0x66, 0x69, 0x00},
+		{PUNPCKHDQ, 0x66, 0x6a, 0x00},
+		{PUNPCKHQDQ, 0x66, 0x6d, 0x00},
+		{UNPCKLPS, 0x00, 0x14, 0x00},
+		{UNPCKHPS, 0x00, 0x15, 0x00},
+		{UNPCKLPD, 0x66, 0x14, 0x00},
+		{UNPCKHPD, 0x66, 0x15, 0x00},
+		{ADDPS, 0x00, 0x58, 0x00},
+		{MULPS, 0x00, 0x59, 0x00},
+		{SUBPS, 0x00, 0x5c, 0x00},
+		{MINPS, 0x00, 0x5d, 0x00},
+		{DIVPS, 0x00, 0x5e, 0x00},
+		{MAXPS, 0x00, 0x5f, 0x00},
+		{SQRTPS, 0x00, 0x51, 0x00}, // To = sqrt(From)
+		{ADDPD, 0x66, 0x58, 0x00},
+		{MULPD, 0x66, 0x59, 0x00},
+		{SUBPD, 0x66, 0x5c, 0x00},
+		{MINPD, 0x66, 0x5d, 0x00},
+		{DIVPD, 0x66, 0x5e, 0x00},
+		{MAXPD, 0x66, 0x5f, 0x00},
+		{SQRTPD, 0x66, 0x51, 0x00},
+		{ANDPS, 0x00, 0x54, 0x00},
+		{ANDNPS, 0x00, 0x55, 0x00},
+		{ORPS, 0x00, 0x56, 0x00},
+		{XORPS, 0x00, 0x57, 0x00},
+		{ANDPD, 0x66, 0x54, 0x00},
+		{ANDNPD, 0x66, 0x55, 0x00},
+		{ORPD, 0x66, 0x56, 0x00},
+		{XORPD, 0x66, 0x57, 0x00},
+		{HADDPS, 0xf2, 0x7c, 0x00},
+		{HADDPD, 0x66, 0x7c, 0x00},
+		{PMINSB, 0x66, 0x38, 0x38},
+		{PMINSW, 0x66, 0xea, 0x00},
+		{PMINSD, 0x66, 0x38, 0x39},
+		{PMAXSB, 0x66, 0x38, 0x3c},
+		{PMAXSW, 0x66, 0xee, 0x00},
+		{PMAXSD, 0x66, 0x38, 0x3d},
+		{PMINUB, 0x66, 0xda, 0x00},
+		{PMINUW, 0x66, 0x38, 0x3a},
+		{PMINUD, 0x66, 0x38, 0x3b},
+		{PMAXUB, 0x66, 0xde, 0x00},
+		{PMAXUW, 0x66, 0x38, 0x3e},
+		{PMAXUD, 0x66, 0x38, 0x3f},
+		{PTEST, 0x66, 0x38, 0x17},
+		{PBLENDVB, 0x66, 0x38, 0x10}, // mask is X0
+		{BLENDVPS, 0x66, 0x38, 0x14},
+		{BLENDVPD, 0x66, 0x38, 0x15},
+	} {
+		v := opVal{c0: x.c0, c1: 0x0f, c2: x.c2, c3: x.c3, regTo: true, feature: sse[x.op]}
+		switch x.op {
+		case SQRTPS, SQRTPD:
+			v.dst = AccessWrite
+		case PTEST:
+			v.dst, v.flags = AccessRead, AccessWrite
+		case PBLENDVB, BLENDVPS, BLENDVPD:
+			v.iuse = regX0
+		case PXOR, XORPS, XORPD:
+			v.zeroIdiom = true
+		}
+		add(x.op, Xmm|Ind, Xmm, v)
+	}
+	for _, x := range []struct {
+		op          Op
+		c0          uint8
+		load, store uint8
+	}{
+		{MOVDQU, 0xf3, 0x6f, 0x7f},
+		{MOVDQA, 0x66, 0x6f, 0x7f},
+		{MOVUPS, 0, 0x10, 0x11},
+		{MOVAPS, 0, 0x28, 0x29},
+		{MOVUPD, 0x66, 0x10, 0x11},
+		{MOVAPD, 0x66, 0x28, 0x29},
+	} {
+		add(x.op, Xmm|Ind, Xmm, opVal{c0: x.c0, c1: 0x0f, c2: x.load, regTo: true, dst: AccessWrite})
+		add(x.op, Xmm, Ind, opVal{c0: x.c0, c1: 0x0f, c2: x.store, dst: AccessWrite})
+	}
+	add(PMOVMSKB, Xmm, Reg, opVal{c0: 0x66, c1: 0x0f, c2: 0xd7, regTo: true, dst: AccessWrite})
+	add(PSHUFD, List, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x70, regTo: true, layout: argsImmRMReg, args: []AddrType{Imm8, Xmm | Ind}})
+	// SHUFPS takes its low elements from To.
+	add(SHUFPS, List, Xmm, opVal{c1: 0x0f, c2: 0xc6, regTo: true, layout: argsImmRMReg, args: []AddrType{Imm8, Xmm | Ind}, dst: AccessReadWrite})
+	for _, x := range []struct {
+		op  Op
+		c2  uint8
+		mod modBits
+	}{
+		{PSLLW, 0x71, mod6},
+		{PSLLD, 0x72, mod6},
+		{PSLLQ, 0x73, mod6},
+		{PSRLW, 0x71, mod2},
+		{PSRLD, 0x72, mod2},
+		{PSRLQ, 0x73, mod2},
+		{PSRAW, 0x71, mod4},
+		{PSRAD, 0x72, mod4},
+		{PSLLDQ, 0x73, mod7},
+		{PSRLDQ, 0x73, mod3},
+	} {
+		add(x.op, Imm8, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: x.c2, mod: x.mod})
+	}
+	// AVX and AVX2 ops. The 128-bit form operates on Xmm, the 256-bit
+	// form, with VEX.L set, on Ymm. The 128-bit form requires AVX, unless
+	// v says otherwise, and the 256-bit form requires f256.
+	avx := func(op Op, from, to AddrType, v opVal, f256 cpu.Feature) {
+		v.vex = true
+		if v.feature == 0 {
+			v.feature = cpu.AVX
+		}
+		add(op, from, to, v)
+		ymm := func(t AddrType) AddrType {
+			if t&Xmm != 0 {
+				return t&^Xmm | Ymm
+			}
+			return t
+		}
+		args := make([]AddrType, len(v.args))
+		for i, t := range v.args {
+			args[i] = ymm(t)
+		}
+		v.args = args
+		v.vexL = true
+		v.feature = f256
+		add(op, ymm(from), ymm(to), v)
+	}
+	for _, x := range []struct {
+		op         Op
+		c0, c2, c3 uint8
+		w          bool
+	}{
+		{VADDPS, 0x00, 0x58, 0x00, false},
+		{VMULPS, 0x00, 0x59, 0x00, false},
+		{VSUBPS, 0x00, 0x5c, 0x00, false},
+		{VMINPS, 0x00, 0x5d, 0x00, false},
+		{VDIVPS, 0x00, 0x5e, 0x00, false},
+		{VMAXPS, 0x00, 0x5f, 0x00, false},
+		{VADDPD, 0x66, 0x58, 0x00, false},
+		{VMULPD, 0x66, 0x59, 0x00, false},
+		{VSUBPD, 0x66, 0x5c, 0x00, false},
+		{VMINPD, 0x66, 0x5d, 0x00, false},
+		{VDIVPD, 0x66, 0x5e, 0x00, false},
+		{VMAXPD, 0x66, 0x5f, 0x00, false},
+		{VANDPS, 0x00, 0x54, 0x00, false},
+		{VANDNPS, 0x00, 0x55, 0x00, false},
+		{VORPS, 0x00, 0x56, 0x00, false},
+		{VXORPS, 0x00, 0x57, 0x00, false},
+		{VFMADD132PS, 0x66, 0x38, 0x98, false},
+		{VFMADD213PS, 0x66, 0x38, 0xa8, false},
+		{VFMADD231PS, 0x66, 0x38, 0xb8, false},
+		{VFMADD132PD, 0x66, 0x38, 0x98, true},
+		{VFMADD213PD, 0x66, 0x38, 0xa8, true},
+		{VFMADD231PD, 0x66, 0x38, 0xb8, true},
+		{VPADDB, 0x66, 0xfc, 0x00, false},
+		{VPADDW, 0x66, 0xfd, 0x00, false},
+		{VPADDD, 0x66, 0xfe, 0x00, false},
+		{VPADDQ, 0x66, 0xd4, 0x00, false},
+		{VPSUBB, 0x66, 0xf8, 0x00, false},
+		{VPSUBW, 0x66, 0xf9, 0x00, false},
+		{VPSUBD, 0x66, 0xfa, 0x00, false},
+		{VPSUBQ, 0x66, 0xfb, 0x00, false},
+		{VPMULLD, 0x66, 0x38, 0x40, false},
+		{VPAND, 0x66, 0xdb, 0x00, false},
+		{VPANDN, 0x66, 0xdf, 0x00, false},
+		{VPOR, 0x66, 0xeb, 0x00, false},
+		{VPXOR, 0x66, 0xef, 0x00, false},
+		{VPCMPEQB, 0x66, 0x74, 0x00, false},
+		{VPCMPEQD, 0x66, 0x76, 0x00, false},
+		{VPSHUFB, 0x66, 0x38, 0x00, false},
+		{VPMINUB, 0x66, 0xda, 0x00, false},
+		{VPMAXUB, 0x66, 0xde, 0x00, false},
+	} {
+		// To = vvvv op From. Integer ops on Ymm are AVX2.
+		f, f256 := cpu.AVX, cpu.AVX
+		if x.op >= VFMADD132PS && x.op <= VFMADD231PD {
+			f, f256 = cpu.FMA, cpu.FMA
+		} else if x.op >= VPADDB {
+			f256 = cpu.AVX2
+		}
+		v := opVal{c0: x.c0, c1: 0x0f, c2: x.c2, c3: x.c3, rex: x.w, regTo: true, layout: argsRMVReg, args: []AddrType{Xmm | Ind, Xmm}, feature: f}
+		switch {
+		case f == cpu.FMA:
+			v.dst = AccessReadWrite // To += vvvv * From, in some order
+		case x.op == VPXOR || x.op == VXORPS:
+			v.zeroIdiom = true
+		}
+		avx(x.op, List, Xmm, v, f256)
+	}
+	for _, x := range []struct {
+		op          Op
+		c0          uint8
+		load, store uint8
+	}{
+		{VMOVDQU, 0xf3, 0x6f, 0x7f},
+		{VMOVDQA, 0x66, 0x6f, 0x7f},
+		{VMOVUPS, 0, 0x10, 0x11},
+		{VMOVAPS, 0, 0x28, 0x29},
+	} {
+		avx(x.op, Xmm|Ind, Xmm, opVal{c0: x.c0, c1: 0x0f, c2: x.load, regTo: true, dst: AccessWrite}, cpu.AVX)
+		avx(x.op, Xmm, Ind, opVal{c0: x.c0, c1: 0x0f, c2: x.store, dst: AccessWrite}, cpu.AVX)
+	}
+	add(VPERMD, List, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x36, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, layout: argsRMVReg, args: []AddrType{Ymm | Ind, Ymm}})
+	add(VPERMPS, List, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x16, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, layout: argsRMVReg, args: []AddrType{Ymm | Ind, Ymm}})
+	add(VPERMQ, List, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: 0x00, rex: true, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, layout: argsImmRMReg, args: []AddrType{Imm8, Ymm | Ind}})
+	add(VPERMPD, List, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: 0x01, rex: true, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, layout: argsImmRMReg, args: []AddrType{Imm8, Ymm | Ind}})
+	for i, c3 := range []uint8{0x78, 0x79, 0x58, 0x59} {
+		// The source of a broadcast is Xmm or memory at either width.
+		op := VPBROADCASTB + Op(i)
+		add(op, Xmm|Ind, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: c3, vex: true, regTo: true, feature: cpu.AVX2, dst: AccessWrite})
+		add(op, Xmm|Ind, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: c3, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, dst: AccessWrite})
+	}
+	// VBROADCASTSS from memory is AVX, from a register AVX2.
+	add(VBROADCASTSS, Ind, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x18, vex: true, regTo: true, feature: cpu.AVX, dst: AccessWrite})
+	add(VBROADCASTSS, Ind, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x18, vex: true, vexL: true, regTo: true, feature: cpu.AVX, dst: AccessWrite})
+	add(VBROADCASTSS, Xmm, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x18, vex: true, regTo: true, feature: cpu.AVX2, dst: AccessWrite})
+	add(VBROADCASTSS, Xmm, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x18, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, dst: AccessWrite})
+	avx(VPMOVMSKB, Xmm, Reg, opVal{c0: 0x66, c1: 0x0f, c2: 0xd7, regTo: true, dst: AccessWrite}, cpu.AVX2)
+	avx(VPTEST, Xmm|Ind, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x17, regTo: true, dst: AccessRead, flags: AccessWrite}, cpu.AVX)
+	for _, x := range []struct {
+		op   Op
+		c3   uint8
+		f256 cpu.Feature
+	}{
+		{VPBLENDVB, 0x4c, cpu.AVX2},
+		{VBLENDVPS, 0x4a, cpu.AVX},
+		{VBLENDVPD, 0x4b, cpu.AVX},
+	} {
+		// To = bytes or elements of the first operand select between
+		// vvvv (clear) and From (set).
+		avx(x.op, List, Xmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: x.c3, regTo: true, layout: argsIs4RMVReg, args: []AddrType{Xmm, Xmm | Ind, Xmm}}, x.f256)
+	}
+	add(VINSERTI128, List, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: 0x38, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, layout: argsImmRMVReg, args: []AddrType{Imm8, Xmm | Ind, Ymm}})
+	add(VEXTRACTI128, List, Xmm|Ind, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: 0x39, vex: true, vexL: true, feature: cpu.AVX2, layout: argsImmRegRM, args: []AddrType{Imm8, Ymm}, dst: AccessWrite})
+	add(VPERM2I128, List, Ymm, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: 0x46, vex: true, vexL: true, regTo: true, feature: cpu.AVX2, layout: argsImmRMVReg, args: []AddrType{Imm8, Ymm | Ind, Ymm}})
+	add(VZEROUPPER, None, None, opVal{c1: 0x0f, c2: 0x77, vex: true, mod: modNone, feature: cpu.AVX})
+	var xmms []Register
+	for r := X0; r <= X15; r++ {
+		xmms = append(xmms, r)
+	}
+	add(VZEROALL, None, None, opVal{c1: 0x0f, c2: 0x77, vex: true, vexL: true, mod: modNone, feature: cpu.AVX, idef: xmms})
+	// AVX-512 ops, EVEX encoded. Only the 512-bit form is supported.
+	// Byte and word element ops are AVX-512BW.
+	avx512, avx512bw := cpu.AVX512F, cpu.AVX512F|cpu.AVX512BW
+	type evexOp struct {
+		op         Op
+		c0, c2, c3 uint8
+		w          bool
+		bcst       uint8
+		f          cpu.Feature
+	}
+	for _, x := range []evexOp{
+		{VADDPS, 0x00, 0x58, 0x00, false, 4, avx512},
+		{VMULPS, 0x00, 0x59, 0x00, false, 4, avx512},
+		{VSUBPS, 0x00, 0x5c, 0x00, false, 4, avx512},
+		{VMINPS, 0x00, 0x5d, 0x00, false, 4, avx512},
+		{VDIVPS, 0x00, 0x5e, 0x00, false, 4, avx512},
+		{VMAXPS, 0x00, 0x5f, 0x00, false, 4, avx512},
+		{VADDPD, 0x66, 0x58, 0x00, true, 8, avx512},
+		{VMULPD, 0x66, 0x59, 0x00, true, 8, avx512},
+		{VSUBPD, 0x66, 0x5c, 0x00, true, 8, avx512},
+		{VMINPD, 0x66, 0x5d, 0x00, true, 8, avx512},
+		{VDIVPD, 0x66, 0x5e, 0x00, true, 8, avx512},
+		{VMAXPD, 0x66, 0x5f, 0x00, true, 8, avx512},
+		{VFMADD132PS, 0x66, 0x38, 0x98, false, 4, avx512},
+		{VFMADD213PS, 0x66, 0x38, 0xa8, false, 4, avx512},
+		{VFMADD231PS, 0x66, 0x38, 0xb8, false, 4, avx512},
+		{VFMADD132PD, 0x66, 0x38, 0x98, true, 8, avx512},
+		{VFMADD213PD, 0x66, 0x38, 0xa8, true, 8, avx512},
+		{VFMADD231PD, 0x66, 0x38, 0xb8, true, 8, avx512},
+		{VPADDB, 0x66, 0xfc, 0x00, false, 0, avx512bw},
+		{VPADDW, 0x66, 0xfd, 0x00, false, 0, avx512bw},
+		{VPADDD, 0x66, 0xfe, 0x00, false, 4, avx512},
+		{VPADDQ, 0x66, 0xd4, 0x00, true, 8, avx512},
+		{VPSUBB, 0x66, 0xf8, 0x00, false, 0, avx512bw},
+		{VPSUBW, 0x66, 0xf9, 0x00, false, 0, avx512bw},
+		{VPSUBD, 0x66, 0xfa, 0x00, false, 4, avx512},
+		{VPSUBQ, 0x66, 0xfb, 0x00, true, 8, avx512},
+		{VPMULLD, 0x66, 0x38, 0x40, false, 4, avx512},
+		{VPANDD, 0x66, 0xdb, 0x00, false, 4, avx512},
+		{VPANDQ, 0x66, 0xdb, 0x00, true, 8, avx512},
+		{VPORD, 0x66, 0xeb, 0x00, false, 4, avx512},
+		{VPORQ, 0x66, 0xeb, 0x00, true, 8, avx512},
+		{VPXORD, 0x66, 0xef, 0x00, false, 4, avx512},
+		{VPXORQ, 0x66, 0xef, 0x00, true, 8, avx512},
+		{VPSHUFB, 0x66, 0x38, 0x00, false, 0, avx512bw},
+		{VPMINUB, 0x66, 0xda, 0x00, false, 0, avx512bw},
+		{VPMAXUB, 0x66, 0xde, 0x00, false, 0, avx512bw},
+		{VPERMD, 0x66, 0x38, 0x36, false, 4, avx512},
+		{VPERMPS, 0x66, 0x38, 0x16, false, 4, avx512},
+	} {
+		// Zmm = vvvv op From.
+		v := opVal{c0: x.c0, c1: 0x0f, c2: x.c2, c3: x.c3, rex: x.w, evex: true, evexN: 64, bcst: x.bcst, regTo: true, feature: x.f, layout: argsRMVReg, args: []AddrType{Zmm | Ind, Zmm}}
+		switch {
+		case x.op >= VFMADD132PS && x.op <= VFMADD231PD:
+			v.dst = AccessReadWrite
+		case x.op == VPXORD || x.op == VPXORQ:
+			v.zeroIdiom = true
+		}
+		add(x.op, List, Zmm, v)
+	}
+	for _, x := range []evexOp{
+		{VPCMPEQB, 0x66, 0x74, 0x00, false, 0, avx512bw},
+		{VPCMPEQD, 0x66, 0x76, 0x00, false, 4, avx512},
+		{VPTESTMB, 0x66, 0x38, 0x26, false, 0, avx512bw},
+		{VPTESTMD, 0x66, 0x38, 0x27, false, 4, avx512},
+		{VPTESTNMB, 0xf3, 0x38, 0x26, false, 0, avx512bw},
+		{VPTESTNMD, 0xf3, 0x38, 0x27, false, 4, avx512},
+	} {
+		// Kreg = vvvv cmp From, one bit per element.
+		add(x.op, List, Kreg, opVal{c0: x.c0, c1: 0x0f, c2: x.c2, c3: x.c3, rex: x.w, evex: true, evexN: 64, bcst: x.bcst, regTo: true, feature: x.f, layout: argsRMVReg, args: []AddrType{Zmm | Ind, Zmm}})
+	}
+	for _, x := range []struct {
+		op          Op
+		c0          uint8
+		load, store uint8
+		w           bool
+		f           cpu.Feature
+	}{
+		{VMOVDQU8, 0xf2, 0x6f, 0x7f, false, avx512bw},
+		{VMOVDQU16, 0xf2, 0x6f, 0x7f, true, avx512bw},
+		{VMOVDQU32, 0xf3, 0x6f, 0x7f, false, avx512},
+		{VMOVDQU64, 0xf3, 0x6f, 0x7f, true, avx512},
+		{VMOVDQA32, 0x66, 0x6f, 0x7f, false, avx512},
+		{VMOVDQA64, 0x66, 0x6f, 0x7f, true, avx512},
+		{VMOVUPS, 0, 0x10, 0x11, false, avx512},
+		{VMOVAPS, 0, 0x28, 0x29, false, avx512},
+	} {
+		add(x.op, Zmm|Ind, Zmm, opVal{c0: x.c0, c1: 0x0f, c2: x.load, rex: x.w, evex: true, evexN: 64, regTo: true, feature: x.f, dst: AccessWrite})
+		add(x.op, Zmm, Ind, opVal{c0: x.c0, c1: 0x0f, c2: x.store, rex: x.w, evex: true, evexN: 64, feature: x.f, dst: AccessWrite})
+	}
+	for _, x := range []struct {
+		op      Op
+		c3, gpr uint8
+		w       bool
+		n       uint8
+		f       cpu.Feature
+	}{
+		{VPBROADCASTB, 0x78, 0x7a, false, 1, avx512bw},
+		{VPBROADCASTW, 0x79, 0x7b, false, 2, avx512bw},
+		{VPBROADCASTD, 0x58, 0x7c, false, 4, avx512},
+		{VPBROADCASTQ, 0x59, 0x7c, true, 8, avx512},
+	} {
+		add(x.op, Xmm|Ind, Zmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: x.c3, rex: x.w, evex: true, evexN: x.n, regTo: true, feature: x.f, dst: AccessWrite})
+		add(x.op, Reg, Zmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: x.gpr, rex: x.w, evex: true, regTo: true, feature: x.f, dst: AccessWrite})
+	}
+	for _, x := range []struct {
+		op   Op
+		w    bool
+		bcst uint8
+	}{
+		{VPTERNLOGD, false, 4},
+		{VPTERNLOGQ, true, 8},
+	} {
+		// To = the truth table imm applied to To, vvvv, and From.
+		add(x.op, List, Zmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: 0x25, rex: x.w, evex: true, evexN: 64, bcst: x.bcst, regTo: true, layout: argsImmRMVReg, args: []AddrType{Imm8, Zmm | Ind, Zmm}, feature: avx512, dst: AccessReadWrite})
+	}
+	for _, x := range []struct {
+		op   Op
+		c3   uint8
+		bcst uint8
+		f    cpu.Feature
+	}{
+		{VPCMPB, 0x3f, 0, avx512bw},
+		{VPCMPUB, 0x3e, 0, avx512bw},
+		{VPCMPD, 0x1f, 4, avx512},
+		{VPCMPUD, 0x1e, 4, avx512},
+	} {
+		// Kreg = vvvv cmp From, with the predicate imm.
+		add(x.op, List, Kreg, opVal{c0: 0x66, c1: 0x0f, c2: 0x3a, c3: x.c3, evex: true, evexN: 64, bcst: x.bcst, regTo: true, layout: argsImmRMVReg, args: []AddrType{Imm8, Zmm | Ind, Zmm}, feature: x.f})
+	}
+	add(VBROADCASTSS, Xmm|Ind, Zmm, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x18, evex: true, evexN: 4, regTo: true, feature: avx512, dst: AccessWrite})
+	add(VPCOMPRESSD, Zmm, Zmm|Ind, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x8b, evex: true, evexN: 4, feature: avx512, dst: AccessWrite})
+	add(VPCOMPRESSQ, Zmm, Zmm|Ind, opVal{c0: 0x66, c1: 0x0f, c2: 0x38, c3: 0x8b, rex: true, evex: true, evexN: 8, feature: avx512, dst: AccessWrite})
+
+	// Opmask register ops, VEX encoded.
+	for _, x := range []struct {
+		op Op
+		w  bool
+		c0 uint8 // for moves to and from Reg
+		f  cpu.Feature
+	}{
+		{KMOVW, false, 0, avx512},
+		{KMOVQ, true, 0xf2, avx512bw},
+	} {
+		add(x.op, Kreg|Ind, Kreg, opVal{c1: 0x0f, c2: 0x90, rex: x.w, vex: true, regTo: true, feature: x.f, dst: AccessWrite})
+		add(x.op, Kreg, Ind, opVal{c1: 0x0f, c2: 0x91, rex: x.w, vex: true, feature: x.f, dst: AccessWrite})
+		add(x.op, Reg, Kreg, opVal{c0: x.c0, c1: 0x0f, c2: 0x92, rex: x.w, vex: true, regTo: true, feature: x.f, dst: AccessWrite})
+		add(x.op, Kreg, Reg, opVal{c0: x.c0, c1: 0x0f, c2: 0x93, rex: x.w, vex: true, regTo: true, feature: x.f, dst: AccessWrite})
+	}
+	add(KORTESTW, Kreg, Kreg, opVal{c1: 0x0f, c2: 0x98, vex: true, regTo: true, feature: avx512, dst: AccessRead, flags: AccessWrite})
+	add(KORTESTQ, Kreg, Kreg, opVal{c1: 0x0f, c2: 0x98, rex: true, vex: true, regTo: true, feature: avx512bw, dst: AccessRead, flags: AccessWrite})
+	add(TESTB, Reg|Ind, Reg, opVal{c1: 0x84, byteReg: true, dst: AccessRead, flags: AccessWrite})
+	add(TESTB, Reg, Ind, opVal{c1: 0x84, byteReg: true, dst: AccessRead, flags: AccessWrite})
+	add(TESTB, Imm8, Reg|Ind, opVal{c1: 0xf6, byteReg: true, mod: mod0, dst: AccessRead, flags: AccessWrite})
+	add(TESTL, Reg|Ind, Reg, opVal{c1: 0x85, dst: AccessRead, flags: AccessWrite})
+	add(TESTL, Reg, Ind, opVal{c1: 0x85, dst: AccessRead, flags: AccessWrite})
+	add(TESTL, Imm32, Reg|Ind, opVal{c1: 0xf7, mod: mod0, dst: AccessRead, flags: AccessWrite})
+	add(TESTQ, Reg|Ind, Reg, opVal{c1: 0x85, rex: true, dst: AccessRead, flags: AccessWrite})
+	add(TESTQ, Reg, Ind, opVal{c1: 0x85, rex: true, dst: AccessRead, flags: AccessWrite})
+	add(TESTQ, Imm32, Reg|Ind, opVal{c1: 0xf7, rex: true, mod: mod0, dst: AccessRead, flags: AccessWrite})
+	for i := BTL; i <= BTCL; i++ {
+		var lock Op
+		dst := Access(0)
+		if i != BTL {
+			lock = LOCK
+		} else {
+			dst = AccessRead
+		}
+		add(i, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xa3 + uint8(i-BTL)*8, prefix: lock, dst: dst, flags: AccessWrite})
+		add(i, Imm8, Reg|Ind, opVal{c1: 0x0f, c2: 0xba, mod: mod4 + modBits(i-BTL), prefix: lock, dst: dst, flags: AccessWrite})
+	}
+	for i := BTQ; i <= BTCQ; i++ {
+		var lock Op
+		dst := Access(0)
+		if i != BTQ {
+			lock = LOCK
+		} else {
+			dst = AccessRead
+		}
+		add(i, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xa3 + uint8(i-BTQ)*8, rex: true, prefix: lock, dst: dst, flags: AccessWrite})
+		add(i, Imm8, Reg|Ind, opVal{c1: 0x0f, c2: 0xba, rex: true, mod: mod4 + modBits(i-BTQ), prefix: lock, dst: dst, flags: AccessWrite})
+	}
+	xchg := []Access{AccessReadWrite, AccessReadWrite}
+	add(XADDL, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xc1, prefix: LOCK, access: xchg, flags: AccessWrite})
+	add(XADDQ, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xc1, rex: true, prefix: LOCK, access: xchg, flags: AccessWrite})
+	add(XCHGL, Reg, Reg|Ind, opVal{c1: 0x87, prefix: LOCK, access: xchg})
+	add(XCHGQ, Reg, Reg|Ind, opVal{c1: 0x87, rex: true, prefix: LOCK, access: xchg})
+	add(CMPXCHGL, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xb1, prefix: LOCK, flags: AccessWrite, iuse: regAX, idef: regAX})
+	add(CMPXCHGQ, Reg, Reg|Ind, opVal{c1: 0x0f, c2: 0xb1, rex: true, prefix: LOCK, flags: AccessWrite, iuse: regAX, idef: regAX})
+	// CMPXCHG8B and CMPXCHG16B only write ZF.
+	cmpxchg := opVal{c1: 0x0f, c2: 0xc7, mod: mod1, prefix: LOCK, flags: AccessReadWrite,
+		iuse: []Register{AX, BX, CX, DX}, idef: []Register{AX, DX}}
+	add(CMPXCHG8B, None, Ind, cmpxchg)
+	cmpxchg.rex, cmpxchg.feature = true, cpu.CX16
+	add(CMPXCHG16B, None, Ind, cmpxchg)
+	str := func(op Op, c1 uint8, prefix Op, flags Access, iuse, idef []Register) {
+		add(op, None, None, opVal{c1: c1, mod: modNone, prefix: prefix, flags: flags, iuse: iuse, idef: idef})
+		add(op+1, None, None, opVal{c0: 0x66, c1: c1 + 1, mod: modNone, prefix: prefix, flags: flags, iuse: iuse, idef: idef})
+		add(op+2, None, None, opVal{c1: c1 + 1, mod: modNone, prefix: prefix, flags: flags, iuse: iuse, idef: idef})
+		add(op+3, None, None, opVal{c1: c1 + 1, rex: true, mod: modNone, prefix: prefix, flags: flags, iuse: iuse, idef: idef})
+	}
+	regSIDI := []Register{SI, DI}
+	regDI := []Register{DI}
+	regAXDI := []Register{AX, DI}
+	str(MOVSB, 0xa4, REP, 0, regSIDI, regSIDI)
+	str(STOSB, 0xaa, REP, 0, regAXDI, regDI)
+	str(LODSB, 0xac, REP, 0, []Register{SI}, []Register{AX, SI})
+	str(CMPSB, 0xa6, REPE|REPNE, AccessWrite, regSIDI, regSIDI)
+	str(SCASB, 0xae, REPE|REPNE, AccessWrite, regAXDI, regDI)
+	add(CLD, None, None, opVal{c1: 0xfc, mod: modNone})
+	add(STD, None, None, opVal{c1: 0xfd, mod: modNone})
+	add(LFENCE, None, None, opVal{c1: 0x0f, c2: 0xae, mod: mod5})
+	add(MFENCE, None, None, opVal{c1: 0x0f, c2: 0xae, mod: mod6})
+	add(SFENCE, None, None, opVal{c1: 0x0f, c2: 0xae, mod: mod7})
+	add(CPUID, None, None, opVal{c1: 0x0f, c2: 0xa2, mod: modNone, iuse: []Register{AX, CX}, idef: []Register{AX, BX, CX, DX}})
+	add(XGETBV, None, None, opVal{c1: 0x0f, c2: 0x01, mod: mod2, iuse: []Register{CX}, idef: []Register{AX, DX}}) // ModRM 0xd0
+	// With a zero source, BSF and BSR leave To as it was.
+	add(BSFL, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbc, regTo: true, flags: AccessWrite})
+	add(BSRL, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbd, regTo: true, flags: AccessWrite})
+	add(BSFQ, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbc, rex: true, regTo: true, flags: AccessWrite})
+	add(BSRQ, Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0xbd, rex: true, regTo: true, flags: AccessWrite})
+	add(POPCNTL, Reg|Ind, Reg, opVal{c0: 0xf3, c1: 0x0f, c2: 0xb8, regTo: true, feature: cpu.POPCNT, dst: AccessWrite, flags: AccessWrite})
+	add(POPCNTQ, Reg|Ind, Reg, opVal{c0: 0xf3, c1: 0x0f, c2: 0xb8, rex: true, regTo: true, feature: cpu.POPCNT, dst: AccessWrite, flags: AccessWrite})
+	add(LZCNTL, Reg|Ind, Reg, opVal{c0: 0xf3, c1: 0x0f, c2: 0xbd, regTo: true, feature: cpu.LZCNT, dst: AccessWrite, flags: AccessWrite})
+	add(LZCNTQ, Reg|Ind, Reg, opVal{c0: 0xf3, c1: 0x0f, c2: 0xbd, rex: true, regTo: true, feature: cpu.LZCNT, dst: AccessWrite, flags: AccessWrite})
+	add(TZCNTL, Reg|Ind, Reg, opVal{c0: 0xf3, c1: 0x0f, c2: 0xbc, regTo: true, feature: cpu.BMI1, dst: AccessWrite, flags: AccessWrite})
+	add(TZCNTQ, Reg|Ind, Reg, opVal{c0: 0xf3, c1: 0x0f, c2: 0xbc, rex: true, regTo: true, feature: cpu.BMI1, dst: AccessWrite, flags: AccessWrite})
+	for w := 0; w <= 1; w++ {
+		rmv := []AddrType{Reg | Ind, Reg}
+		vrm := []AddrType{Reg, Reg | Ind}
+		bmi := func(op Op, c0, c2, c3 uint8, layout argsLayout, args []AddrType) {
+			f := cpu.BMI2
+			if op <= BLSRQ {
+				f = cpu.BMI1
+			}
+			v := opVal{c0: c0, c1: 0x0f, c2: c2, c3: c3, rex: w == 1, vex: true, regTo: true, layout: layout, args: args, feature: f}
+			switch op {
+			case ANDNL, BEXTRL, BZHIL:
+				v.flags = AccessWrite
+			case MULXL:
+				// The second source is the destination of the low half.
+				v.access, v.iuse = []Access{AccessRead, AccessWrite, AccessWrite}, regDX
+			}
+			add(op+Op(w), List, Reg, v)
+		}
+		bmi(ANDNL, 0, 0x38, 0xf2, argsRMVReg, rmv)
+		bmi(BEXTRL, 0, 0x38, 0xf7, argsVRMReg, vrm)
+		bmi(BZHIL, 0, 0x38, 0xf5, argsVRMReg, vrm)
+		bmi(MULXL, 0xf2, 0x38, 0xf6, argsRMVReg, rmv)
+		bmi(PDEPL, 0xf2, 0x38, 0xf5, argsRMVReg, rmv)
+		bmi(PEXTL, 0xf3, 0x38, 0xf5, argsRMVReg, rmv)
+		bmi(RORXL, 0xf2, 0x3a, 0xf0, argsImmRMReg, []AddrType{Imm8, Reg | Ind})
+		bmi(SARXL, 0xf3, 0x38, 0xf7, argsVRMReg, vrm)
+		bmi(SHLXL, 0x66, 0x38, 0xf7, argsVRMReg, vrm)
+		bmi(SHRXL, 0xf2, 0x38, 0xf7, argsVRMReg, vrm)
+		add(BLSIL+Op(w), Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0x38, c3: 0xf3, rex: w == 1, vex: true, mod: mod3, layout: argsRMV, feature: cpu.BMI1, flags: AccessWrite})
+		add(BLSMSKL+Op(w), Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0x38, c3: 0xf3, rex: w == 1, vex: true, mod: mod2, layout: argsRMV, feature: cpu.BMI1, flags: AccessWrite})
+		add(BLSRL+Op(w), Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0x38, c3: 0xf3, rex: w == 1, vex: true, mod: mod1, layout: argsRMV, feature: cpu.BMI1, flags: AccessWrite})
+	}
+	idiv := []Register{AX, DX}
+	add(IDIVL, None, Reg|Ind, opVal{c1: 0xf7, mod: mod7, dst: AccessRead, flags: AccessWrite, iuse: idiv, idef: idiv})
+	add(IDIVQ, None, Reg|Ind, opVal{c1: 0xf7, rex: true, mod: mod7, dst: AccessRead, flags: AccessWrite, iuse: idiv, idef: idiv})
+	add(CALL, None, Rel32, opVal{c1: 0xe8, mod: modNone, dst: AccessRead, iuse: regSP, idef: regSP})
+	add(JMP, None, Rel8, opVal{c1: 0xeb, mod: modNone, dst: AccessRead})
+	add(JMP, None, Rel32, opVal{c1: 0xe9, mod: modNone, dst: AccessRead})
+	add(CALL, None, Reg|Ind, opVal{c1: 0xff, mod: mod2, dst: AccessRead, iuse: regSP, idef: regSP})
+	add(JMP, None, Reg|Ind, opVal{c1: 0xff, mod: mod4, dst: AccessRead})
+	for c := CondO; c <= CondG; c++ {
+		add(c.Jump(), None, Rel8, opVal{c1: 0x70 + uint8(c), mod: modNone, dst: AccessRead, flags: AccessRead})
+		add(c.Jump(), None, Rel32, opVal{c1: 0x0f, c2: 0x80 + uint8(c), mod: modNone, dst: AccessRead, flags: AccessRead})
+		add(c.Set(), None, Reg|Ind, opVal{c1: 0x0f, c2: 0x90 + uint8(c), byteReg: true, mod: mod0, flags: AccessRead})
+		add(c.CMOVL(), Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0x40 + uint8(c), regTo: true, flags: AccessRead})
+		add(c.CMOVQ(), Reg|Ind, Reg, opVal{c1: 0x0f, c2: 0x40 + uint8(c), rex: true, regTo: true, flags: AccessRead})
+	}
 }
 
 type opKey struct {
 	Op   Op
 	From AddrType
 	To   AddrType
+	Args argTypes // types of an Args list, for List From.
+}
+
+// argTypes are the types of the operands in an Args list. Unused
+// operands are None. Counting To, an instruction has at most five
+// operands, the fifth being an AVX-512 opmask.
+type argTypes [4]AddrType
+
+// noArgs is the argTypes of an instruction without an Args list.
+var noArgs argTypes
+
+// makeKey returns the optab key of an instruction with the given operands.
+func makeKey(op Op, from, to Addr) (opKey, error) {
+	key := opKey{op.Base(), from.Type, to.Type, noArgs}
+	if from.Type == List {
+		list := from.Value.([]Addr)
+		if len(list) > len(key.Args) {
+			return key, fmt.Errorf("%v: too many operands", op)
+		}
+		for i, a := range list {
+			if a.Type == List {
+				return key, fmt.Errorf("%v: nested operand list", op)
+			}
+			key.Args[i] = a.Type
+		}
+	}
+	return key, nil
 }
 
 type opVal struct {
-	c0     uint8 // 1-byte prefix, either 66, f3, or f2.
-	c1     uint8 // 1-byte op code
-	c2     uint8 // 2nd byte of op code. Only used if code==0x0f.
-	rex    bool  // REX prefix is present, W is set.
-	addReg bool  // add the register number to the op code.
-	mod    modBits
+	c0      uint8 // 1-byte prefix, either 66, f3, or f2.
+	c1      uint8 // 1-byte op code
+	c2      uint8 // 2nd byte of op code. Only used if code==0x0f.
+	c3      uint8 // 3rd byte of op code. Only used if c2 is 0x38 or 0x3a.
+	rex     bool  // REX prefix is present, W is set.
+	vex     bool  // VEX prefix, encoding rex, c0, c1, and c2.
+	vexL    bool  // VEX.L is set, for 256-bit vectors.
+	evex    bool  // EVEX prefix, for 512-bit vectors.
+	evexN   uint8 // EVEX memory operand size, scaling an 8-bit displacement.
+	bcst    uint8 // EVEX element size that may be broadcast, 0 if none.
+	addReg  bool  // add the register number to the op code.
+	regTo   bool  // ModRM.reg encodes To, ModRM.rm encodes From.
+	byteReg bool  // register operands are 8-bit, so SP-DI need REX.
+	mod     modBits
+	prefix  Op // permitted prefixes, besides segment overrides.
+	layout  argsLayout
+	args    []AddrType  // permitted types of an Args list, expanded by add.
+	feature cpu.Feature // CPU features required, beyond the x86-64 baseline.
+
+	// How the instruction uses its operands, reported by Form.
+	dst    Access     // use of To, if not the default for the layout.
+	access []Access   // use of each operand, if not the default for dst.
+	flags  Access     // use of the status flags.
+	iuse   []Register // registers read without being named.
+	idef   []Register // registers written without being named.
+
+	// zeroIdiom is set if the instruction sets To to zero when its
+	// register sources are the same, as XORL AX, AX does.
+	zeroIdiom bool
 }
 
+// argsLayout describes how the operands of an instruction are assigned to
+// ModRM.reg, ModRM.rm, VEX.vvvv, and the immediate. Operands are listed in
+// Go assembler order, source first, as they appear in an Args list.
+type argsLayout int
+
+const (
+	// argsDefault is the two-operand From/To layout, where the ModRM
+	// fields are assigned by mod and regTo.
+	argsDefault argsLayout = iota
+
+	argsRMVReg    // From: Args(rm, vvvv), To: reg.
+	argsVRMReg    // From: Args(vvvv, rm), To: reg.
+	argsImmRMReg  // From: Args(imm, rm), To: reg.
+	argsImmRMVReg // From: Args(imm, rm, vvvv), To: reg.
+	argsImmRegRM  // From: Args(imm or CX, reg), To: rm.
+	argsIs4RMVReg // From: Args(is4, rm, vvvv), To: reg. is4 is a register in imm[7:4].
+	argsRMV       // From: rm, To: vvvv. ModRM.reg is an opcode extension.
+)
+
 // modBits describes what the ModRM.mod bits are used for.
 type modBits int
 
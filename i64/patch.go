@@ -0,0 +1,82 @@
+package i64
+
+// PatchKind is the kind of field a PatchPoint locates.
+type PatchKind int
+
+const (
+	PatchImm  PatchKind = iota // immediate data
+	PatchDisp                  // displacement of a memory operand
+	PatchRel                   // relative target of a jump or call
+)
+
+func (k PatchKind) String() string {
+	switch k {
+	case PatchImm:
+		return "Imm"
+	case PatchDisp:
+		return "Disp"
+	case PatchRel:
+		return "Rel"
+	}
+	return "PatchKind(?)"
+}
+
+// A PatchPoint is the location of a field in the assembled code of a
+// Program that may be rewritten after the code is loaded.
+//
+// A PatchRel field holds the target relative to the end of the field,
+// which is also the end of the instruction.
+type PatchPoint struct {
+	Index  int // index of the instruction in the Program, counting LABEL and POS
+	Kind   PatchKind
+	Offset int // offset of the field in the assembled code
+	Width  int // width of the field in bytes: 1, 2, 4 or 8
+}
+
+// PatchPoints returns the immediate, displacement and relative target
+// fields of the assembled program, in order of Offset.
+//
+// The layout of a Program is deterministic, so the patch points apply
+// to the bytes returned by Bytes, Assemble or Load. A compressed EVEX
+// displacement is scaled by the operand size, so it is not reported.
+func (p Program) PatchPoints() ([]PatchPoint, error) {
+	laidOut, err := p.layOut()
+	if err != nil {
+		return nil, err
+	}
+	var pts []PatchPoint
+	for i := range laidOut {
+		c := &laidOut[i]
+		if c.ins.Op == LABEL || c.ins.Op == POS {
+			continue
+		}
+		immBytes := 0
+		kind := PatchImm
+		for _, a := range c.ins.Operands() {
+			switch a.Type {
+			case Imm8, Imm16, Imm32, Imm64:
+				immBytes = c.immWidth / 8
+			case Rel8, Rel16, Rel32:
+				immBytes = c.immWidth / 8
+				kind = PatchRel
+			}
+		}
+		if c.dispWidth > 0 && !(c.evexN != 0 && c.dispWidth == 8) {
+			pts = append(pts, PatchPoint{
+				Index:  i,
+				Kind:   PatchDisp,
+				Offset: c.codeblockEnd - immBytes - c.dispWidth/8,
+				Width:  c.dispWidth / 8,
+			})
+		}
+		if immBytes > 0 {
+			pts = append(pts, PatchPoint{
+				Index:  i,
+				Kind:   kind,
+				Offset: c.codeblockEnd - immBytes,
+				Width:  immBytes,
+			})
+		}
+	}
+	return pts, nil
+}
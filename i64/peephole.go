@@ -0,0 +1,204 @@
+package i64
+
+// A Rule is a peephole rewrite. It reports whether the instructions
+// p[i:i+n] can be replaced by repl, returning n == 0 if not. labels maps
+// each label to the index of its LABEL instruction in p.
+//
+// A rule must preserve the meaning of the program, must not add or
+// remove LABEL instructions, and must match only where it changes the
+// program, so that Optimize terminates.
+type Rule func(p Program, i int, labels map[string]int) (repl Program, n int)
+
+// PeepholeRules is the standard set of peephole rules.
+var PeepholeRules = []Rule{
+	SelfMove,
+	AddZero,
+	ZeroMove,
+	JumpNext,
+	JumpChain,
+}
+
+// Optimize returns p rewritten by rules. Each rule is tried at each
+// instruction until none matches anywhere. p is not modified.
+func (p Program) Optimize(rules []Rule) Program {
+	q := append(Program(nil), p...)
+	for changed := true; changed; {
+		changed = false
+		labels := labelIndex(q)
+		for i := 0; i < len(q); {
+			matched := false
+			for _, rule := range rules {
+				repl, n := rule(q, i, labels)
+				if n == 0 {
+					continue
+				}
+				next := make(Program, 0, len(q)-n+len(repl))
+				next = append(next, q[:i]...)
+				next = append(next, repl...)
+				q = append(next, q[i+n:]...)
+				labels = labelIndex(q)
+				matched, changed = true, true
+				break
+			}
+			if !matched {
+				i++
+			}
+		}
+	}
+	return q
+}
+
+func labelIndex(p Program) map[string]int {
+	labels := make(map[string]int)
+	for i := range p {
+		if p[i].Op == LABEL {
+			labels[p[i].From.Name] = i
+		}
+	}
+	return labels
+}
+
+// SelfMove removes a MOVQ of a general purpose register to itself.
+// A MOVL to itself clears the upper half of the register, and a MOVQ
+// of an XMM register to itself clears the upper lanes, so both are kept.
+func SelfMove(p Program, i int, labels map[string]int) (Program, int) {
+	in := &p[i]
+	if in.Op.Base() == MOVQ && in.From.Type == Reg && in.To.Type == Reg && in.From.Value == in.To.Value {
+		return nil, 1
+	}
+	return nil, 0
+}
+
+// AddZero removes an ADDQ or SUBQ of zero to a register, if the flags
+// it sets are not used.
+func AddZero(p Program, i int, labels map[string]int) (Program, int) {
+	in := &p[i]
+	if op := in.Op.Base(); (op == ADDQ || op == SUBQ) && isZero(in.From) && in.To.Type == Reg && flagsDead(p, i+1, labels) {
+		return nil, 1
+	}
+	return nil, 0
+}
+
+// ZeroMove replaces a MOVL or MOVQ of zero to a register with the
+// shorter XORL of the register with itself, if the flags XORL sets
+// are not used.
+func ZeroMove(p Program, i int, labels map[string]int) (Program, int) {
+	in := &p[i]
+	if op := in.Op.Base(); (op == MOVL || op == MOVQ) && isZero(in.From) && in.To.Type == Reg && flagsDead(p, i+1, labels) {
+		return Program{{XORL, in.To, in.To}}, 1
+	}
+	return nil, 0
+}
+
+// JumpNext removes a jump to the instruction that follows it.
+func JumpNext(p Program, i int, labels map[string]int) (Program, int) {
+	target, ok := jumpTarget(p, i, labels)
+	if !ok || target <= i {
+		return nil, 0
+	}
+	for j := i + 1; j < target; j++ {
+		if op := p[j].Op; op != LABEL && op != POS {
+			return nil, 0
+		}
+	}
+	return nil, 1
+}
+
+// JumpChain retargets a jump to a JMP, directly or through a chain of
+// JMPs, to the final target of the chain.
+func JumpChain(p Program, i int, labels map[string]int) (Program, int) {
+	if _, ok := jumpTarget(p, i, labels); !ok {
+		return nil, 0
+	}
+	name := p[i].To.Name
+	seen := map[string]bool{name: true}
+	for {
+		j, ok := labels[name]
+		if !ok {
+			break
+		}
+		j = skipPseudo(p, j)
+		if j == len(p) || p[j].Op.Base() != JMP || p[j].To.Type != Label || seen[p[j].To.Name] {
+			break
+		}
+		name = p[j].To.Name
+		seen[name] = true
+	}
+	if name == p[i].To.Name {
+		return nil, 0
+	}
+	in := p[i]
+	in.To = LabelAddr(name)
+	return Program{in}, 1
+}
+
+// jumpTarget returns the index of the first instruction after the label
+// that the jump p[i] targets, and whether p[i] is a JMP or conditional
+// jump to a label.
+func jumpTarget(p Program, i int, labels map[string]int) (int, bool) {
+	in := &p[i]
+	if in.To.Type != Label {
+		return 0, false
+	}
+	if op := in.Op.Base(); op != JMP && !(op >= JO && op <= JG) {
+		return 0, false
+	}
+	j, ok := labels[in.To.Name]
+	if !ok {
+		return 0, false
+	}
+	return skipPseudo(p, j), true
+}
+
+// skipPseudo returns the index of the first instruction at or after i
+// that is not a LABEL or POS.
+func skipPseudo(p Program, i int) int {
+	for i < len(p) && (p[i].Op == LABEL || p[i].Op == POS) {
+		i++
+	}
+	return i
+}
+
+func isZero(a Addr) bool {
+	switch a.Type {
+	case Imm8, Imm16, Imm32, Imm64, ImmAny:
+		return a.valueUint64() == 0
+	}
+	return false
+}
+
+// flagsDead reports whether the status flags are overwritten or the
+// function returns, before they are read, on every path from p[i].
+// It is conservative: an instruction it does not know reads the flags.
+func flagsDead(p Program, i int, labels map[string]int) bool {
+	seen := make(map[int]bool)
+	for i < len(p) && !seen[i] {
+		seen[i] = true
+		in := &p[i]
+		switch in.Op.Base() {
+		case LABEL, POS,
+			MOVB, MOVL, MOVQ,
+			MOVBLSX, MOVBLZX, MOVWLSX, MOVWLZX,
+			MOVBQSX, MOVBQZX, MOVWQSX, MOVWQZX, MOVLQSX,
+			LEAL, LEAQ:
+			i++
+		case ADD, OR, AND, SUB, XOR, CMP,
+			ADDL, ORL, ANDL, SUBL, XORL, CMPL,
+			ADDQ, ORQ, ANDQ, SUBQ, XORQ, CMPQ,
+			TESTB, TESTL, TESTQ:
+			return true
+		case RET:
+			return true
+		case JMP:
+			j, ok := labels[in.To.Name]
+			if in.To.Type != Label || !ok {
+				return false
+			}
+			i = j
+		default:
+			return false
+		}
+	}
+	// A loop that neither reads nor writes the flags.
+	return i < len(p)
+}
@@ -0,0 +1,61 @@
+package i64
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Pos is a source position: a position in Go source recorded by a
+// Builder, or a line of assembly text.
+type Pos struct {
+	File string
+	Line int
+}
+
+// IsValid reports whether the position is known.
+func (p Pos) IsValid() bool { return p.Line > 0 }
+
+func (p Pos) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d", p.Line)
+	}
+	return fmt.Sprintf("%s:%d", p.File, p.Line)
+}
+
+// Instruction returns a POS pseudo-instruction, which sets the source
+// position of the instructions that follow it to p. Like LABEL, it
+// assembles to nothing.
+func (p Pos) Instruction() Instruction {
+	return Instruction{Op: POS, From: Addr{Name: p.File, Value: p.Line}}
+}
+
+// pos returns the position set by a POS instruction.
+func (p *Instruction) pos() Pos {
+	line, _ := p.From.Value.(int)
+	return Pos{File: p.From.Name, Line: line}
+}
+
+// Positions returns the source position of each instruction of p, set
+// by the nearest POS instruction before it. An instruction with no POS
+// before it has the zero Pos.
+func (p Program) Positions() []Pos {
+	positions := make([]Pos, len(p))
+	var pos Pos
+	for i := range p {
+		if p[i].Op == POS {
+			pos = p[i].pos()
+		}
+		positions[i] = pos
+	}
+	return positions
+}
+
+// errorf returns an error about instruction i, prefixed by its index
+// and, if known, its source position.
+func (p Program) errorf(i int, format string, args ...interface{}) error {
+	msg := fmt.Sprintf("ins %d: %s", i, fmt.Sprintf(format, args...))
+	if pos := p.Positions()[i]; pos.IsValid() {
+		msg = pos.String() + ": " + msg
+	}
+	return errors.New(msg)
+}
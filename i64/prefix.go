@@ -0,0 +1,45 @@
+package i64
+
+// Instruction prefixes. A prefix is added to an instruction by ORing it
+// into the Op:
+//
+//	Instruction{XADDQ | LOCK, AX.Addr(), BX.Ind(0)}
+const (
+	LOCK  Op = 1 << (16 + iota) // atomic read-modify-write of memory To
+	REP                         // repeat string op CX times
+	REPE                        // repeat string op while equal, at most CX times
+	REPNE                       // repeat string op while not equal, at most CX times
+	SEGFS                       // FS segment override for memory operands
+	SEGGS                       // GS segment override for memory operands
+
+	opMask Op = 1<<16 - 1
+)
+
+var prefixes = []struct {
+	op   Op
+	name string
+	b    byte
+}{
+	{SEGFS, "SEGFS", 0x64},
+	{SEGGS, "SEGGS", 0x65},
+	{LOCK, "LOCK", 0xf0},
+	{REP, "REP", 0xf3},
+	{REPE, "REPE", 0xf3},
+	{REPNE, "REPNE", 0xf2},
+}
+
+// Base returns op with any prefixes removed.
+func (op Op) Base() Op { return op & opMask }
+
+// Prefix returns the prefixes of op.
+func (op Op) Prefix() Op { return op &^ opMask }
+
+// appendPrefix appends the legacy prefix bytes of op to buf.
+func appendPrefix(buf []byte, op Op) []byte {
+	for _, p := range prefixes {
+		if op&p.op != 0 {
+			buf = append(buf, p.b)
+		}
+	}
+	return buf
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+
+	"github.com/crawshaw/asm/cpu"
 )
 
 // Program is an amd64 program.
@@ -11,84 +13,114 @@ type Program []Instruction
 
 // layOut translates Instruction into ins and resolves labels.
 func (p Program) layOut() ([]ins, error) {
+	// Resolving labels rewrites jumps, so work on a copy to leave the
+	// caller's Program intact.
+	p = append(Program(nil), p...)
+
 	// Collect labels.
 	labels := make(map[string]int)
 	for i := 0; i < len(p); i++ {
 		if p[i].Op == LABEL {
 			name := p[i].From.Name
 			if _, ok := labels[name]; ok {
-				return nil, fmt.Errorf("ins %d: label %q previously defined at ins %d", i, name, labels[name])
+				return nil, p.errorf(i, "label %q previously defined at ins %d", name, labels[name])
 			}
 			labels[name] = i
 		}
 	}
 
-	// Use short jump if labels are close, otherwise mark it as a 16-bit jump.
-	// Collect the jumps so we can update the call sites after codeblock offsets
-	// are calculated.
-	//
-	// The amd64 spec limits Ops to being at most 15 bytes. However, this is an
-	// ultra-conservative limit, we could do much better by measuring the upper bound
-	// on known instructions..
-	const shortJumpLimit = 17
+	// Collect the jumps so we can update the call sites after codeblock
+	// offsets are calculated. Each jump starts out short, and is widened
+	// until every displacement fits.
 	var jumps []int
 	for i := 0; i < len(p); i++ {
 		if l := &p[i].To; l.Type == Label {
+			if _, ok := labels[l.Name]; !ok {
+				return nil, p.errorf(i, "undefined label %q", l.Name)
+			}
 			jumps = append(jumps, i)
-			if p[i].Op == CALL {
-				// TODO(crawshaw): Rel16 CALLs should be possible.
+			if p[i].Op.Base() == CALL {
+				// There is no short relative CALL in 64-bit mode.
 				l.Type = Rel32
-				l.Value = int8(0)
-			} else if i-labels[l.Name] < shortJumpLimit {
+				l.Value = int32(0)
+			} else {
 				l.Type = Rel8
 				l.Value = int8(0)
-			} else {
-				// TODO(crawshaw): Support Rel32 jumps.
-				l.Type = Rel16
-				l.Value = int16(0)
 			}
 		}
 	}
 
-	// Lay out instructions.
 	laidOut := make([]ins, len(p))
-	for i := 0; i < len(p); i++ {
-		if err := laidOut[i].make(&p[i]); err != nil {
-			return nil, fmt.Errorf("%v: %v", p[i], err)
+	for {
+		// Lay out instructions.
+		for i := 0; i < len(p); i++ {
+			laidOut[i] = ins{}
+			if err := laidOut[i].make(&p[i]); err != nil {
+				return nil, p.errorf(i, "%v: %v", p[i], err)
+			}
 		}
-	}
 
-	// Calculate codeblock offsets.
-	buf := new(bytes.Buffer)
-	codeblock := 0
-	for i := 0; i < len(p); i++ {
-		laidOut[i].codeblock = codeblock
-		buf.Reset()
-		laidOut[i].writeTo(buf)
-		codeblock += buf.Len()
-		laidOut[i].codeblockEnd = codeblock
+		// Calculate codeblock offsets.
+		buf := new(bytes.Buffer)
+		codeblock := 0
+		for i := 0; i < len(p); i++ {
+			laidOut[i].codeblock = codeblock
+			buf.Reset()
+			laidOut[i].writeTo(buf)
+			codeblock += buf.Len()
+			laidOut[i].codeblockEnd = codeblock
+		}
+
+		// Widen the short jumps that do not reach their label. Jumps
+		// with a 16-bit operand are not supported in 64-bit mode, so
+		// a long jump is a Rel32. Widening only moves code further
+		// apart, so this terminates.
+		widened := false
+		for _, jump := range jumps {
+			if l := &p[jump].To; l.Type == Rel8 {
+				if val := jumpDisp(laidOut, labels, p, jump); val < -128 || val > 127 {
+					l.Type = Rel32
+					l.Value = int32(0)
+					widened = true
+				}
+			}
+		}
+		if !widened {
+			break
+		}
 	}
 
 	// Update jump locations now that we have real offsets.
 	for _, jump := range jumps {
-		target := &laidOut[labels[p[jump].To.Name]]
-		val := target.codeblock - laidOut[jump].codeblockEnd
+		val := jumpDisp(laidOut, labels, p, jump)
 		switch p[jump].To.Type {
 		case Rel8:
+			if val < -128 || val > 127 {
+				return nil, p.errorf(jump, "%v: jump of %d bytes does not fit in 8 bits", p[jump], val)
+			}
 			p[jump].To.Value = int8(val)
-		case Rel16:
-			p[jump].To.Value = int16(val)
 		case Rel32:
 			p[jump].To.Value = int32(val)
 		default:
 			panic(fmt.Sprintf("unexpected jump type: %v", p[jump].To.Type))
 		}
-		laidOut[jump].make(&p[jump]) // remake
+		codeblock, codeblockEnd := laidOut[jump].codeblock, laidOut[jump].codeblockEnd
+		laidOut[jump] = ins{}
+		if err := laidOut[jump].make(&p[jump]); err != nil { // remake
+			return nil, p.errorf(jump, "%v: %v", p[jump], err)
+		}
+		laidOut[jump].codeblock, laidOut[jump].codeblockEnd = codeblock, codeblockEnd
 	}
 
 	return laidOut, nil
 }
 
+// jumpDisp returns the displacement from the end of the jump p[jump]
+// to its label.
+func jumpDisp(laidOut []ins, labels map[string]int, p Program, jump int) int {
+	return laidOut[labels[p[jump].To.Name]].codeblock - laidOut[jump].codeblockEnd
+}
+
 // WriteTo writes the assembled bytes of a program to w.
 func (p Program) WriteTo(w io.Writer) (n int64, err error) {
 	laidOut, err := p.layOut()
@@ -105,16 +137,103 @@ func (p Program) WriteTo(w io.Writer) (n int64, err error) {
 	return n, err
 }
 
+// Options configures Assemble.
+type Options struct {
+	// Target is the set of CPU features the program may use, beyond
+	// the x86-64 baseline. For example, cpu.V2 or cpu.Host.
+	// If zero, any instruction is permitted.
+	Target cpu.Feature
+}
+
+// Assemble returns the assembled bytes of a program. If an instruction
+// requires features outside opts.Target, it returns a *FeatureError.
+func (p Program) Assemble(opts Options) ([]uint8, error) {
+	laidOut, err := p.layOut()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTarget(p, laidOut, opts.Target); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	for _, c := range laidOut {
+		if _, err := c.writeTo(buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// checkTarget returns a *FeatureError for the first instruction of p
+// that requires features outside target. A zero target permits any.
+func checkTarget(p Program, laidOut []ins, target cpu.Feature) error {
+	if target == 0 {
+		return nil
+	}
+	for i := range laidOut {
+		if f := laidOut[i].feature; !target.Has(f) {
+			return &FeatureError{Index: i, Pos: p.Positions()[i], Instruction: p[i], Missing: f &^ target}
+		}
+	}
+	return nil
+}
+
 // Bytes returns the assembled bytes of a program.
 func (p Program) Bytes() ([]uint8, error) {
-	buf := new(bytes.Buffer)
-	if _, err := p.WriteTo(buf); err != nil {
+	return p.Assemble(Options{})
+}
+
+// Features returns the CPU features required to run the program.
+func (p Program) Features() (cpu.Feature, error) {
+	laidOut, err := p.layOut()
+	if err != nil {
+		return 0, err
+	}
+	var f cpu.Feature
+	for i := range laidOut {
+		f |= laidOut[i].feature
+	}
+	return f, nil
+}
+
+// Offsets returns the offset of each instruction in the assembled
+// program. A LABEL has the offset of the instruction that follows it.
+func (p Program) Offsets() ([]int, error) {
+	laidOut, err := p.layOut()
+	if err != nil {
 		return nil, err
 	}
-	return buf.Bytes(), nil
+	offsets := make([]int, len(laidOut))
+	for i := range laidOut {
+		offsets[i] = laidOut[i].codeblock
+	}
+	return offsets, nil
+}
+
+// Labels returns the offset of each label in the assembled program.
+func (p Program) Labels() (map[string]int, error) {
+	laidOut, err := p.layOut()
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]int)
+	for i := range p {
+		if p[i].Op == LABEL {
+			labels[p[i].From.Name] = laidOut[i].codeblock
+		}
+	}
+	return labels, nil
+}
+
+// Load returns the assembled bytes of a program for execution on the host.
+// Unlike Bytes, it returns a *FeatureError if the program uses instructions
+// the host CPU does not support, rather than code that faults with SIGILL.
+func (p Program) Load() ([]uint8, error) {
+	return p.Assemble(Options{Target: cpu.Host})
 }
 
 // PrintText writes a textual representation of the program to w.
+// A POS instruction is written as a comment holding its position.
 func (p Program) PrintText(w io.Writer) error {
 	laidOut, err := p.layOut()
 	if err != nil {
@@ -127,6 +246,10 @@ func (p Program) PrintText(w io.Writer) error {
 			fmt.Fprintf(w, "%s:\n", p[i].From.Name)
 			continue
 		}
+		if p[i].Op == POS {
+			fmt.Fprintf(w, "// %v\n", p[i].pos())
+			continue
+		}
 		buf.Reset()
 		ins.writeTo(buf)
 		b := buf.Bytes()
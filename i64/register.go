@@ -8,13 +8,42 @@ func (r Register) Addr() Addr {
 	addrType := Reg
 	if r >= X0 && r <= X15 {
 		addrType = Xmm
+	} else if r >= Y0 && r <= Y15 {
+		addrType = Ymm
+	} else if r >= Z0 && r <= Z31 {
+		addrType = Zmm
+	} else if r >= K0 && r <= K7 {
+		addrType = Kreg
 	}
 	return Addr{addrType, r, 0, ""}
 }
 
 // Ind makes an Addr representing a memory address pointed to by the given register.
+// disp is the two's complement of the displacement, see Off for a negative one.
 func (r Register) Ind(disp uint64) Addr { return Addr{Ind, r, disp, ""} }
 
+// Off makes an Addr representing the memory address disp bytes from the
+// address in the given register, such as -8(SP) for SP.Off(-8).
+func (r Register) Off(disp int64) Addr { return r.Ind(uint64(disp)) }
+
+// num returns the 4-bit register number used in an instruction encoding.
+// AVX-512 registers Z16-Z31 have a fifth bit, carried by EVEX.
+func (r Register) num() uint8 {
+	if r >= K0 {
+		return uint8(r - K0)
+	}
+	if r >= Z0 {
+		return uint8(r - Z0)
+	}
+	if r >= Y0 {
+		return uint8(r - Y0)
+	}
+	if r >= X0 {
+		return uint8(r - X0)
+	}
+	return uint8(r - AX)
+}
+
 // String returns the name of the register.
 func (r Register) String() string { return registerName[r] }
 
@@ -53,6 +82,69 @@ const (
 	X13
 	X14
 	X15
+
+	// AVX registers. The low 128 bits of Yn are Xn.
+	Y0
+	Y1
+	Y2
+	Y3
+	Y4
+	Y5
+	Y6
+	Y7
+	Y8
+	Y9
+	Y10
+	Y11
+	Y12
+	Y13
+	Y14
+	Y15
+
+	// AVX-512 registers. The low 256 bits of Zn are Yn, for n < 16.
+	// Z16-Z31 are only reachable with an EVEX prefix.
+	Z0
+	Z1
+	Z2
+	Z3
+	Z4
+	Z5
+	Z6
+	Z7
+	Z8
+	Z9
+	Z10
+	Z11
+	Z12
+	Z13
+	Z14
+	Z15
+	Z16
+	Z17
+	Z18
+	Z19
+	Z20
+	Z21
+	Z22
+	Z23
+	Z24
+	Z25
+	Z26
+	Z27
+	Z28
+	Z29
+	Z30
+	Z31
+
+	// AVX-512 opmask registers. K0 cannot be used as a write mask.
+	K0
+	K1
+	K2
+	K3
+	K4
+	K5
+	K6
+	K7
 )
 
 var registerName = map[Register]string{
@@ -88,4 +180,60 @@ var registerName = map[Register]string{
 	X13: "X13",
 	X14: "X14",
 	X15: "X15",
+	Y0:  "Y0",
+	Y1:  "Y1",
+	Y2:  "Y2",
+	Y3:  "Y3",
+	Y4:  "Y4",
+	Y5:  "Y5",
+	Y6:  "Y6",
+	Y7:  "Y7",
+	Y8:  "Y8",
+	Y9:  "Y9",
+	Y10: "Y10",
+	Y11: "Y11",
+	Y12: "Y12",
+	Y13: "Y13",
+	Y14: "Y14",
+	Y15: "Y15",
+	Z0:  "Z0",
+	Z1:  "Z1",
+	Z2:  "Z2",
+	Z3:  "Z3",
+	Z4:  "Z4",
+	Z5:  "Z5",
+	Z6:  "Z6",
+	Z7:  "Z7",
+	Z8:  "Z8",
+	Z9:  "Z9",
+	Z10: "Z10",
+	Z11: "Z11",
+	Z12: "Z12",
+	Z13: "Z13",
+	Z14: "Z14",
+	Z15: "Z15",
+	Z16: "Z16",
+	Z17: "Z17",
+	Z18: "Z18",
+	Z19: "Z19",
+	Z20: "Z20",
+	Z21: "Z21",
+	Z22: "Z22",
+	Z23: "Z23",
+	Z24: "Z24",
+	Z25: "Z25",
+	Z26: "Z26",
+	Z27: "Z27",
+	Z28: "Z28",
+	Z29: "Z29",
+	Z30: "Z30",
+	Z31: "Z31",
+	K0:  "K0",
+	K1:  "K1",
+	K2:  "K2",
+	K3:  "K3",
+	K4:  "K4",
+	K5:  "K5",
+	K6:  "K6",
+	K7:  "K7",
 }
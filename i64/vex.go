@@ -0,0 +1,43 @@
+package i64
+
+// appendVEX appends a VEX prefix and the final opcode byte to buf.
+//
+// The VEX prefix replaces the REX prefix, the 66, F3, or F2 prefix (c0), and
+// the 0F, 0F38, or 0F3A escape bytes (c1, c2). It also carries an extra
+// register operand, VEX.vvvv, and the vector length, VEX.L.
+//
+// The 2-byte form is used when it can encode the instruction, that is
+// when the escape is 0F and VEX.W, VEX.X, and VEX.B are clear.
+func (c *ins) appendVEX(buf []byte) []byte {
+	var pp uint8
+	switch c.c0 {
+	case 0x66:
+		pp = 1
+	case 0xf3:
+		pp = 2
+	case 0xf2:
+		pp = 3
+	}
+	var mmmmm, op uint8
+	switch c.c2 {
+	case 0x38:
+		mmmmm, op = 2, c.c3
+	case 0x3a:
+		mmmmm, op = 3, c.c3
+	default:
+		mmmmm, op = 1, c.c2
+	}
+
+	// VEX.R, VEX.X, VEX.B, and VEX.vvvv are stored inverted.
+	vlpp := (^c.vexV&0xf)<<3 | c.vexL<<2 | pp
+	if mmmmm == 1 && c.rex&(rexW|rexX|rexB) == 0 {
+		r := (^c.rex & rexR) << 5
+		return append(buf, 0xc5, r|vlpp, op)
+	}
+	b1 := (^c.rex&(rexR|rexX|rexB))<<5 | mmmmm
+	b2 := vlpp
+	if c.rex&rexW != 0 {
+		b2 |= 0x80
+	}
+	return append(buf, 0xc4, b1, b2, op)
+}
@@ -0,0 +1,1251 @@
+// Code generated by go test -run TestZBuilder -update. DO NOT EDIT.
+
+package i64
+
+// ADD appends a ADD instruction.
+func (b *Builder) ADD(src, dst Addr) { b.add(ADD, src, dst) }
+
+// OR appends a OR instruction.
+func (b *Builder) OR(src, dst Addr) { b.add(OR, src, dst) }
+
+// ADC appends a ADC instruction.
+func (b *Builder) ADC(src, dst Addr) { b.add(ADC, src, dst) }
+
+// SBB appends a SBB instruction.
+func (b *Builder) SBB(src, dst Addr) { b.add(SBB, src, dst) }
+
+// AND appends a AND instruction.
+func (b *Builder) AND(src, dst Addr) { b.add(AND, src, dst) }
+
+// SUB appends a SUB instruction.
+func (b *Builder) SUB(src, dst Addr) { b.add(SUB, src, dst) }
+
+// XOR appends a XOR instruction.
+func (b *Builder) XOR(src, dst Addr) { b.add(XOR, src, dst) }
+
+// CMP appends a CMP instruction.
+func (b *Builder) CMP(src, dst Addr) { b.add(CMP, src, dst) }
+
+// ADDL appends a ADDL instruction.
+func (b *Builder) ADDL(src, dst Addr) { b.add(ADDL, src, dst) }
+
+// ORL appends a ORL instruction.
+func (b *Builder) ORL(src, dst Addr) { b.add(ORL, src, dst) }
+
+// ADCL appends a ADCL instruction.
+func (b *Builder) ADCL(src, dst Addr) { b.add(ADCL, src, dst) }
+
+// SBBL appends a SBBL instruction.
+func (b *Builder) SBBL(src, dst Addr) { b.add(SBBL, src, dst) }
+
+// ANDL appends a ANDL instruction.
+func (b *Builder) ANDL(src, dst Addr) { b.add(ANDL, src, dst) }
+
+// SUBL appends a SUBL instruction.
+func (b *Builder) SUBL(src, dst Addr) { b.add(SUBL, src, dst) }
+
+// XORL appends a XORL instruction.
+func (b *Builder) XORL(src, dst Addr) { b.add(XORL, src, dst) }
+
+// CMPL appends a CMPL instruction.
+func (b *Builder) CMPL(src, dst Addr) { b.add(CMPL, src, dst) }
+
+// ADDQ appends a ADDQ instruction.
+func (b *Builder) ADDQ(src, dst Addr) { b.add(ADDQ, src, dst) }
+
+// ORQ appends a ORQ instruction.
+func (b *Builder) ORQ(src, dst Addr) { b.add(ORQ, src, dst) }
+
+// ADCQ appends a ADCQ instruction.
+func (b *Builder) ADCQ(src, dst Addr) { b.add(ADCQ, src, dst) }
+
+// SBBQ appends a SBBQ instruction.
+func (b *Builder) SBBQ(src, dst Addr) { b.add(SBBQ, src, dst) }
+
+// ANDQ appends a ANDQ instruction.
+func (b *Builder) ANDQ(src, dst Addr) { b.add(ANDQ, src, dst) }
+
+// SUBQ appends a SUBQ instruction.
+func (b *Builder) SUBQ(src, dst Addr) { b.add(SUBQ, src, dst) }
+
+// XORQ appends a XORQ instruction.
+func (b *Builder) XORQ(src, dst Addr) { b.add(XORQ, src, dst) }
+
+// CMPQ appends a CMPQ instruction.
+func (b *Builder) CMPQ(src, dst Addr) { b.add(CMPQ, src, dst) }
+
+// IMULL appends a IMULL instruction.
+func (b *Builder) IMULL(args ...Addr) { b.addOps(IMULL, args) }
+
+// IMULQ appends a IMULQ instruction.
+func (b *Builder) IMULQ(args ...Addr) { b.addOps(IMULQ, args) }
+
+// IDIVL appends a IDIVL instruction.
+func (b *Builder) IDIVL(dst Addr) { b.add(IDIVL, Addr{}, dst) }
+
+// IDIVQ appends a IDIVQ instruction.
+func (b *Builder) IDIVQ(dst Addr) { b.add(IDIVQ, Addr{}, dst) }
+
+// SHLDL appends a SHLDL instruction.
+func (b *Builder) SHLDL(args ...Addr) { b.addOps(SHLDL, args) }
+
+// SHLDQ appends a SHLDQ instruction.
+func (b *Builder) SHLDQ(args ...Addr) { b.addOps(SHLDQ, args) }
+
+// SHRDL appends a SHRDL instruction.
+func (b *Builder) SHRDL(args ...Addr) { b.addOps(SHRDL, args) }
+
+// SHRDQ appends a SHRDQ instruction.
+func (b *Builder) SHRDQ(args ...Addr) { b.addOps(SHRDQ, args) }
+
+// TESTB appends a TESTB instruction.
+func (b *Builder) TESTB(src, dst Addr) { b.add(TESTB, src, dst) }
+
+// TESTL appends a TESTL instruction.
+func (b *Builder) TESTL(src, dst Addr) { b.add(TESTL, src, dst) }
+
+// TESTQ appends a TESTQ instruction.
+func (b *Builder) TESTQ(src, dst Addr) { b.add(TESTQ, src, dst) }
+
+// BTL appends a BTL instruction.
+func (b *Builder) BTL(src, dst Addr) { b.add(BTL, src, dst) }
+
+// BTSL appends a BTSL instruction.
+func (b *Builder) BTSL(src, dst Addr) { b.add(BTSL, src, dst) }
+
+// BTRL appends a BTRL instruction.
+func (b *Builder) BTRL(src, dst Addr) { b.add(BTRL, src, dst) }
+
+// BTCL appends a BTCL instruction.
+func (b *Builder) BTCL(src, dst Addr) { b.add(BTCL, src, dst) }
+
+// BTQ appends a BTQ instruction.
+func (b *Builder) BTQ(src, dst Addr) { b.add(BTQ, src, dst) }
+
+// BTSQ appends a BTSQ instruction.
+func (b *Builder) BTSQ(src, dst Addr) { b.add(BTSQ, src, dst) }
+
+// BTRQ appends a BTRQ instruction.
+func (b *Builder) BTRQ(src, dst Addr) { b.add(BTRQ, src, dst) }
+
+// BTCQ appends a BTCQ instruction.
+func (b *Builder) BTCQ(src, dst Addr) { b.add(BTCQ, src, dst) }
+
+// BSFL appends a BSFL instruction.
+func (b *Builder) BSFL(src, dst Addr) { b.add(BSFL, src, dst) }
+
+// BSRL appends a BSRL instruction.
+func (b *Builder) BSRL(src, dst Addr) { b.add(BSRL, src, dst) }
+
+// BSFQ appends a BSFQ instruction.
+func (b *Builder) BSFQ(src, dst Addr) { b.add(BSFQ, src, dst) }
+
+// BSRQ appends a BSRQ instruction.
+func (b *Builder) BSRQ(src, dst Addr) { b.add(BSRQ, src, dst) }
+
+// XADDL appends a XADDL instruction.
+func (b *Builder) XADDL(src, dst Addr) { b.add(XADDL, src, dst) }
+
+// XADDQ appends a XADDQ instruction.
+func (b *Builder) XADDQ(src, dst Addr) { b.add(XADDQ, src, dst) }
+
+// XCHGL appends a XCHGL instruction.
+func (b *Builder) XCHGL(src, dst Addr) { b.add(XCHGL, src, dst) }
+
+// XCHGQ appends a XCHGQ instruction.
+func (b *Builder) XCHGQ(src, dst Addr) { b.add(XCHGQ, src, dst) }
+
+// CMPXCHGL appends a CMPXCHGL instruction.
+func (b *Builder) CMPXCHGL(src, dst Addr) { b.add(CMPXCHGL, src, dst) }
+
+// CMPXCHGQ appends a CMPXCHGQ instruction.
+func (b *Builder) CMPXCHGQ(src, dst Addr) { b.add(CMPXCHGQ, src, dst) }
+
+// CMPXCHG8B appends a CMPXCHG8B instruction.
+func (b *Builder) CMPXCHG8B(dst Addr) { b.add(CMPXCHG8B, Addr{}, dst) }
+
+// CMPXCHG16B appends a CMPXCHG16B instruction.
+func (b *Builder) CMPXCHG16B(dst Addr) { b.add(CMPXCHG16B, Addr{}, dst) }
+
+// LFENCE appends a LFENCE instruction.
+func (b *Builder) LFENCE() { b.add(LFENCE, Addr{}, Addr{}) }
+
+// MFENCE appends a MFENCE instruction.
+func (b *Builder) MFENCE() { b.add(MFENCE, Addr{}, Addr{}) }
+
+// SFENCE appends a SFENCE instruction.
+func (b *Builder) SFENCE() { b.add(SFENCE, Addr{}, Addr{}) }
+
+// CPUID appends a CPUID instruction.
+func (b *Builder) CPUID() { b.add(CPUID, Addr{}, Addr{}) }
+
+// XGETBV appends a XGETBV instruction.
+func (b *Builder) XGETBV() { b.add(XGETBV, Addr{}, Addr{}) }
+
+// MOVSB appends a MOVSB instruction.
+func (b *Builder) MOVSB() { b.add(MOVSB, Addr{}, Addr{}) }
+
+// MOVSW appends a MOVSW instruction.
+func (b *Builder) MOVSW() { b.add(MOVSW, Addr{}, Addr{}) }
+
+// MOVSL appends a MOVSL instruction.
+func (b *Builder) MOVSL() { b.add(MOVSL, Addr{}, Addr{}) }
+
+// MOVSQ appends a MOVSQ instruction.
+func (b *Builder) MOVSQ() { b.add(MOVSQ, Addr{}, Addr{}) }
+
+// STOSB appends a STOSB instruction.
+func (b *Builder) STOSB() { b.add(STOSB, Addr{}, Addr{}) }
+
+// STOSW appends a STOSW instruction.
+func (b *Builder) STOSW() { b.add(STOSW, Addr{}, Addr{}) }
+
+// STOSL appends a STOSL instruction.
+func (b *Builder) STOSL() { b.add(STOSL, Addr{}, Addr{}) }
+
+// STOSQ appends a STOSQ instruction.
+func (b *Builder) STOSQ() { b.add(STOSQ, Addr{}, Addr{}) }
+
+// LODSB appends a LODSB instruction.
+func (b *Builder) LODSB() { b.add(LODSB, Addr{}, Addr{}) }
+
+// LODSW appends a LODSW instruction.
+func (b *Builder) LODSW() { b.add(LODSW, Addr{}, Addr{}) }
+
+// LODSL appends a LODSL instruction.
+func (b *Builder) LODSL() { b.add(LODSL, Addr{}, Addr{}) }
+
+// LODSQ appends a LODSQ instruction.
+func (b *Builder) LODSQ() { b.add(LODSQ, Addr{}, Addr{}) }
+
+// CMPSB appends a CMPSB instruction.
+func (b *Builder) CMPSB() { b.add(CMPSB, Addr{}, Addr{}) }
+
+// CMPSW appends a CMPSW instruction.
+func (b *Builder) CMPSW() { b.add(CMPSW, Addr{}, Addr{}) }
+
+// CMPSL appends a CMPSL instruction.
+func (b *Builder) CMPSL() { b.add(CMPSL, Addr{}, Addr{}) }
+
+// CMPSQ appends a CMPSQ instruction.
+func (b *Builder) CMPSQ() { b.add(CMPSQ, Addr{}, Addr{}) }
+
+// SCASB appends a SCASB instruction.
+func (b *Builder) SCASB() { b.add(SCASB, Addr{}, Addr{}) }
+
+// SCASW appends a SCASW instruction.
+func (b *Builder) SCASW() { b.add(SCASW, Addr{}, Addr{}) }
+
+// SCASL appends a SCASL instruction.
+func (b *Builder) SCASL() { b.add(SCASL, Addr{}, Addr{}) }
+
+// SCASQ appends a SCASQ instruction.
+func (b *Builder) SCASQ() { b.add(SCASQ, Addr{}, Addr{}) }
+
+// CLD appends a CLD instruction.
+func (b *Builder) CLD() { b.add(CLD, Addr{}, Addr{}) }
+
+// STD appends a STD instruction.
+func (b *Builder) STD() { b.add(STD, Addr{}, Addr{}) }
+
+// POPCNTL appends a POPCNTL instruction.
+func (b *Builder) POPCNTL(src, dst Addr) { b.add(POPCNTL, src, dst) }
+
+// POPCNTQ appends a POPCNTQ instruction.
+func (b *Builder) POPCNTQ(src, dst Addr) { b.add(POPCNTQ, src, dst) }
+
+// LZCNTL appends a LZCNTL instruction.
+func (b *Builder) LZCNTL(src, dst Addr) { b.add(LZCNTL, src, dst) }
+
+// LZCNTQ appends a LZCNTQ instruction.
+func (b *Builder) LZCNTQ(src, dst Addr) { b.add(LZCNTQ, src, dst) }
+
+// TZCNTL appends a TZCNTL instruction.
+func (b *Builder) TZCNTL(src, dst Addr) { b.add(TZCNTL, src, dst) }
+
+// TZCNTQ appends a TZCNTQ instruction.
+func (b *Builder) TZCNTQ(src, dst Addr) { b.add(TZCNTQ, src, dst) }
+
+// ANDNL appends a ANDNL instruction.
+func (b *Builder) ANDNL(args ...Addr) { b.addOps(ANDNL, args) }
+
+// ANDNQ appends a ANDNQ instruction.
+func (b *Builder) ANDNQ(args ...Addr) { b.addOps(ANDNQ, args) }
+
+// BEXTRL appends a BEXTRL instruction.
+func (b *Builder) BEXTRL(args ...Addr) { b.addOps(BEXTRL, args) }
+
+// BEXTRQ appends a BEXTRQ instruction.
+func (b *Builder) BEXTRQ(args ...Addr) { b.addOps(BEXTRQ, args) }
+
+// BLSIL appends a BLSIL instruction.
+func (b *Builder) BLSIL(src, dst Addr) { b.add(BLSIL, src, dst) }
+
+// BLSIQ appends a BLSIQ instruction.
+func (b *Builder) BLSIQ(src, dst Addr) { b.add(BLSIQ, src, dst) }
+
+// BLSMSKL appends a BLSMSKL instruction.
+func (b *Builder) BLSMSKL(src, dst Addr) { b.add(BLSMSKL, src, dst) }
+
+// BLSMSKQ appends a BLSMSKQ instruction.
+func (b *Builder) BLSMSKQ(src, dst Addr) { b.add(BLSMSKQ, src, dst) }
+
+// BLSRL appends a BLSRL instruction.
+func (b *Builder) BLSRL(src, dst Addr) { b.add(BLSRL, src, dst) }
+
+// BLSRQ appends a BLSRQ instruction.
+func (b *Builder) BLSRQ(src, dst Addr) { b.add(BLSRQ, src, dst) }
+
+// BZHIL appends a BZHIL instruction.
+func (b *Builder) BZHIL(args ...Addr) { b.addOps(BZHIL, args) }
+
+// BZHIQ appends a BZHIQ instruction.
+func (b *Builder) BZHIQ(args ...Addr) { b.addOps(BZHIQ, args) }
+
+// MULXL appends a MULXL instruction.
+func (b *Builder) MULXL(args ...Addr) { b.addOps(MULXL, args) }
+
+// MULXQ appends a MULXQ instruction.
+func (b *Builder) MULXQ(args ...Addr) { b.addOps(MULXQ, args) }
+
+// PDEPL appends a PDEPL instruction.
+func (b *Builder) PDEPL(args ...Addr) { b.addOps(PDEPL, args) }
+
+// PDEPQ appends a PDEPQ instruction.
+func (b *Builder) PDEPQ(args ...Addr) { b.addOps(PDEPQ, args) }
+
+// PEXTL appends a PEXTL instruction.
+func (b *Builder) PEXTL(args ...Addr) { b.addOps(PEXTL, args) }
+
+// PEXTQ appends a PEXTQ instruction.
+func (b *Builder) PEXTQ(args ...Addr) { b.addOps(PEXTQ, args) }
+
+// RORXL appends a RORXL instruction.
+func (b *Builder) RORXL(args ...Addr) { b.addOps(RORXL, args) }
+
+// RORXQ appends a RORXQ instruction.
+func (b *Builder) RORXQ(args ...Addr) { b.addOps(RORXQ, args) }
+
+// SARXL appends a SARXL instruction.
+func (b *Builder) SARXL(args ...Addr) { b.addOps(SARXL, args) }
+
+// SARXQ appends a SARXQ instruction.
+func (b *Builder) SARXQ(args ...Addr) { b.addOps(SARXQ, args) }
+
+// SHLXL appends a SHLXL instruction.
+func (b *Builder) SHLXL(args ...Addr) { b.addOps(SHLXL, args) }
+
+// SHLXQ appends a SHLXQ instruction.
+func (b *Builder) SHLXQ(args ...Addr) { b.addOps(SHLXQ, args) }
+
+// SHRXL appends a SHRXL instruction.
+func (b *Builder) SHRXL(args ...Addr) { b.addOps(SHRXL, args) }
+
+// SHRXQ appends a SHRXQ instruction.
+func (b *Builder) SHRXQ(args ...Addr) { b.addOps(SHRXQ, args) }
+
+// MOVB appends a MOVB instruction.
+func (b *Builder) MOVB(src, dst Addr) { b.add(MOVB, src, dst) }
+
+// MOVL appends a MOVL instruction.
+func (b *Builder) MOVL(src, dst Addr) { b.add(MOVL, src, dst) }
+
+// MOVQ appends a MOVQ instruction.
+func (b *Builder) MOVQ(src, dst Addr) { b.add(MOVQ, src, dst) }
+
+// MOVBLSX appends a MOVBLSX instruction.
+func (b *Builder) MOVBLSX(src, dst Addr) { b.add(MOVBLSX, src, dst) }
+
+// MOVBLZX appends a MOVBLZX instruction.
+func (b *Builder) MOVBLZX(src, dst Addr) { b.add(MOVBLZX, src, dst) }
+
+// MOVWLSX appends a MOVWLSX instruction.
+func (b *Builder) MOVWLSX(src, dst Addr) { b.add(MOVWLSX, src, dst) }
+
+// MOVWLZX appends a MOVWLZX instruction.
+func (b *Builder) MOVWLZX(src, dst Addr) { b.add(MOVWLZX, src, dst) }
+
+// MOVBQSX appends a MOVBQSX instruction.
+func (b *Builder) MOVBQSX(src, dst Addr) { b.add(MOVBQSX, src, dst) }
+
+// MOVBQZX appends a MOVBQZX instruction.
+func (b *Builder) MOVBQZX(src, dst Addr) { b.add(MOVBQZX, src, dst) }
+
+// MOVWQSX appends a MOVWQSX instruction.
+func (b *Builder) MOVWQSX(src, dst Addr) { b.add(MOVWQSX, src, dst) }
+
+// MOVWQZX appends a MOVWQZX instruction.
+func (b *Builder) MOVWQZX(src, dst Addr) { b.add(MOVWQZX, src, dst) }
+
+// MOVLQSX appends a MOVLQSX instruction.
+func (b *Builder) MOVLQSX(src, dst Addr) { b.add(MOVLQSX, src, dst) }
+
+// LEAL appends a LEAL instruction.
+func (b *Builder) LEAL(src, dst Addr) { b.add(LEAL, src, dst) }
+
+// LEAQ appends a LEAQ instruction.
+func (b *Builder) LEAQ(src, dst Addr) { b.add(LEAQ, src, dst) }
+
+// CALL appends a CALL instruction.
+func (b *Builder) CALL(label string) { b.jump(CALL, label) }
+
+// RET appends a RET instruction.
+func (b *Builder) RET() { b.add(RET, Addr{}, Addr{}) }
+
+// JMP appends a JMP instruction.
+func (b *Builder) JMP(label string) { b.jump(JMP, label) }
+
+// JO appends a JO instruction.
+func (b *Builder) JO(label string) { b.jump(JO, label) }
+
+// JNO appends a JNO instruction.
+func (b *Builder) JNO(label string) { b.jump(JNO, label) }
+
+// JB appends a JB instruction.
+func (b *Builder) JB(label string) { b.jump(JB, label) }
+
+// JAE appends a JAE instruction.
+func (b *Builder) JAE(label string) { b.jump(JAE, label) }
+
+// JE appends a JE instruction.
+func (b *Builder) JE(label string) { b.jump(JE, label) }
+
+// JNE appends a JNE instruction.
+func (b *Builder) JNE(label string) { b.jump(JNE, label) }
+
+// JBE appends a JBE instruction.
+func (b *Builder) JBE(label string) { b.jump(JBE, label) }
+
+// JHI appends a JHI instruction.
+func (b *Builder) JHI(label string) { b.jump(JHI, label) }
+
+// JS appends a JS instruction.
+func (b *Builder) JS(label string) { b.jump(JS, label) }
+
+// JNS appends a JNS instruction.
+func (b *Builder) JNS(label string) { b.jump(JNS, label) }
+
+// JP appends a JP instruction.
+func (b *Builder) JP(label string) { b.jump(JP, label) }
+
+// JNP appends a JNP instruction.
+func (b *Builder) JNP(label string) { b.jump(JNP, label) }
+
+// JL appends a JL instruction.
+func (b *Builder) JL(label string) { b.jump(JL, label) }
+
+// JGE appends a JGE instruction.
+func (b *Builder) JGE(label string) { b.jump(JGE, label) }
+
+// JLE appends a JLE instruction.
+func (b *Builder) JLE(label string) { b.jump(JLE, label) }
+
+// JG appends a JG instruction.
+func (b *Builder) JG(label string) { b.jump(JG, label) }
+
+// SETO appends a SETO instruction.
+func (b *Builder) SETO(dst Addr) { b.add(SETO, Addr{}, dst) }
+
+// SETNO appends a SETNO instruction.
+func (b *Builder) SETNO(dst Addr) { b.add(SETNO, Addr{}, dst) }
+
+// SETB appends a SETB instruction.
+func (b *Builder) SETB(dst Addr) { b.add(SETB, Addr{}, dst) }
+
+// SETAE appends a SETAE instruction.
+func (b *Builder) SETAE(dst Addr) { b.add(SETAE, Addr{}, dst) }
+
+// SETE appends a SETE instruction.
+func (b *Builder) SETE(dst Addr) { b.add(SETE, Addr{}, dst) }
+
+// SETNE appends a SETNE instruction.
+func (b *Builder) SETNE(dst Addr) { b.add(SETNE, Addr{}, dst) }
+
+// SETBE appends a SETBE instruction.
+func (b *Builder) SETBE(dst Addr) { b.add(SETBE, Addr{}, dst) }
+
+// SETA appends a SETA instruction.
+func (b *Builder) SETA(dst Addr) { b.add(SETA, Addr{}, dst) }
+
+// SETS appends a SETS instruction.
+func (b *Builder) SETS(dst Addr) { b.add(SETS, Addr{}, dst) }
+
+// SETNS appends a SETNS instruction.
+func (b *Builder) SETNS(dst Addr) { b.add(SETNS, Addr{}, dst) }
+
+// SETP appends a SETP instruction.
+func (b *Builder) SETP(dst Addr) { b.add(SETP, Addr{}, dst) }
+
+// SETNP appends a SETNP instruction.
+func (b *Builder) SETNP(dst Addr) { b.add(SETNP, Addr{}, dst) }
+
+// SETL appends a SETL instruction.
+func (b *Builder) SETL(dst Addr) { b.add(SETL, Addr{}, dst) }
+
+// SETGE appends a SETGE instruction.
+func (b *Builder) SETGE(dst Addr) { b.add(SETGE, Addr{}, dst) }
+
+// SETLE appends a SETLE instruction.
+func (b *Builder) SETLE(dst Addr) { b.add(SETLE, Addr{}, dst) }
+
+// SETG appends a SETG instruction.
+func (b *Builder) SETG(dst Addr) { b.add(SETG, Addr{}, dst) }
+
+// CMOVLOS appends a CMOVLOS instruction.
+func (b *Builder) CMOVLOS(src, dst Addr) { b.add(CMOVLOS, src, dst) }
+
+// CMOVLOC appends a CMOVLOC instruction.
+func (b *Builder) CMOVLOC(src, dst Addr) { b.add(CMOVLOC, src, dst) }
+
+// CMOVLCS appends a CMOVLCS instruction.
+func (b *Builder) CMOVLCS(src, dst Addr) { b.add(CMOVLCS, src, dst) }
+
+// CMOVLCC appends a CMOVLCC instruction.
+func (b *Builder) CMOVLCC(src, dst Addr) { b.add(CMOVLCC, src, dst) }
+
+// CMOVLEQ appends a CMOVLEQ instruction.
+func (b *Builder) CMOVLEQ(src, dst Addr) { b.add(CMOVLEQ, src, dst) }
+
+// CMOVLNE appends a CMOVLNE instruction.
+func (b *Builder) CMOVLNE(src, dst Addr) { b.add(CMOVLNE, src, dst) }
+
+// CMOVLLS appends a CMOVLLS instruction.
+func (b *Builder) CMOVLLS(src, dst Addr) { b.add(CMOVLLS, src, dst) }
+
+// CMOVLHI appends a CMOVLHI instruction.
+func (b *Builder) CMOVLHI(src, dst Addr) { b.add(CMOVLHI, src, dst) }
+
+// CMOVLMI appends a CMOVLMI instruction.
+func (b *Builder) CMOVLMI(src, dst Addr) { b.add(CMOVLMI, src, dst) }
+
+// CMOVLPL appends a CMOVLPL instruction.
+func (b *Builder) CMOVLPL(src, dst Addr) { b.add(CMOVLPL, src, dst) }
+
+// CMOVLPS appends a CMOVLPS instruction.
+func (b *Builder) CMOVLPS(src, dst Addr) { b.add(CMOVLPS, src, dst) }
+
+// CMOVLPC appends a CMOVLPC instruction.
+func (b *Builder) CMOVLPC(src, dst Addr) { b.add(CMOVLPC, src, dst) }
+
+// CMOVLLT appends a CMOVLLT instruction.
+func (b *Builder) CMOVLLT(src, dst Addr) { b.add(CMOVLLT, src, dst) }
+
+// CMOVLGE appends a CMOVLGE instruction.
+func (b *Builder) CMOVLGE(src, dst Addr) { b.add(CMOVLGE, src, dst) }
+
+// CMOVLLE appends a CMOVLLE instruction.
+func (b *Builder) CMOVLLE(src, dst Addr) { b.add(CMOVLLE, src, dst) }
+
+// CMOVLGT appends a CMOVLGT instruction.
+func (b *Builder) CMOVLGT(src, dst Addr) { b.add(CMOVLGT, src, dst) }
+
+// CMOVQOS appends a CMOVQOS instruction.
+func (b *Builder) CMOVQOS(src, dst Addr) { b.add(CMOVQOS, src, dst) }
+
+// CMOVQOC appends a CMOVQOC instruction.
+func (b *Builder) CMOVQOC(src, dst Addr) { b.add(CMOVQOC, src, dst) }
+
+// CMOVQCS appends a CMOVQCS instruction.
+func (b *Builder) CMOVQCS(src, dst Addr) { b.add(CMOVQCS, src, dst) }
+
+// CMOVQCC appends a CMOVQCC instruction.
+func (b *Builder) CMOVQCC(src, dst Addr) { b.add(CMOVQCC, src, dst) }
+
+// CMOVQEQ appends a CMOVQEQ instruction.
+func (b *Builder) CMOVQEQ(src, dst Addr) { b.add(CMOVQEQ, src, dst) }
+
+// CMOVQNE appends a CMOVQNE instruction.
+func (b *Builder) CMOVQNE(src, dst Addr) { b.add(CMOVQNE, src, dst) }
+
+// CMOVQLS appends a CMOVQLS instruction.
+func (b *Builder) CMOVQLS(src, dst Addr) { b.add(CMOVQLS, src, dst) }
+
+// CMOVQHI appends a CMOVQHI instruction.
+func (b *Builder) CMOVQHI(src, dst Addr) { b.add(CMOVQHI, src, dst) }
+
+// CMOVQMI appends a CMOVQMI instruction.
+func (b *Builder) CMOVQMI(src, dst Addr) { b.add(CMOVQMI, src, dst) }
+
+// CMOVQPL appends a CMOVQPL instruction.
+func (b *Builder) CMOVQPL(src, dst Addr) { b.add(CMOVQPL, src, dst) }
+
+// CMOVQPS appends a CMOVQPS instruction.
+func (b *Builder) CMOVQPS(src, dst Addr) { b.add(CMOVQPS, src, dst) }
+
+// CMOVQPC appends a CMOVQPC instruction.
+func (b *Builder) CMOVQPC(src, dst Addr) { b.add(CMOVQPC, src, dst) }
+
+// CMOVQLT appends a CMOVQLT instruction.
+func (b *Builder) CMOVQLT(src, dst Addr) { b.add(CMOVQLT, src, dst) }
+
+// CMOVQGE appends a CMOVQGE instruction.
+func (b *Builder) CMOVQGE(src, dst Addr) { b.add(CMOVQGE, src, dst) }
+
+// CMOVQLE appends a CMOVQLE instruction.
+func (b *Builder) CMOVQLE(src, dst Addr) { b.add(CMOVQLE, src, dst) }
+
+// CMOVQGT appends a CMOVQGT instruction.
+func (b *Builder) CMOVQGT(src, dst Addr) { b.add(CMOVQGT, src, dst) }
+
+// PUSHQ appends a PUSHQ instruction.
+func (b *Builder) PUSHQ(src Addr) { b.add(PUSHQ, src, Addr{}) }
+
+// POPQ appends a POPQ instruction.
+func (b *Builder) POPQ(dst Addr) { b.add(POPQ, Addr{}, dst) }
+
+// MOVSS appends a MOVSS instruction.
+func (b *Builder) MOVSS(src, dst Addr) { b.add(MOVSS, src, dst) }
+
+// ADDSS appends a ADDSS instruction.
+func (b *Builder) ADDSS(src, dst Addr) { b.add(ADDSS, src, dst) }
+
+// MULSS appends a MULSS instruction.
+func (b *Builder) MULSS(src, dst Addr) { b.add(MULSS, src, dst) }
+
+// SUBSS appends a SUBSS instruction.
+func (b *Builder) SUBSS(src, dst Addr) { b.add(SUBSS, src, dst) }
+
+// MINSS appends a MINSS instruction.
+func (b *Builder) MINSS(src, dst Addr) { b.add(MINSS, src, dst) }
+
+// DIVSS appends a DIVSS instruction.
+func (b *Builder) DIVSS(src, dst Addr) { b.add(DIVSS, src, dst) }
+
+// MAXSS appends a MAXSS instruction.
+func (b *Builder) MAXSS(src, dst Addr) { b.add(MAXSS, src, dst) }
+
+// SQRTSS appends a SQRTSS instruction.
+func (b *Builder) SQRTSS(src, dst Addr) { b.add(SQRTSS, src, dst) }
+
+// MOVSD appends a MOVSD instruction.
+func (b *Builder) MOVSD(src, dst Addr) { b.add(MOVSD, src, dst) }
+
+// ADDSD appends a ADDSD instruction.
+func (b *Builder) ADDSD(src, dst Addr) { b.add(ADDSD, src, dst) }
+
+// MULSD appends a MULSD instruction.
+func (b *Builder) MULSD(src, dst Addr) { b.add(MULSD, src, dst) }
+
+// SUBSD appends a SUBSD instruction.
+func (b *Builder) SUBSD(src, dst Addr) { b.add(SUBSD, src, dst) }
+
+// MINSD appends a MINSD instruction.
+func (b *Builder) MINSD(src, dst Addr) { b.add(MINSD, src, dst) }
+
+// DIVSD appends a DIVSD instruction.
+func (b *Builder) DIVSD(src, dst Addr) { b.add(DIVSD, src, dst) }
+
+// MAXSD appends a MAXSD instruction.
+func (b *Builder) MAXSD(src, dst Addr) { b.add(MAXSD, src, dst) }
+
+// SQRTSD appends a SQRTSD instruction.
+func (b *Builder) SQRTSD(src, dst Addr) { b.add(SQRTSD, src, dst) }
+
+// UCOMISS appends a UCOMISS instruction.
+func (b *Builder) UCOMISS(src, dst Addr) { b.add(UCOMISS, src, dst) }
+
+// UCOMISD appends a UCOMISD instruction.
+func (b *Builder) UCOMISD(src, dst Addr) { b.add(UCOMISD, src, dst) }
+
+// COMISS appends a COMISS instruction.
+func (b *Builder) COMISS(src, dst Addr) { b.add(COMISS, src, dst) }
+
+// COMISD appends a COMISD instruction.
+func (b *Builder) COMISD(src, dst Addr) { b.add(COMISD, src, dst) }
+
+// CVTSL2SS appends a CVTSL2SS instruction.
+func (b *Builder) CVTSL2SS(src, dst Addr) { b.add(CVTSL2SS, src, dst) }
+
+// CVTSQ2SS appends a CVTSQ2SS instruction.
+func (b *Builder) CVTSQ2SS(src, dst Addr) { b.add(CVTSQ2SS, src, dst) }
+
+// CVTSL2SD appends a CVTSL2SD instruction.
+func (b *Builder) CVTSL2SD(src, dst Addr) { b.add(CVTSL2SD, src, dst) }
+
+// CVTSQ2SD appends a CVTSQ2SD instruction.
+func (b *Builder) CVTSQ2SD(src, dst Addr) { b.add(CVTSQ2SD, src, dst) }
+
+// CVTSS2SL appends a CVTSS2SL instruction.
+func (b *Builder) CVTSS2SL(src, dst Addr) { b.add(CVTSS2SL, src, dst) }
+
+// CVTSS2SQ appends a CVTSS2SQ instruction.
+func (b *Builder) CVTSS2SQ(src, dst Addr) { b.add(CVTSS2SQ, src, dst) }
+
+// CVTSD2SL appends a CVTSD2SL instruction.
+func (b *Builder) CVTSD2SL(src, dst Addr) { b.add(CVTSD2SL, src, dst) }
+
+// CVTSD2SQ appends a CVTSD2SQ instruction.
+func (b *Builder) CVTSD2SQ(src, dst Addr) { b.add(CVTSD2SQ, src, dst) }
+
+// CVTTSS2SL appends a CVTTSS2SL instruction.
+func (b *Builder) CVTTSS2SL(src, dst Addr) { b.add(CVTTSS2SL, src, dst) }
+
+// CVTTSS2SQ appends a CVTTSS2SQ instruction.
+func (b *Builder) CVTTSS2SQ(src, dst Addr) { b.add(CVTTSS2SQ, src, dst) }
+
+// CVTTSD2SL appends a CVTTSD2SL instruction.
+func (b *Builder) CVTTSD2SL(src, dst Addr) { b.add(CVTTSD2SL, src, dst) }
+
+// CVTTSD2SQ appends a CVTTSD2SQ instruction.
+func (b *Builder) CVTTSD2SQ(src, dst Addr) { b.add(CVTTSD2SQ, src, dst) }
+
+// CVTSS2SD appends a CVTSS2SD instruction.
+func (b *Builder) CVTSS2SD(src, dst Addr) { b.add(CVTSS2SD, src, dst) }
+
+// CVTSD2SS appends a CVTSD2SS instruction.
+func (b *Builder) CVTSD2SS(src, dst Addr) { b.add(CVTSD2SS, src, dst) }
+
+// MOVDQU appends a MOVDQU instruction.
+func (b *Builder) MOVDQU(src, dst Addr) { b.add(MOVDQU, src, dst) }
+
+// MOVDQA appends a MOVDQA instruction.
+func (b *Builder) MOVDQA(src, dst Addr) { b.add(MOVDQA, src, dst) }
+
+// MOVUPS appends a MOVUPS instruction.
+func (b *Builder) MOVUPS(src, dst Addr) { b.add(MOVUPS, src, dst) }
+
+// MOVAPS appends a MOVAPS instruction.
+func (b *Builder) MOVAPS(src, dst Addr) { b.add(MOVAPS, src, dst) }
+
+// MOVUPD appends a MOVUPD instruction.
+func (b *Builder) MOVUPD(src, dst Addr) { b.add(MOVUPD, src, dst) }
+
+// MOVAPD appends a MOVAPD instruction.
+func (b *Builder) MOVAPD(src, dst Addr) { b.add(MOVAPD, src, dst) }
+
+// PADDB appends a PADDB instruction.
+func (b *Builder) PADDB(src, dst Addr) { b.add(PADDB, src, dst) }
+
+// PADDW appends a PADDW instruction.
+func (b *Builder) PADDW(src, dst Addr) { b.add(PADDW, src, dst) }
+
+// PADDD appends a PADDD instruction.
+func (b *Builder) PADDD(src, dst Addr) { b.add(PADDD, src, dst) }
+
+// PADDQ appends a PADDQ instruction.
+func (b *Builder) PADDQ(src, dst Addr) { b.add(PADDQ, src, dst) }
+
+// PSUBB appends a PSUBB instruction.
+func (b *Builder) PSUBB(src, dst Addr) { b.add(PSUBB, src, dst) }
+
+// PSUBW appends a PSUBW instruction.
+func (b *Builder) PSUBW(src, dst Addr) { b.add(PSUBW, src, dst) }
+
+// PSUBD appends a PSUBD instruction.
+func (b *Builder) PSUBD(src, dst Addr) { b.add(PSUBD, src, dst) }
+
+// PSUBQ appends a PSUBQ instruction.
+func (b *Builder) PSUBQ(src, dst Addr) { b.add(PSUBQ, src, dst) }
+
+// PMULLW appends a PMULLW instruction.
+func (b *Builder) PMULLW(src, dst Addr) { b.add(PMULLW, src, dst) }
+
+// PMULLD appends a PMULLD instruction.
+func (b *Builder) PMULLD(src, dst Addr) { b.add(PMULLD, src, dst) }
+
+// PMULUDQ appends a PMULUDQ instruction.
+func (b *Builder) PMULUDQ(src, dst Addr) { b.add(PMULUDQ, src, dst) }
+
+// PMADDWD appends a PMADDWD instruction.
+func (b *Builder) PMADDWD(src, dst Addr) { b.add(PMADDWD, src, dst) }
+
+// PAND appends a PAND instruction.
+func (b *Builder) PAND(src, dst Addr) { b.add(PAND, src, dst) }
+
+// PANDN appends a PANDN instruction.
+func (b *Builder) PANDN(src, dst Addr) { b.add(PANDN, src, dst) }
+
+// POR appends a POR instruction.
+func (b *Builder) POR(src, dst Addr) { b.add(POR, src, dst) }
+
+// PXOR appends a PXOR instruction.
+func (b *Builder) PXOR(src, dst Addr) { b.add(PXOR, src, dst) }
+
+// PCMPEQB appends a PCMPEQB instruction.
+func (b *Builder) PCMPEQB(src, dst Addr) { b.add(PCMPEQB, src, dst) }
+
+// PCMPEQW appends a PCMPEQW instruction.
+func (b *Builder) PCMPEQW(src, dst Addr) { b.add(PCMPEQW, src, dst) }
+
+// PCMPEQD appends a PCMPEQD instruction.
+func (b *Builder) PCMPEQD(src, dst Addr) { b.add(PCMPEQD, src, dst) }
+
+// PCMPGTB appends a PCMPGTB instruction.
+func (b *Builder) PCMPGTB(src, dst Addr) { b.add(PCMPGTB, src, dst) }
+
+// PCMPGTW appends a PCMPGTW instruction.
+func (b *Builder) PCMPGTW(src, dst Addr) { b.add(PCMPGTW, src, dst) }
+
+// PCMPGTD appends a PCMPGTD instruction.
+func (b *Builder) PCMPGTD(src, dst Addr) { b.add(PCMPGTD, src, dst) }
+
+// PMOVMSKB appends a PMOVMSKB instruction.
+func (b *Builder) PMOVMSKB(src, dst Addr) { b.add(PMOVMSKB, src, dst) }
+
+// PSHUFB appends a PSHUFB instruction.
+func (b *Builder) PSHUFB(src, dst Addr) { b.add(PSHUFB, src, dst) }
+
+// PSHUFD appends a PSHUFD instruction.
+func (b *Builder) PSHUFD(args ...Addr) { b.addOps(PSHUFD, args) }
+
+// SHUFPS appends a SHUFPS instruction.
+func (b *Builder) SHUFPS(args ...Addr) { b.addOps(SHUFPS, args) }
+
+// PSLLW appends a PSLLW instruction.
+func (b *Builder) PSLLW(src, dst Addr) { b.add(PSLLW, src, dst) }
+
+// PSLLD appends a PSLLD instruction.
+func (b *Builder) PSLLD(src, dst Addr) { b.add(PSLLD, src, dst) }
+
+// PSLLQ appends a PSLLQ instruction.
+func (b *Builder) PSLLQ(src, dst Addr) { b.add(PSLLQ, src, dst) }
+
+// PSRLW appends a PSRLW instruction.
+func (b *Builder) PSRLW(src, dst Addr) { b.add(PSRLW, src, dst) }
+
+// PSRLD appends a PSRLD instruction.
+func (b *Builder) PSRLD(src, dst Addr) { b.add(PSRLD, src, dst) }
+
+// PSRLQ appends a PSRLQ instruction.
+func (b *Builder) PSRLQ(src, dst Addr) { b.add(PSRLQ, src, dst) }
+
+// PSRAW appends a PSRAW instruction.
+func (b *Builder) PSRAW(src, dst Addr) { b.add(PSRAW, src, dst) }
+
+// PSRAD appends a PSRAD instruction.
+func (b *Builder) PSRAD(src, dst Addr) { b.add(PSRAD, src, dst) }
+
+// PSLLDQ appends a PSLLDQ instruction.
+func (b *Builder) PSLLDQ(src, dst Addr) { b.add(PSLLDQ, src, dst) }
+
+// PSRLDQ appends a PSRLDQ instruction.
+func (b *Builder) PSRLDQ(src, dst Addr) { b.add(PSRLDQ, src, dst) }
+
+// PUNPCKLBW appends a PUNPCKLBW instruction.
+func (b *Builder) PUNPCKLBW(src, dst Addr) { b.add(PUNPCKLBW, src, dst) }
+
+// PUNPCKLWD appends a PUNPCKLWD instruction.
+func (b *Builder) PUNPCKLWD(src, dst Addr) { b.add(PUNPCKLWD, src, dst) }
+
+// PUNPCKLDQ appends a PUNPCKLDQ instruction.
+func (b *Builder) PUNPCKLDQ(src, dst Addr) { b.add(PUNPCKLDQ, src, dst) }
+
+// PUNPCKLQDQ appends a PUNPCKLQDQ instruction.
+func (b *Builder) PUNPCKLQDQ(src, dst Addr) { b.add(PUNPCKLQDQ, src, dst) }
+
+// PUNPCKHBW appends a PUNPCKHBW instruction.
+func (b *Builder) PUNPCKHBW(src, dst Addr) { b.add(PUNPCKHBW, src, dst) }
+
+// PUNPCKHWD appends a PUNPCKHWD instruction.
+func (b *Builder) PUNPCKHWD(src, dst Addr) { b.add(PUNPCKHWD, src, dst) }
+
+// PUNPCKHDQ appends a PUNPCKHDQ instruction.
+func (b *Builder) PUNPCKHDQ(src, dst Addr) { b.add(PUNPCKHDQ, src, dst) }
+
+// PUNPCKHQDQ appends a PUNPCKHQDQ instruction.
+func (b *Builder) PUNPCKHQDQ(src, dst Addr) { b.add(PUNPCKHQDQ, src, dst) }
+
+// UNPCKLPS appends a UNPCKLPS instruction.
+func (b *Builder) UNPCKLPS(src, dst Addr) { b.add(UNPCKLPS, src, dst) }
+
+// UNPCKHPS appends a UNPCKHPS instruction.
+func (b *Builder) UNPCKHPS(src, dst Addr) { b.add(UNPCKHPS, src, dst) }
+
+// UNPCKLPD appends a UNPCKLPD instruction.
+func (b *Builder) UNPCKLPD(src, dst Addr) { b.add(UNPCKLPD, src, dst) }
+
+// UNPCKHPD appends a UNPCKHPD instruction.
+func (b *Builder) UNPCKHPD(src, dst Addr) { b.add(UNPCKHPD, src, dst) }
+
+// ADDPS appends a ADDPS instruction.
+func (b *Builder) ADDPS(src, dst Addr) { b.add(ADDPS, src, dst) }
+
+// MULPS appends a MULPS instruction.
+func (b *Builder) MULPS(src, dst Addr) { b.add(MULPS, src, dst) }
+
+// SUBPS appends a SUBPS instruction.
+func (b *Builder) SUBPS(src, dst Addr) { b.add(SUBPS, src, dst) }
+
+// MINPS appends a MINPS instruction.
+func (b *Builder) MINPS(src, dst Addr) { b.add(MINPS, src, dst) }
+
+// DIVPS appends a DIVPS instruction.
+func (b *Builder) DIVPS(src, dst Addr) { b.add(DIVPS, src, dst) }
+
+// MAXPS appends a MAXPS instruction.
+func (b *Builder) MAXPS(src, dst Addr) { b.add(MAXPS, src, dst) }
+
+// SQRTPS appends a SQRTPS instruction.
+func (b *Builder) SQRTPS(src, dst Addr) { b.add(SQRTPS, src, dst) }
+
+// ADDPD appends a ADDPD instruction.
+func (b *Builder) ADDPD(src, dst Addr) { b.add(ADDPD, src, dst) }
+
+// MULPD appends a MULPD instruction.
+func (b *Builder) MULPD(src, dst Addr) { b.add(MULPD, src, dst) }
+
+// SUBPD appends a SUBPD instruction.
+func (b *Builder) SUBPD(src, dst Addr) { b.add(SUBPD, src, dst) }
+
+// MINPD appends a MINPD instruction.
+func (b *Builder) MINPD(src, dst Addr) { b.add(MINPD, src, dst) }
+
+// DIVPD appends a DIVPD instruction.
+func (b *Builder) DIVPD(src, dst Addr) { b.add(DIVPD, src, dst) }
+
+// MAXPD appends a MAXPD instruction.
+func (b *Builder) MAXPD(src, dst Addr) { b.add(MAXPD, src, dst) }
+
+// SQRTPD appends a SQRTPD instruction.
+func (b *Builder) SQRTPD(src, dst Addr) { b.add(SQRTPD, src, dst) }
+
+// ANDPS appends a ANDPS instruction.
+func (b *Builder) ANDPS(src, dst Addr) { b.add(ANDPS, src, dst) }
+
+// ANDNPS appends a ANDNPS instruction.
+func (b *Builder) ANDNPS(src, dst Addr) { b.add(ANDNPS, src, dst) }
+
+// ORPS appends a ORPS instruction.
+func (b *Builder) ORPS(src, dst Addr) { b.add(ORPS, src, dst) }
+
+// XORPS appends a XORPS instruction.
+func (b *Builder) XORPS(src, dst Addr) { b.add(XORPS, src, dst) }
+
+// ANDPD appends a ANDPD instruction.
+func (b *Builder) ANDPD(src, dst Addr) { b.add(ANDPD, src, dst) }
+
+// ANDNPD appends a ANDNPD instruction.
+func (b *Builder) ANDNPD(src, dst Addr) { b.add(ANDNPD, src, dst) }
+
+// ORPD appends a ORPD instruction.
+func (b *Builder) ORPD(src, dst Addr) { b.add(ORPD, src, dst) }
+
+// XORPD appends a XORPD instruction.
+func (b *Builder) XORPD(src, dst Addr) { b.add(XORPD, src, dst) }
+
+// HADDPS appends a HADDPS instruction.
+func (b *Builder) HADDPS(src, dst Addr) { b.add(HADDPS, src, dst) }
+
+// HADDPD appends a HADDPD instruction.
+func (b *Builder) HADDPD(src, dst Addr) { b.add(HADDPD, src, dst) }
+
+// PMINSB appends a PMINSB instruction.
+func (b *Builder) PMINSB(src, dst Addr) { b.add(PMINSB, src, dst) }
+
+// PMINSW appends a PMINSW instruction.
+func (b *Builder) PMINSW(src, dst Addr) { b.add(PMINSW, src, dst) }
+
+// PMINSD appends a PMINSD instruction.
+func (b *Builder) PMINSD(src, dst Addr) { b.add(PMINSD, src, dst) }
+
+// PMAXSB appends a PMAXSB instruction.
+func (b *Builder) PMAXSB(src, dst Addr) { b.add(PMAXSB, src, dst) }
+
+// PMAXSW appends a PMAXSW instruction.
+func (b *Builder) PMAXSW(src, dst Addr) { b.add(PMAXSW, src, dst) }
+
+// PMAXSD appends a PMAXSD instruction.
+func (b *Builder) PMAXSD(src, dst Addr) { b.add(PMAXSD, src, dst) }
+
+// PMINUB appends a PMINUB instruction.
+func (b *Builder) PMINUB(src, dst Addr) { b.add(PMINUB, src, dst) }
+
+// PMINUW appends a PMINUW instruction.
+func (b *Builder) PMINUW(src, dst Addr) { b.add(PMINUW, src, dst) }
+
+// PMINUD appends a PMINUD instruction.
+func (b *Builder) PMINUD(src, dst Addr) { b.add(PMINUD, src, dst) }
+
+// PMAXUB appends a PMAXUB instruction.
+func (b *Builder) PMAXUB(src, dst Addr) { b.add(PMAXUB, src, dst) }
+
+// PMAXUW appends a PMAXUW instruction.
+func (b *Builder) PMAXUW(src, dst Addr) { b.add(PMAXUW, src, dst) }
+
+// PMAXUD appends a PMAXUD instruction.
+func (b *Builder) PMAXUD(src, dst Addr) { b.add(PMAXUD, src, dst) }
+
+// PTEST appends a PTEST instruction.
+func (b *Builder) PTEST(src, dst Addr) { b.add(PTEST, src, dst) }
+
+// PBLENDVB appends a PBLENDVB instruction.
+func (b *Builder) PBLENDVB(src, dst Addr) { b.add(PBLENDVB, src, dst) }
+
+// BLENDVPS appends a BLENDVPS instruction.
+func (b *Builder) BLENDVPS(src, dst Addr) { b.add(BLENDVPS, src, dst) }
+
+// BLENDVPD appends a BLENDVPD instruction.
+func (b *Builder) BLENDVPD(src, dst Addr) { b.add(BLENDVPD, src, dst) }
+
+// VMOVDQU appends a VMOVDQU instruction.
+func (b *Builder) VMOVDQU(src, dst Addr) { b.add(VMOVDQU, src, dst) }
+
+// VMOVDQA appends a VMOVDQA instruction.
+func (b *Builder) VMOVDQA(src, dst Addr) { b.add(VMOVDQA, src, dst) }
+
+// VMOVUPS appends a VMOVUPS instruction.
+func (b *Builder) VMOVUPS(src, dst Addr) { b.add(VMOVUPS, src, dst) }
+
+// VMOVAPS appends a VMOVAPS instruction.
+func (b *Builder) VMOVAPS(src, dst Addr) { b.add(VMOVAPS, src, dst) }
+
+// VADDPS appends a VADDPS instruction.
+func (b *Builder) VADDPS(args ...Addr) { b.addOps(VADDPS, args) }
+
+// VMULPS appends a VMULPS instruction.
+func (b *Builder) VMULPS(args ...Addr) { b.addOps(VMULPS, args) }
+
+// VSUBPS appends a VSUBPS instruction.
+func (b *Builder) VSUBPS(args ...Addr) { b.addOps(VSUBPS, args) }
+
+// VMINPS appends a VMINPS instruction.
+func (b *Builder) VMINPS(args ...Addr) { b.addOps(VMINPS, args) }
+
+// VDIVPS appends a VDIVPS instruction.
+func (b *Builder) VDIVPS(args ...Addr) { b.addOps(VDIVPS, args) }
+
+// VMAXPS appends a VMAXPS instruction.
+func (b *Builder) VMAXPS(args ...Addr) { b.addOps(VMAXPS, args) }
+
+// VADDPD appends a VADDPD instruction.
+func (b *Builder) VADDPD(args ...Addr) { b.addOps(VADDPD, args) }
+
+// VMULPD appends a VMULPD instruction.
+func (b *Builder) VMULPD(args ...Addr) { b.addOps(VMULPD, args) }
+
+// VSUBPD appends a VSUBPD instruction.
+func (b *Builder) VSUBPD(args ...Addr) { b.addOps(VSUBPD, args) }
+
+// VMINPD appends a VMINPD instruction.
+func (b *Builder) VMINPD(args ...Addr) { b.addOps(VMINPD, args) }
+
+// VDIVPD appends a VDIVPD instruction.
+func (b *Builder) VDIVPD(args ...Addr) { b.addOps(VDIVPD, args) }
+
+// VMAXPD appends a VMAXPD instruction.
+func (b *Builder) VMAXPD(args ...Addr) { b.addOps(VMAXPD, args) }
+
+// VANDPS appends a VANDPS instruction.
+func (b *Builder) VANDPS(args ...Addr) { b.addOps(VANDPS, args) }
+
+// VANDNPS appends a VANDNPS instruction.
+func (b *Builder) VANDNPS(args ...Addr) { b.addOps(VANDNPS, args) }
+
+// VORPS appends a VORPS instruction.
+func (b *Builder) VORPS(args ...Addr) { b.addOps(VORPS, args) }
+
+// VXORPS appends a VXORPS instruction.
+func (b *Builder) VXORPS(args ...Addr) { b.addOps(VXORPS, args) }
+
+// VFMADD132PS appends a VFMADD132PS instruction.
+func (b *Builder) VFMADD132PS(args ...Addr) { b.addOps(VFMADD132PS, args) }
+
+// VFMADD213PS appends a VFMADD213PS instruction.
+func (b *Builder) VFMADD213PS(args ...Addr) { b.addOps(VFMADD213PS, args) }
+
+// VFMADD231PS appends a VFMADD231PS instruction.
+func (b *Builder) VFMADD231PS(args ...Addr) { b.addOps(VFMADD231PS, args) }
+
+// VFMADD132PD appends a VFMADD132PD instruction.
+func (b *Builder) VFMADD132PD(args ...Addr) { b.addOps(VFMADD132PD, args) }
+
+// VFMADD213PD appends a VFMADD213PD instruction.
+func (b *Builder) VFMADD213PD(args ...Addr) { b.addOps(VFMADD213PD, args) }
+
+// VFMADD231PD appends a VFMADD231PD instruction.
+func (b *Builder) VFMADD231PD(args ...Addr) { b.addOps(VFMADD231PD, args) }
+
+// VPADDB appends a VPADDB instruction.
+func (b *Builder) VPADDB(args ...Addr) { b.addOps(VPADDB, args) }
+
+// VPADDW appends a VPADDW instruction.
+func (b *Builder) VPADDW(args ...Addr) { b.addOps(VPADDW, args) }
+
+// VPADDD appends a VPADDD instruction.
+func (b *Builder) VPADDD(args ...Addr) { b.addOps(VPADDD, args) }
+
+// VPADDQ appends a VPADDQ instruction.
+func (b *Builder) VPADDQ(args ...Addr) { b.addOps(VPADDQ, args) }
+
+// VPSUBB appends a VPSUBB instruction.
+func (b *Builder) VPSUBB(args ...Addr) { b.addOps(VPSUBB, args) }
+
+// VPSUBW appends a VPSUBW instruction.
+func (b *Builder) VPSUBW(args ...Addr) { b.addOps(VPSUBW, args) }
+
+// VPSUBD appends a VPSUBD instruction.
+func (b *Builder) VPSUBD(args ...Addr) { b.addOps(VPSUBD, args) }
+
+// VPSUBQ appends a VPSUBQ instruction.
+func (b *Builder) VPSUBQ(args ...Addr) { b.addOps(VPSUBQ, args) }
+
+// VPMULLD appends a VPMULLD instruction.
+func (b *Builder) VPMULLD(args ...Addr) { b.addOps(VPMULLD, args) }
+
+// VPAND appends a VPAND instruction.
+func (b *Builder) VPAND(args ...Addr) { b.addOps(VPAND, args) }
+
+// VPANDN appends a VPANDN instruction.
+func (b *Builder) VPANDN(args ...Addr) { b.addOps(VPANDN, args) }
+
+// VPOR appends a VPOR instruction.
+func (b *Builder) VPOR(args ...Addr) { b.addOps(VPOR, args) }
+
+// VPXOR appends a VPXOR instruction.
+func (b *Builder) VPXOR(args ...Addr) { b.addOps(VPXOR, args) }
+
+// VPCMPEQB appends a VPCMPEQB instruction.
+func (b *Builder) VPCMPEQB(args ...Addr) { b.addOps(VPCMPEQB, args) }
+
+// VPCMPEQD appends a VPCMPEQD instruction.
+func (b *Builder) VPCMPEQD(args ...Addr) { b.addOps(VPCMPEQD, args) }
+
+// VPSHUFB appends a VPSHUFB instruction.
+func (b *Builder) VPSHUFB(args ...Addr) { b.addOps(VPSHUFB, args) }
+
+// VPMINUB appends a VPMINUB instruction.
+func (b *Builder) VPMINUB(args ...Addr) { b.addOps(VPMINUB, args) }
+
+// VPMAXUB appends a VPMAXUB instruction.
+func (b *Builder) VPMAXUB(args ...Addr) { b.addOps(VPMAXUB, args) }
+
+// VPERMD appends a VPERMD instruction.
+func (b *Builder) VPERMD(args ...Addr) { b.addOps(VPERMD, args) }
+
+// VPERMPS appends a VPERMPS instruction.
+func (b *Builder) VPERMPS(args ...Addr) { b.addOps(VPERMPS, args) }
+
+// VPERMQ appends a VPERMQ instruction.
+func (b *Builder) VPERMQ(args ...Addr) { b.addOps(VPERMQ, args) }
+
+// VPERMPD appends a VPERMPD instruction.
+func (b *Builder) VPERMPD(args ...Addr) { b.addOps(VPERMPD, args) }
+
+// VPBROADCASTB appends a VPBROADCASTB instruction.
+func (b *Builder) VPBROADCASTB(src, dst Addr) { b.add(VPBROADCASTB, src, dst) }
+
+// VPBROADCASTW appends a VPBROADCASTW instruction.
+func (b *Builder) VPBROADCASTW(src, dst Addr) { b.add(VPBROADCASTW, src, dst) }
+
+// VPBROADCASTD appends a VPBROADCASTD instruction.
+func (b *Builder) VPBROADCASTD(src, dst Addr) { b.add(VPBROADCASTD, src, dst) }
+
+// VPBROADCASTQ appends a VPBROADCASTQ instruction.
+func (b *Builder) VPBROADCASTQ(src, dst Addr) { b.add(VPBROADCASTQ, src, dst) }
+
+// VBROADCASTSS appends a VBROADCASTSS instruction.
+func (b *Builder) VBROADCASTSS(src, dst Addr) { b.add(VBROADCASTSS, src, dst) }
+
+// VPMOVMSKB appends a VPMOVMSKB instruction.
+func (b *Builder) VPMOVMSKB(src, dst Addr) { b.add(VPMOVMSKB, src, dst) }
+
+// VPTEST appends a VPTEST instruction.
+func (b *Builder) VPTEST(src, dst Addr) { b.add(VPTEST, src, dst) }
+
+// VZEROUPPER appends a VZEROUPPER instruction.
+func (b *Builder) VZEROUPPER() { b.add(VZEROUPPER, Addr{}, Addr{}) }
+
+// VZEROALL appends a VZEROALL instruction.
+func (b *Builder) VZEROALL() { b.add(VZEROALL, Addr{}, Addr{}) }
+
+// VPBLENDVB appends a VPBLENDVB instruction.
+func (b *Builder) VPBLENDVB(args ...Addr) { b.addOps(VPBLENDVB, args) }
+
+// VBLENDVPS appends a VBLENDVPS instruction.
+func (b *Builder) VBLENDVPS(args ...Addr) { b.addOps(VBLENDVPS, args) }
+
+// VBLENDVPD appends a VBLENDVPD instruction.
+func (b *Builder) VBLENDVPD(args ...Addr) { b.addOps(VBLENDVPD, args) }
+
+// VINSERTI128 appends a VINSERTI128 instruction.
+func (b *Builder) VINSERTI128(args ...Addr) { b.addOps(VINSERTI128, args) }
+
+// VEXTRACTI128 appends a VEXTRACTI128 instruction.
+func (b *Builder) VEXTRACTI128(args ...Addr) { b.addOps(VEXTRACTI128, args) }
+
+// VPERM2I128 appends a VPERM2I128 instruction.
+func (b *Builder) VPERM2I128(args ...Addr) { b.addOps(VPERM2I128, args) }
+
+// VMOVDQU8 appends a VMOVDQU8 instruction.
+func (b *Builder) VMOVDQU8(src, dst Addr) { b.add(VMOVDQU8, src, dst) }
+
+// VMOVDQU16 appends a VMOVDQU16 instruction.
+func (b *Builder) VMOVDQU16(src, dst Addr) { b.add(VMOVDQU16, src, dst) }
+
+// VMOVDQU32 appends a VMOVDQU32 instruction.
+func (b *Builder) VMOVDQU32(src, dst Addr) { b.add(VMOVDQU32, src, dst) }
+
+// VMOVDQU64 appends a VMOVDQU64 instruction.
+func (b *Builder) VMOVDQU64(src, dst Addr) { b.add(VMOVDQU64, src, dst) }
+
+// VMOVDQA32 appends a VMOVDQA32 instruction.
+func (b *Builder) VMOVDQA32(src, dst Addr) { b.add(VMOVDQA32, src, dst) }
+
+// VMOVDQA64 appends a VMOVDQA64 instruction.
+func (b *Builder) VMOVDQA64(src, dst Addr) { b.add(VMOVDQA64, src, dst) }
+
+// VPANDD appends a VPANDD instruction.
+func (b *Builder) VPANDD(args ...Addr) { b.addOps(VPANDD, args) }
+
+// VPANDQ appends a VPANDQ instruction.
+func (b *Builder) VPANDQ(args ...Addr) { b.addOps(VPANDQ, args) }
+
+// VPORD appends a VPORD instruction.
+func (b *Builder) VPORD(args ...Addr) { b.addOps(VPORD, args) }
+
+// VPORQ appends a VPORQ instruction.
+func (b *Builder) VPORQ(args ...Addr) { b.addOps(VPORQ, args) }
+
+// VPXORD appends a VPXORD instruction.
+func (b *Builder) VPXORD(args ...Addr) { b.addOps(VPXORD, args) }
+
+// VPXORQ appends a VPXORQ instruction.
+func (b *Builder) VPXORQ(args ...Addr) { b.addOps(VPXORQ, args) }
+
+// VPTESTMB appends a VPTESTMB instruction.
+func (b *Builder) VPTESTMB(args ...Addr) { b.addOps(VPTESTMB, args) }
+
+// VPTESTMD appends a VPTESTMD instruction.
+func (b *Builder) VPTESTMD(args ...Addr) { b.addOps(VPTESTMD, args) }
+
+// VPTESTNMB appends a VPTESTNMB instruction.
+func (b *Builder) VPTESTNMB(args ...Addr) { b.addOps(VPTESTNMB, args) }
+
+// VPTESTNMD appends a VPTESTNMD instruction.
+func (b *Builder) VPTESTNMD(args ...Addr) { b.addOps(VPTESTNMD, args) }
+
+// VPCOMPRESSD appends a VPCOMPRESSD instruction.
+func (b *Builder) VPCOMPRESSD(src, dst Addr) { b.add(VPCOMPRESSD, src, dst) }
+
+// VPCOMPRESSQ appends a VPCOMPRESSQ instruction.
+func (b *Builder) VPCOMPRESSQ(src, dst Addr) { b.add(VPCOMPRESSQ, src, dst) }
+
+// VPTERNLOGD appends a VPTERNLOGD instruction.
+func (b *Builder) VPTERNLOGD(args ...Addr) { b.addOps(VPTERNLOGD, args) }
+
+// VPTERNLOGQ appends a VPTERNLOGQ instruction.
+func (b *Builder) VPTERNLOGQ(args ...Addr) { b.addOps(VPTERNLOGQ, args) }
+
+// VPCMPB appends a VPCMPB instruction.
+func (b *Builder) VPCMPB(args ...Addr) { b.addOps(VPCMPB, args) }
+
+// VPCMPUB appends a VPCMPUB instruction.
+func (b *Builder) VPCMPUB(args ...Addr) { b.addOps(VPCMPUB, args) }
+
+// VPCMPD appends a VPCMPD instruction.
+func (b *Builder) VPCMPD(args ...Addr) { b.addOps(VPCMPD, args) }
+
+// VPCMPUD appends a VPCMPUD instruction.
+func (b *Builder) VPCMPUD(args ...Addr) { b.addOps(VPCMPUD, args) }
+
+// KMOVW appends a KMOVW instruction.
+func (b *Builder) KMOVW(src, dst Addr) { b.add(KMOVW, src, dst) }
+
+// KMOVQ appends a KMOVQ instruction.
+func (b *Builder) KMOVQ(src, dst Addr) { b.add(KMOVQ, src, dst) }
+
+// KORTESTW appends a KORTESTW instruction.
+func (b *Builder) KORTESTW(src, dst Addr) { b.add(KORTESTW, src, dst) }
+
+// KORTESTQ appends a KORTESTQ instruction.
+func (b *Builder) KORTESTQ(src, dst Addr) { b.add(KORTESTQ, src, dst) }
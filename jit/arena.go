@@ -0,0 +1,305 @@
+// Package jit manages executable memory for code assembled by package i64.
+package jit
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+const (
+	// DefaultChunkSize is the size of the executable memory mappings
+	// an Arena packs code into, unless ChunkSize is set.
+	DefaultChunkSize = 64 << 10
+
+	// align is the alignment of each function's entry point.
+	align = 16
+
+	// int3 fills unused code bytes, so stray jumps trap.
+	int3 = 0xcc
+)
+
+// An Arena packs many small functions into shared executable pages.
+//
+// The zero value is an empty Arena ready to use. An Arena is safe for
+// concurrent use by multiple goroutines.
+//
+// Memory is mapped readable, writable and executable, so that code can
+// be added to a page while other code on it runs.
+type Arena struct {
+	// ChunkSize is the size of each memory mapping. It is rounded up
+	// to a multiple of the page size. If zero, DefaultChunkSize is used.
+	// A function larger than ChunkSize gets a mapping of its own.
+	ChunkSize int
+
+	mu     sync.Mutex
+	chunks []*chunk
+	funcs  map[*Func]bool
+	closed bool
+}
+
+// chunk is one executable memory mapping.
+type chunk struct {
+	mem   []byte
+	holes []span // free space, sorted by offset
+}
+
+type span struct {
+	off, size int
+}
+
+// A Func is code allocated in an Arena.
+type Func struct {
+	a     *Arena
+	chunk *chunk
+	off   int
+	size  int
+}
+
+// Stats describes the memory used by an Arena.
+type Stats struct {
+	Chunks int // number of memory mappings
+	Mapped int // bytes mapped
+	Used   int // bytes used by live functions, including alignment
+	Funcs  int // number of live functions
+}
+
+// Alloc copies code into the arena and returns its Func.
+func (a *Arena) Alloc(code []byte) (*Func, error) {
+	if len(code) == 0 {
+		return nil, errors.New("jit: empty function")
+	}
+	size := roundUp(len(code), align)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil, errors.New("jit: arena closed")
+	}
+	c, off := a.find(size)
+	if c == nil {
+		chunkSize := a.ChunkSize
+		if chunkSize == 0 {
+			chunkSize = DefaultChunkSize
+		}
+		if size > chunkSize {
+			chunkSize = size
+		}
+		mem, err := mmap(roundUp(chunkSize, pageSize))
+		if err != nil {
+			return nil, err
+		}
+		fill(mem)
+		c = &chunk{mem: mem, holes: []span{{0, len(mem)}}}
+		a.chunks = append(a.chunks, c)
+		off = c.take(0, size)
+	}
+	copy(c.mem[off:], code)
+	f := &Func{a: a, chunk: c, off: off, size: size}
+	if a.funcs == nil {
+		a.funcs = make(map[*Func]bool)
+	}
+	a.funcs[f] = true
+	return f, nil
+}
+
+// Load assembles p for the host CPU and allocates it in the arena.
+func (a *Arena) Load(p i64.Program) (*Func, error) {
+	code, err := p.Load()
+	if err != nil {
+		return nil, err
+	}
+	return a.Alloc(code)
+}
+
+// find returns the first hole with room for size bytes, and takes them.
+func (a *Arena) find(size int) (*chunk, int) {
+	for _, c := range a.chunks {
+		for i, h := range c.holes {
+			if h.size >= size {
+				return c, c.take(i, size)
+			}
+		}
+	}
+	return nil, 0
+}
+
+// take allocates size bytes from the start of hole i.
+func (c *chunk) take(i, size int) int {
+	h := &c.holes[i]
+	off := h.off
+	h.off += size
+	h.size -= size
+	if h.size == 0 {
+		c.holes = append(c.holes[:i], c.holes[i+1:]...)
+	}
+	return off
+}
+
+// release returns the bytes at off to the free space of c, merging
+// them with adjacent holes.
+func (c *chunk) release(off, size int) {
+	i := sort.Search(len(c.holes), func(i int) bool { return c.holes[i].off > off })
+	c.holes = append(c.holes, span{})
+	copy(c.holes[i+1:], c.holes[i:])
+	c.holes[i] = span{off, size}
+	if i+1 < len(c.holes) && off+size == c.holes[i+1].off {
+		c.holes[i].size += c.holes[i+1].size
+		c.holes = append(c.holes[:i+1], c.holes[i+2:]...)
+	}
+	if i > 0 && c.holes[i-1].off+c.holes[i-1].size == off {
+		c.holes[i-1].size += c.holes[i].size
+		c.holes = append(c.holes[:i], c.holes[i+1:]...)
+	}
+}
+
+// Entry returns the address of the first instruction of f.
+//
+// The address is valid until f is freed or the arena is compacted.
+func (f *Func) Entry() uintptr {
+	f.a.mu.Lock()
+	defer f.a.mu.Unlock()
+	if f.chunk == nil {
+		return 0
+	}
+	return addr(f.chunk.mem[f.off:])
+}
+
+// Size returns the number of bytes allocated to f.
+func (f *Func) Size() int { return f.size }
+
+// Code returns a copy of the bytes allocated to f. Code is padded with
+// INT3 instructions to the alignment of the next entry point.
+func (f *Func) Code() []byte {
+	f.a.mu.Lock()
+	defer f.a.mu.Unlock()
+	if f.chunk == nil {
+		return nil
+	}
+	return append([]byte(nil), f.chunk.mem[f.off:f.off+f.size]...)
+}
+
+// Free releases the memory of f. The code of f must not be running.
+func (f *Func) Free() {
+	a := f.a
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if f.chunk == nil {
+		return
+	}
+	fill(f.chunk.mem[f.off : f.off+f.size])
+	f.chunk.release(f.off, f.size)
+	f.chunk = nil
+	delete(a.funcs, f)
+}
+
+// Compact moves live functions toward the start of the arena, filling
+// the holes left by freed functions, and unmaps memory no longer used.
+//
+// Moving code changes the entry points of functions. Compact must not
+// be called while code in the arena is running, and addresses returned
+// by Entry before Compact must not be used after it. The code moved
+// must be position-independent, as code assembled from a Program
+// without absolute references to itself is.
+func (a *Arena) Compact() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	index := make(map[*chunk]int)
+	for i, c := range a.chunks {
+		index[c] = i
+	}
+	live := make([]*Func, 0, len(a.funcs))
+	for f := range a.funcs {
+		live = append(live, f)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		ci, cj := index[live[i].chunk], index[live[j].chunk]
+		if ci != cj {
+			return ci < cj
+		}
+		return live[i].off < live[j].off
+	})
+
+	// Each function moves to the same or an earlier position, so
+	// copying in order never overwrites code not yet moved, and a chunk
+	// is only left behind once all of its functions have moved.
+	i, pos := 0, 0
+	for _, f := range live {
+		for pos+f.size > len(a.chunks[i].mem) {
+			fill(a.chunks[i].mem[pos:])
+			a.chunks[i].holes = holesAfter(pos, len(a.chunks[i].mem))
+			i, pos = i+1, 0
+		}
+		c := a.chunks[i]
+		if c != f.chunk || pos != f.off {
+			copy(c.mem[pos:pos+f.size], f.chunk.mem[f.off:f.off+f.size])
+			f.chunk, f.off = c, pos
+		}
+		pos += f.size
+	}
+
+	// Unmap the chunks left empty.
+	var err error
+	if len(live) > 0 {
+		fill(a.chunks[i].mem[pos:])
+		a.chunks[i].holes = holesAfter(pos, len(a.chunks[i].mem))
+		i++
+	}
+	for _, c := range a.chunks[i:] {
+		if err1 := munmap(c.mem); err == nil {
+			err = err1
+		}
+	}
+	a.chunks = a.chunks[:i]
+	return err
+}
+
+func holesAfter(pos, end int) []span {
+	if pos == end {
+		return nil
+	}
+	return []span{{pos, end - pos}}
+}
+
+// Stats reports the memory used by the arena.
+func (a *Arena) Stats() Stats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := Stats{Chunks: len(a.chunks), Funcs: len(a.funcs)}
+	for _, c := range a.chunks {
+		s.Mapped += len(c.mem)
+	}
+	for f := range a.funcs {
+		s.Used += f.size
+	}
+	return s
+}
+
+// Close unmaps all of the arena's memory. Functions in the arena must
+// not be running, and must not be used after Close.
+func (a *Arena) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var err error
+	for _, c := range a.chunks {
+		if err1 := munmap(c.mem); err == nil {
+			err = err1
+		}
+	}
+	for f := range a.funcs {
+		f.chunk = nil
+	}
+	a.chunks, a.funcs, a.closed = nil, nil, true
+	return err
+}
+
+func fill(b []byte) {
+	for i := range b {
+		b[i] = int3
+	}
+}
+
+func roundUp(n, m int) int { return (n + m - 1) / m * m }
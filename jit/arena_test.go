@@ -0,0 +1,155 @@
+package jit
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func code(n int, b byte) []byte { return bytes.Repeat([]byte{b}, n) }
+
+func TestArenaPacking(t *testing.T) {
+	var a Arena
+	defer a.Close()
+	f1, err := a.Alloc(code(5, 0x90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := a.Alloc(code(20, 0x91))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f2.Entry() - f1.Entry(); got != align {
+		t.Errorf("second entry at +%d, want +%d", got, align)
+	}
+	if got, want := f1.Code(), append(code(5, 0x90), code(11, int3)...); !bytes.Equal(got, want) {
+		t.Errorf("f1.Code()=%x, want %x", got, want)
+	}
+	if s := a.Stats(); s.Chunks != 1 || s.Funcs != 2 || s.Used != 48 || s.Mapped != DefaultChunkSize {
+		t.Errorf("Stats()=%+v", s)
+	}
+
+	// A freed hole is reused.
+	entry := f1.Entry()
+	f1.Free()
+	if f1.Entry() != 0 || f1.Code() != nil {
+		t.Error("freed function still has code")
+	}
+	f3, err := a.Alloc(code(16, 0x92))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f3.Entry() != entry {
+		t.Errorf("f3 at %#x, want reused hole at %#x", f3.Entry(), entry)
+	}
+
+	// A function larger than a chunk gets a chunk of its own.
+	big, err := a.Alloc(code(DefaultChunkSize+1, 0x93))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := a.Stats(); s.Chunks != 2 || big.Size() != DefaultChunkSize+align {
+		t.Errorf("Stats()=%+v, big.Size()=%d", s, big.Size())
+	}
+}
+
+func TestArenaCompact(t *testing.T) {
+	a := Arena{ChunkSize: 4096}
+	defer a.Close()
+	var funcs []*Func
+	for i := 0; i < 600; i++ {
+		f, err := a.Alloc(code(1+i%40, byte(i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		funcs = append(funcs, f)
+	}
+	before := a.Stats()
+	var live []*Func
+	var want [][]byte
+	for i, f := range funcs {
+		if i%3 != 0 {
+			f.Free()
+			continue
+		}
+		live = append(live, f)
+		want = append(want, f.Code())
+	}
+	if err := a.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	after := a.Stats()
+	if after.Chunks >= before.Chunks || after.Funcs != len(live) {
+		t.Errorf("Compact: before %+v, after %+v", before, after)
+	}
+	for i, f := range live {
+		if got := f.Code(); !bytes.Equal(got, want[i]) {
+			t.Errorf("func %d moved to %#x with code %x, want %x", i, f.Entry(), got, want[i])
+		}
+	}
+
+	// Freeing everything and compacting unmaps all memory.
+	for _, f := range live {
+		f.Free()
+	}
+	if err := a.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if s := a.Stats(); s != (Stats{}) {
+		t.Errorf("empty arena Stats()=%+v", s)
+	}
+}
+
+func TestArenaConcurrent(t *testing.T) {
+	var a Arena
+	defer a.Close()
+	var wg sync.WaitGroup
+	errc := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				c := code(1+(g*7+i)%50, byte(g))
+				f, err := a.Alloc(c)
+				if err != nil {
+					errc <- err
+					return
+				}
+				if got := f.Code(); !bytes.Equal(got[:len(c)], c) {
+					errc <- fmt.Errorf("goroutine %d: code %x, want %x", g, got, c)
+					return
+				}
+				if i%2 == 0 {
+					f.Free()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		t.Error(err)
+	}
+	if s := a.Stats(); s.Funcs != 8*100 {
+		t.Errorf("Stats().Funcs=%d, want %d", s.Funcs, 8*100)
+	}
+}
+
+func TestArenaClosed(t *testing.T) {
+	var a Arena
+	f, err := a.Alloc(code(1, 0xc3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if f.Entry() != 0 {
+		t.Error("function has an entry after Close")
+	}
+	if _, err := a.Alloc(code(1, 0xc3)); err == nil {
+		t.Error("Alloc after Close succeeded")
+	}
+}
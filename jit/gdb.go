@@ -0,0 +1,364 @@
+package jit
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+// DebugELF returns an ELF object describing the program p loaded at
+// base, in the form the GDB JIT interface reads.
+//
+// The object has a function symbol for each of Symbols(name, p), and a
+// DWARF line table mapping each instruction of p to its source position,
+// set by POS instructions. An instruction without a position is mapped
+// to a line of the file name+".s": instruction i is on line i+1. The
+// code itself is not included, as the debugger reads it from memory.
+func DebugELF(name string, base uintptr, p i64.Program) ([]byte, error) {
+	syms, err := Symbols(name, p)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := debugRows(name, p)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	if n := len(syms); n > 0 {
+		size = syms[n-1].Offset + syms[n-1].Size
+	}
+	return writeELF(name, uint64(base), size, syms, rows), nil
+}
+
+// A debugRow maps the instruction at offset to a source line.
+type debugRow struct {
+	offset int
+	file   string
+	line   int
+}
+
+func debugRows(name string, p i64.Program) ([]debugRow, error) {
+	offsets, err := p.Offsets()
+	if err != nil {
+		return nil, err
+	}
+	positions := p.Positions()
+	var rows []debugRow
+	for i, in := range p {
+		if in.Op == i64.LABEL || in.Op == i64.POS {
+			continue
+		}
+		row := debugRow{offset: offsets[i], file: name + ".s", line: i + 1}
+		if pos := positions[i]; pos.IsValid() {
+			row.file, row.line = pos.File, pos.Line
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// ELF constants, from the System V ABI and the DWARF 2 standard.
+const (
+	elfHeaderSize  = 64
+	elfSectionSize = 64
+	elfSymSize     = 24
+
+	etRel = 1
+
+	shtProgbits = 1
+	shtSymtab   = 2
+	shtStrtab   = 3
+	shtNobits   = 8
+
+	shfAlloc     = 0x2
+	shfExecinstr = 0x4
+
+	stbLocal  = 0
+	stbGlobal = 1
+	sttFunc   = 2
+	sttFile   = 4
+	shnAbs    = 0xfff1
+
+	dwTagCompileUnit = 0x11
+	dwTagSubprogram  = 0x2e
+
+	dwAtName     = 0x03
+	dwAtStmtList = 0x10
+	dwAtLowPC    = 0x11
+	dwAtHighPC   = 0x12
+	dwAtLanguage = 0x13
+	dwAtProducer = 0x25
+	dwAtExternal = 0x3f
+
+	dwFormAddr   = 0x01
+	dwFormData2  = 0x05
+	dwFormData4  = 0x06
+	dwFormString = 0x08
+	dwFormFlag   = 0x0c
+
+	dwLangMipsAssembler = 0x8001
+
+	dwLnsCopy        = 0x01
+	dwLnsAdvancePC   = 0x02
+	dwLnsAdvanceLine = 0x03
+	dwLnsSetFile     = 0x04
+	dwLneEndSeq      = 0x01
+	dwLneSetAddress  = 0x02
+)
+
+type elfSection struct {
+	name      string
+	typ       uint32
+	flags     uint64
+	addr      uint64
+	size      uint64 // of a SHT_NOBITS section
+	link      uint32
+	info      uint32
+	align     uint64
+	entsize   uint64
+	data      []byte
+	nameIndex uint32
+}
+
+// writeELF writes a relocatable ELF object whose .text section of size
+// bytes is at base. It is relocatable so that symbol values are
+// offsets in .text, but as .text has its load address the DWARF
+// addresses are absolute and need no relocations.
+func writeELF(name string, base uint64, size int, syms []i64.Symbol, rows []debugRow) []byte {
+	le := binary.LittleEndian
+	const textIndex = 1
+
+	strtab := newStrtab()
+	var symtab []byte
+	addSym := func(name string, info byte, shndx uint16, value, size uint64) {
+		var s [elfSymSize]byte
+		le.PutUint32(s[0:], strtab.add(name))
+		s[4] = info
+		le.PutUint16(s[6:], shndx)
+		le.PutUint64(s[8:], value)
+		le.PutUint64(s[16:], size)
+		symtab = append(symtab, s[:]...)
+	}
+	addSym("", 0, 0, 0, 0)
+	addSym(name+".s", stbLocal<<4|sttFile, shnAbs, 0, 0)
+	// Local symbols come first, so the entry point, the only global
+	// symbol, is last.
+	var entry *i64.Symbol
+	for i := range syms {
+		if syms[i].Offset == 0 && syms[i].Name == name {
+			entry = &syms[i]
+			continue
+		}
+		addSym(syms[i].Name, stbLocal<<4|sttFunc, textIndex, uint64(syms[i].Offset), uint64(syms[i].Size))
+	}
+	firstGlobal := uint32(len(symtab) / elfSymSize)
+	if entry != nil {
+		addSym(entry.Name, stbGlobal<<4|sttFunc, textIndex, 0, uint64(entry.Size))
+	}
+
+	abbrev, info, line := writeDWARF(name, base, size, rows)
+	sections := []*elfSection{
+		{},
+		{name: ".text", typ: shtNobits, flags: shfAlloc | shfExecinstr, addr: base, size: uint64(size), align: align},
+		{name: ".symtab", typ: shtSymtab, link: 3, info: firstGlobal, align: 8, entsize: elfSymSize, data: symtab},
+		{name: ".strtab", typ: shtStrtab, align: 1}, // data set below
+		{name: ".debug_abbrev", typ: shtProgbits, align: 1, data: abbrev},
+		{name: ".debug_info", typ: shtProgbits, align: 1, data: info},
+		{name: ".debug_line", typ: shtProgbits, align: 1, data: line},
+		{name: ".shstrtab", typ: shtStrtab, align: 1},
+	}
+	sections[3].data = strtab.buf.Bytes()
+	shstrtab := newStrtab()
+	for _, s := range sections[1:] {
+		s.nameIndex = shstrtab.add(s.name)
+	}
+	shstrndx := len(sections) - 1
+	sections[shstrndx].data = shstrtab.buf.Bytes()
+
+	buf := make([]byte, elfHeaderSize)
+	offsets := make([]int, len(sections))
+	for i, s := range sections {
+		if s.data == nil {
+			continue
+		}
+		for len(buf)%8 != 0 {
+			buf = append(buf, 0)
+		}
+		offsets[i] = len(buf)
+		buf = append(buf, s.data...)
+	}
+	for len(buf)%8 != 0 {
+		buf = append(buf, 0)
+	}
+	shoff := len(buf)
+	for i, s := range sections {
+		var h [elfSectionSize]byte
+		size := s.size
+		if s.typ != shtNobits {
+			size = uint64(len(s.data))
+		}
+		le.PutUint32(h[0:], s.nameIndex)
+		le.PutUint32(h[4:], s.typ)
+		le.PutUint64(h[8:], s.flags)
+		le.PutUint64(h[16:], s.addr)
+		le.PutUint64(h[24:], uint64(offsets[i]))
+		le.PutUint64(h[32:], size)
+		le.PutUint32(h[40:], s.link)
+		le.PutUint32(h[44:], s.info)
+		le.PutUint64(h[48:], s.align)
+		le.PutUint64(h[56:], s.entsize)
+		buf = append(buf, h[:]...)
+	}
+
+	h := buf[:elfHeaderSize]
+	copy(h, "\x7fELF")
+	h[4] = 2 // ELFCLASS64
+	h[5] = 1 // ELFDATA2LSB
+	h[6] = 1 // EV_CURRENT
+	le.PutUint16(h[16:], etRel)
+	le.PutUint16(h[18:], emX86_64)
+	le.PutUint32(h[20:], 1)
+	le.PutUint64(h[40:], uint64(shoff))
+	le.PutUint16(h[52:], elfHeaderSize)
+	le.PutUint16(h[58:], elfSectionSize)
+	le.PutUint16(h[60:], uint16(len(sections)))
+	le.PutUint16(h[62:], uint16(shstrndx))
+	return buf
+}
+
+// writeDWARF returns the .debug_abbrev, .debug_info and .debug_line
+// sections describing one compile unit with one subprogram.
+func writeDWARF(name string, base uint64, size int, rows []debugRow) (abbrev, info, line []byte) {
+	le := binary.LittleEndian
+	low, high := base, base+uint64(size)
+
+	abbrev = []byte{
+		1, dwTagCompileUnit, 1, // has children
+		dwAtName, dwFormString,
+		dwAtProducer, dwFormString,
+		dwAtLanguage, dwFormData2,
+		dwAtLowPC, dwFormAddr,
+		dwAtHighPC, dwFormAddr,
+		dwAtStmtList, dwFormData4,
+		0, 0,
+		2, dwTagSubprogram, 0, // no children
+		dwAtName, dwFormString,
+		dwAtLowPC, dwFormAddr,
+		dwAtHighPC, dwFormAddr,
+		dwAtExternal, dwFormFlag,
+		0, 0,
+		0,
+	}
+
+	var files []string
+	fileIndex := make(map[string]int)
+	for _, r := range rows {
+		if fileIndex[r.file] == 0 {
+			files = append(files, r.file)
+			fileIndex[r.file] = len(files)
+		}
+	}
+	if len(files) == 0 {
+		files = append(files, name+".s")
+	}
+
+	info = make([]byte, 11)
+	le.PutUint16(info[4:], 2) // version
+	info[10] = 8              // address size
+	info = append(info, 1)
+	info = append(info, files[0]+"\x00github.com/crawshaw/asm/jit\x00"...)
+	info = le.AppendUint16(info, dwLangMipsAssembler)
+	info = le.AppendUint64(info, low)
+	info = le.AppendUint64(info, high)
+	info = le.AppendUint32(info, 0) // offset in .debug_line
+	info = append(info, 2)
+	info = append(info, name+"\x00"...)
+	info = le.AppendUint64(info, low)
+	info = le.AppendUint64(info, high)
+	info = append(info, 1, 0) // external, end of children
+	le.PutUint32(info[0:], uint32(len(info)-4))
+
+	line = make([]byte, 10)
+	le.PutUint16(line[4:], 2) // version
+	line = append(line,
+		1,                         // minimum_instruction_length
+		1,                         // default_is_stmt
+		0xfb,                      // line_base -5
+		14,                        // line_range
+		10,                        // opcode_base
+		0, 1, 1, 1, 1, 0, 0, 0, 1, // standard_opcode_lengths
+		0, // no include_directories
+	)
+	for _, f := range files {
+		line = append(line, f...)
+		line = append(line, 0, 0, 0, 0) // NUL, directory, mtime, length
+	}
+	line = append(line, 0)
+	le.PutUint32(line[6:], uint32(len(line)-10)) // header_length
+
+	line = append(line, 0, 9, dwLneSetAddress)
+	line = le.AppendUint64(line, low)
+	addr, ln, file := 0, 1, 1
+	for _, r := range rows {
+		if f := fileIndex[r.file]; f != file {
+			line = append(line, dwLnsSetFile)
+			line = binary.AppendUvarint(line, uint64(f))
+			file = f
+		}
+		if r.offset != addr {
+			line = append(line, dwLnsAdvancePC)
+			line = binary.AppendUvarint(line, uint64(r.offset-addr))
+			addr = r.offset
+		}
+		if r.line != ln {
+			line = append(line, dwLnsAdvanceLine)
+			line = appendSleb(line, int64(r.line-ln))
+			ln = r.line
+		}
+		line = append(line, dwLnsCopy)
+	}
+	if size != addr {
+		line = append(line, dwLnsAdvancePC)
+		line = binary.AppendUvarint(line, uint64(size-addr))
+	}
+	line = append(line, 0, 1, dwLneEndSeq)
+	le.PutUint32(line[0:], uint32(len(line)-4))
+	return abbrev, info, line
+}
+
+// appendSleb appends the signed LEB128 encoding of v to b.
+// (The unsigned encoding is binary.AppendUvarint.)
+func appendSleb(b []byte, v int64) []byte {
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if v == 0 && c&0x40 == 0 || v == -1 && c&0x40 != 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+// strtab is an ELF string table.
+type strtab struct {
+	buf   bytes.Buffer
+	index map[string]uint32
+}
+
+func newStrtab() *strtab {
+	t := &strtab{index: map[string]uint32{"": 0}}
+	t.buf.WriteByte(0)
+	return t
+}
+
+func (t *strtab) add(s string) uint32 {
+	if i, ok := t.index[s]; ok {
+		return i
+	}
+	i := uint32(t.buf.Len())
+	t.buf.WriteString(s)
+	t.buf.WriteByte(0)
+	t.index[s] = i
+	return i
+}
@@ -0,0 +1,114 @@
+//go:build cgo
+
+package jit
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+// The GDB JIT interface, described in the "JIT Interface" chapter of
+// the GDB manual. GDB sets a breakpoint in __jit_debug_register_code
+// and, when it is hit, reads the entry __jit_debug_descriptor points to.
+
+typedef enum {
+	JIT_NOACTION = 0,
+	JIT_REGISTER_FN,
+	JIT_UNREGISTER_FN
+} jit_actions_t;
+
+struct jit_code_entry {
+	struct jit_code_entry *next_entry;
+	struct jit_code_entry *prev_entry;
+	const char *symfile_addr;
+	uint64_t symfile_size;
+};
+
+struct jit_descriptor {
+	uint32_t version;
+	uint32_t action_flag;
+	struct jit_code_entry *relevant_entry;
+	struct jit_code_entry *first_entry;
+};
+
+struct jit_descriptor __jit_debug_descriptor = { 1, 0, 0, 0 };
+
+void __attribute__((noinline)) __jit_debug_register_code(void) {
+	__asm__ __volatile__("");
+}
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// gdbMu guards __jit_debug_descriptor.
+var gdbMu sync.Mutex
+
+// A GDBEntry is an object registered with the GDB JIT interface.
+type GDBEntry struct {
+	e *C.struct_jit_code_entry
+}
+
+// RegisterGDB registers the ELF object obj, such as one returned by
+// DebugELF, with the GDB JIT interface. A debugger attached to the
+// process, now or later, loads its symbols and line table.
+func RegisterGDB(obj []byte) (*GDBEntry, error) {
+	e := (*C.struct_jit_code_entry)(C.calloc(1, C.sizeof_struct_jit_code_entry))
+	e.symfile_addr = (*C.char)(C.CBytes(obj))
+	e.symfile_size = C.uint64_t(len(obj))
+
+	gdbMu.Lock()
+	defer gdbMu.Unlock()
+	d := &C.__jit_debug_descriptor
+	e.next_entry = d.first_entry
+	if d.first_entry != nil {
+		d.first_entry.prev_entry = e
+	}
+	d.first_entry = e
+	d.relevant_entry = e
+	d.action_flag = C.JIT_REGISTER_FN
+	C.__jit_debug_register_code()
+	d.action_flag = C.JIT_NOACTION
+	return &GDBEntry{e}, nil
+}
+
+// Unregister removes the object from the GDB JIT interface. It should
+// be called before the code it describes is freed.
+func (g *GDBEntry) Unregister() {
+	gdbMu.Lock()
+	defer gdbMu.Unlock()
+	e := g.e
+	if e == nil {
+		return
+	}
+	g.e = nil
+	d := &C.__jit_debug_descriptor
+	if e.prev_entry != nil {
+		e.prev_entry.next_entry = e.next_entry
+	} else {
+		d.first_entry = e.next_entry
+	}
+	if e.next_entry != nil {
+		e.next_entry.prev_entry = e.prev_entry
+	}
+	d.relevant_entry = e
+	d.action_flag = C.JIT_UNREGISTER_FN
+	C.__jit_debug_register_code()
+	d.action_flag = C.JIT_NOACTION
+	d.relevant_entry = nil
+	C.free(unsafe.Pointer(e.symfile_addr))
+	C.free(unsafe.Pointer(e))
+}
+
+// gdbEntries returns the objects registered with the GDB JIT interface.
+func gdbEntries() [][]byte {
+	gdbMu.Lock()
+	defer gdbMu.Unlock()
+	var objs [][]byte
+	for e := C.__jit_debug_descriptor.first_entry; e != nil; e = e.next_entry {
+		objs = append(objs, C.GoBytes(unsafe.Pointer(e.symfile_addr), C.int(e.symfile_size)))
+	}
+	return objs
+}
@@ -0,0 +1,19 @@
+//go:build !cgo
+
+package jit
+
+import "errors"
+
+// A GDBEntry is an object registered with the GDB JIT interface.
+type GDBEntry struct{}
+
+// RegisterGDB registers the ELF object obj with the GDB JIT interface,
+// which needs cgo to define the symbols GDB looks for.
+func RegisterGDB(obj []byte) (*GDBEntry, error) {
+	return nil, errors.New("jit: GDB JIT interface requires cgo")
+}
+
+// Unregister removes the object from the GDB JIT interface.
+func (g *GDBEntry) Unregister() {}
+
+func gdbEntries() [][]byte { return nil }
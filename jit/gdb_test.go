@@ -0,0 +1,207 @@
+package jit
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+func TestDebugELF(t *testing.T) {
+	const base = 0x7f0000001000
+	obj, err := DebugELF("count", base, loopProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Machine != elf.EM_X86_64 || f.Type != elf.ET_REL {
+		t.Errorf("machine %v, type %v", f.Machine, f.Type)
+	}
+	text := f.Section(".text")
+	if text == nil || text.Addr != base || text.Size != 14 {
+		t.Fatalf(".text=%+v", text)
+	}
+
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type sym struct {
+		name        string
+		bind        elf.SymBind
+		value, size uint64
+	}
+	var got []sym
+	for _, s := range syms {
+		if elf.ST_TYPE(s.Info) == elf.STT_FUNC {
+			got = append(got, sym{s.Name, elf.ST_BIND(s.Info), s.Value, s.Size})
+		}
+	}
+	want := []sym{
+		{"count.loop", elf.STB_LOCAL, 7, 7},
+		{"count", elf.STB_GLOBAL, 0, 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("symbols=%+v, want %+v", got, want)
+	}
+
+	d, err := f.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.Reader()
+	cu, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cu.Tag != dwarf.TagCompileUnit || cu.Val(dwarf.AttrLowpc) != uint64(base) || cu.Val(dwarf.AttrHighpc) != uint64(base+14) {
+		t.Errorf("compile unit %+v", cu)
+	}
+	sub, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Tag != dwarf.TagSubprogram || sub.Val(dwarf.AttrName) != "count" {
+		t.Errorf("subprogram %+v", sub)
+	}
+
+	lr, err := d.LineReader(cu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type row struct {
+		addr uint64
+		file string
+		line int
+		end  bool
+	}
+	var rows []row
+	for {
+		var e dwarf.LineEntry
+		if err := lr.Next(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row{e.Address - base, e.File.Name, e.Line, e.EndSequence})
+	}
+	wantRows := []row{
+		{0, "count.s", 1, false},
+		{7, "count.s", 3, false},
+		{11, "count.s", 4, false},
+		{13, "count.s", 5, false},
+		{14, "count.s", 5, true},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("line table=%v, want %v", rows, wantRows)
+	}
+}
+
+func TestDebugELFPositions(t *testing.T) {
+	p := i64.Program{
+		i64.Pos{File: "count.go", Line: 10}.Instruction(),
+		{i64.MOVQ, i64.Imm(uint32(10)), i64.CX.Addr()},
+		{Op: i64.LABEL, From: i64.LabelAddr("loop")},
+		i64.Pos{File: "loop.s", Line: 2}.Instruction(),
+		{i64.SUBQ, i64.Imm(1), i64.CX.Addr()},
+		{Op: i64.JNE, To: i64.LabelAddr("loop")},
+		i64.Pos{File: "count.go", Line: 8}.Instruction(),
+		{Op: i64.RET},
+	}
+	obj, err := DebugELF("count", 0, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.NewFile(bytes.NewReader(obj))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := f.DWARF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cu, err := d.Reader().Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr, err := d.LineReader(cu)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		var e dwarf.LineEntry
+		if err := lr.Next(&e); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d %s:%d", e.Address, e.File.Name, e.Line))
+	}
+	want := []string{"0 count.go:10", "7 loop.s:2", "11 loop.s:2", "13 count.go:8", "14 count.go:8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("line table=%v, want %v", got, want)
+	}
+}
+
+func TestRegisterGDB(t *testing.T) {
+	obj, err := DebugELF("count", 0x1000, loopProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1, err := RegisterGDB(obj)
+	if err != nil {
+		t.Skip(err)
+	}
+	obj2 := append([]byte(nil), obj...)
+	obj2[len(obj2)-1] ^= 1
+	e2, err := RegisterGDB(obj2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gdbEntries(); !reflect.DeepEqual(got, [][]byte{obj2, obj}) {
+		t.Errorf("after register, %d entries", len(got))
+	}
+	e2.Unregister()
+	if got := gdbEntries(); !reflect.DeepEqual(got, [][]byte{obj}) {
+		t.Errorf("after unregister, %d entries", len(got))
+	}
+	e1.Unregister()
+	e1.Unregister()
+	if got := gdbEntries(); len(got) != 0 {
+		t.Errorf("after unregister, %d entries", len(got))
+	}
+
+	// GDB finds the interface by symbol name in the executable.
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := elf.Open(exe)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer f.Close()
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Skip(err)
+	}
+	found := make(map[string]bool)
+	for _, s := range syms {
+		found[s.Name] = true
+	}
+	for _, name := range []string{"__jit_debug_register_code", "__jit_debug_descriptor"} {
+		if !found[name] {
+			t.Errorf("executable has no symbol %s", name)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+package jit
+
+import (
+	"errors"
+	"unsafe"
+)
+
+var pageSize = 4096
+
+func mmap(size int) ([]byte, error) {
+	return nil, errors.New("jit: executable memory is not supported on this system")
+}
+
+func munmap(b []byte) error { return nil }
+
+func addr(b []byte) uintptr { return uintptr(unsafe.Pointer(&b[0])) }
@@ -0,0 +1,18 @@
+//go:build unix
+
+package jit
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var pageSize = syscall.Getpagesize()
+
+func mmap(size int) ([]byte, error) {
+	return syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE|syscall.PROT_EXEC, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+}
+
+func munmap(b []byte) error { return syscall.Munmap(b) }
+
+func addr(b []byte) uintptr { return uintptr(unsafe.Pointer(&b[0])) }
@@ -0,0 +1,92 @@
+package jit
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+// ErrNotAtomic is returned when a field cannot be patched atomically.
+var ErrNotAtomic = errors.New("jit: field cannot be patched atomically")
+
+// Patch rewrites the field at pt in the code of f with v. The patch
+// point comes from the PatchPoints of the Program f was loaded from.
+//
+// The field is written with a single atomic store, so code running
+// concurrently sees either the old or the new value. That is only
+// possible when the field lies within one aligned 8-byte word. Patch
+// returns ErrNotAtomic, leaving the code unchanged, for a field that
+// crosses a word boundary.
+//
+// The value must fit in the field. An immediate may be given signed or
+// unsigned, a displacement or relative target must be signed.
+func (f *Func) Patch(pt i64.PatchPoint, v int64) error {
+	if !fits(v, pt.Width, pt.Kind == i64.PatchImm) {
+		return fmt.Errorf("jit: %#x does not fit in %d-byte %v field", v, pt.Width, pt.Kind)
+	}
+	f.a.mu.Lock()
+	defer f.a.mu.Unlock()
+	return f.patch(pt, uint64(v))
+}
+
+// PatchTarget rewrites the PatchRel field at pt, the target of a jump or
+// call, to the absolute address target. It is atomic, as Patch.
+func (f *Func) PatchTarget(pt i64.PatchPoint, target uintptr) error {
+	if pt.Kind != i64.PatchRel {
+		return fmt.Errorf("jit: PatchTarget of %v field", pt.Kind)
+	}
+	f.a.mu.Lock()
+	defer f.a.mu.Unlock()
+	if f.chunk == nil {
+		return errors.New("jit: patch of freed function")
+	}
+	end := addr(f.chunk.mem[f.off:]) + uintptr(pt.Offset+pt.Width)
+	rel := int64(target - end)
+	if !fits(rel, pt.Width, false) {
+		return fmt.Errorf("jit: target %#x out of range of %d-byte field", target, pt.Width)
+	}
+	return f.patch(pt, uint64(rel))
+}
+
+func (f *Func) patch(pt i64.PatchPoint, v uint64) error {
+	if f.chunk == nil {
+		return errors.New("jit: patch of freed function")
+	}
+	if pt.Offset < 0 || pt.Width <= 0 || pt.Offset+pt.Width > f.size {
+		return fmt.Errorf("jit: patch point %+v outside function of %d bytes", pt, f.size)
+	}
+	off := f.off + pt.Offset
+	base := off &^ 7
+	if off+pt.Width > base+8 {
+		return ErrNotAtomic
+	}
+	// Chunks are page aligned, so base is an aligned word.
+	word := (*uint64)(unsafe.Pointer(&f.chunk.mem[base]))
+	shift := uint(off-base) * 8
+	mask := ^uint64(0)
+	if pt.Width < 8 {
+		mask = (1<<(uint(pt.Width)*8) - 1) << shift
+	}
+	for {
+		old := atomic.LoadUint64(word)
+		if atomic.CompareAndSwapUint64(word, old, old&^mask|v<<shift&mask) {
+			return nil
+		}
+	}
+}
+
+// fits reports whether v fits in a field of width bytes, as a signed
+// integer, or also as an unsigned one.
+func fits(v int64, width int, unsigned bool) bool {
+	if width >= 8 {
+		return true
+	}
+	bits := uint(width) * 8
+	if v >= -1<<(bits-1) && v < 1<<(bits-1) {
+		return true
+	}
+	return unsigned && v >= 0 && v < 1<<bits
+}
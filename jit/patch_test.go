@@ -0,0 +1,42 @@
+package jit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+func TestPatch(t *testing.T) {
+	var a Arena
+	defer a.Close()
+	f, err := a.Alloc(make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		pt  i64.PatchPoint
+		v   int64
+		err bool
+	}{
+		{i64.PatchPoint{Kind: i64.PatchImm, Offset: 0, Width: 8}, 0x0807060504030201, false},
+		{i64.PatchPoint{Kind: i64.PatchImm, Offset: 9, Width: 1}, 0xff, false},
+		{i64.PatchPoint{Kind: i64.PatchDisp, Offset: 10, Width: 1}, -2, false},
+		{i64.PatchPoint{Kind: i64.PatchImm, Offset: 12, Width: 4}, 0xfffffffc, false},
+		{i64.PatchPoint{Kind: i64.PatchDisp, Offset: 12, Width: 4}, 0xfffffffc, true},
+		{i64.PatchPoint{Kind: i64.PatchImm, Offset: 6, Width: 4}, 1, true},
+		{i64.PatchPoint{Kind: i64.PatchImm, Offset: 14, Width: 4}, 1, true},
+		{i64.PatchPoint{Kind: i64.PatchImm, Offset: 9, Width: 1}, 0x100, true},
+	} {
+		if err := f.Patch(test.pt, test.v); (err != nil) != test.err {
+			t.Errorf("Patch(%+v, %#x): %v", test.pt, test.v, err)
+		}
+	}
+	want := []byte{1, 2, 3, 4, 5, 6, 7, 8, 0, 0xff, 0xfe, 0, 0xfc, 0xff, 0xff, 0xff}
+	if got := f.Code(); !bytes.Equal(got, want) {
+		t.Errorf("Code()=%x, want %x", got, want)
+	}
+	if err := f.Patch(i64.PatchPoint{Offset: 6, Width: 4}, 1); err != ErrNotAtomic {
+		t.Errorf("Patch across a word: %v, want ErrNotAtomic", err)
+	}
+}
@@ -0,0 +1,209 @@
+package jit
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+// Symbols returns the symbols of the assembled program p, split at its
+// labels so that profiles attribute samples to them. The code before
+// the first label is named name, and the code from each label to the
+// next is named name.label. Empty regions are omitted.
+func Symbols(name string, p i64.Program) ([]i64.Symbol, error) {
+	code, err := p.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	labels, err := p.Labels()
+	if err != nil {
+		return nil, err
+	}
+	syms := []i64.Symbol{{Name: name}}
+	for label, off := range labels {
+		syms = append(syms, i64.Symbol{Name: name + "." + label, Offset: off})
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		if syms[i].Offset != syms[j].Offset {
+			return syms[i].Offset < syms[j].Offset
+		}
+		return syms[i].Name < syms[j].Name
+	})
+	var out []i64.Symbol
+	for i, s := range syms {
+		end := len(code)
+		if i+1 < len(syms) {
+			end = syms[i+1].Offset
+		}
+		if s.Size = end - s.Offset; s.Size > 0 {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// A PerfMap writes a perf map, the text file /tmp/perf-<pid>.map that
+// perf reads to name samples in code without symbols.
+// It is safe for concurrent use.
+type PerfMap struct {
+	mu sync.Mutex
+	w  io.Writer
+	c  io.Closer
+}
+
+// NewPerfMap returns a PerfMap writing to w.
+func NewPerfMap(w io.Writer) *PerfMap {
+	return &PerfMap{w: w}
+}
+
+// OpenPerfMap opens the perf map of the current process for appending.
+func OpenPerfMap() (*PerfMap, error) {
+	name := fmt.Sprintf("/tmp/perf-%d.map", os.Getpid())
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &PerfMap{w: f, c: f}, nil
+}
+
+// Add writes an entry for each symbol, loaded at base.
+func (m *PerfMap) Add(base uintptr, syms []i64.Symbol) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, s := range syms {
+		if _, err := fmt.Fprintf(m.w, "%x %x %s\n", base+uintptr(s.Offset), s.Size, s.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the file opened by OpenPerfMap.
+func (m *PerfMap) Close() error {
+	if m.c == nil {
+		return nil
+	}
+	return m.c.Close()
+}
+
+// The jitdump format is described in tools/perf/Documentation/jitdump-specification.txt
+// in the Linux source.
+const (
+	jitdumpMagic   = 0x4a695444 // "JiTD"
+	jitdumpVersion = 1
+	emX86_64       = 62
+
+	jitCodeLoad  = 0
+	jitCodeClose = 3
+
+	jitHeaderSize = 40
+	recHeaderSize = 16
+)
+
+// A JITDump writes a jitdump file, which perf inject --jit uses to
+// attribute samples to JIT compiled code, including the code bytes.
+// It is safe for concurrent use.
+type JITDump struct {
+	mu    sync.Mutex
+	w     *bufio.Writer
+	c     io.Closer
+	index uint64
+	done  func() error // unmaps the marker of OpenJITDump
+}
+
+// NewJITDump returns a JITDump writing to w. It writes the file header.
+func NewJITDump(w io.Writer) (*JITDump, error) {
+	d := &JITDump{w: bufio.NewWriter(w)}
+	var h [jitHeaderSize]byte
+	le := binary.LittleEndian
+	le.PutUint32(h[0:], jitdumpMagic)
+	le.PutUint32(h[4:], jitdumpVersion)
+	le.PutUint32(h[8:], jitHeaderSize)
+	le.PutUint32(h[12:], emX86_64)
+	le.PutUint32(h[20:], uint32(os.Getpid()))
+	le.PutUint64(h[24:], timestamp())
+	d.w.Write(h[:])
+	return d, d.w.Flush()
+}
+
+// OpenJITDump creates the file jit-<pid>.dump in dir and maps it into
+// memory, which is how perf record notices the file.
+func OpenJITDump(dir string) (*JITDump, error) {
+	name := filepath.Join(dir, fmt.Sprintf("jit-%d.dump", os.Getpid()))
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	d, err := NewJITDump(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	d.c = f
+	if d.done, err = markJITDump(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Add writes a code load record for each symbol, with its code taken
+// from code, loaded at base.
+func (d *JITDump) Add(base uintptr, code []byte, syms []i64.Symbol) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	le := binary.LittleEndian
+	for _, s := range syms {
+		if s.Offset < 0 || s.Offset+s.Size > len(code) {
+			return fmt.Errorf("jit: symbol %s outside code", s.Name)
+		}
+		addr := uint64(base) + uint64(s.Offset)
+		var r [recHeaderSize + 40]byte
+		size := len(r) + len(s.Name) + 1 + s.Size
+		le.PutUint32(r[0:], jitCodeLoad)
+		le.PutUint32(r[4:], uint32(size))
+		le.PutUint64(r[8:], timestamp())
+		le.PutUint32(r[16:], uint32(os.Getpid()))
+		le.PutUint32(r[20:], uint32(gettid()))
+		le.PutUint64(r[24:], addr) // vma
+		le.PutUint64(r[32:], addr) // code_addr
+		le.PutUint64(r[40:], uint64(s.Size))
+		le.PutUint64(r[48:], d.index)
+		d.index++
+		d.w.Write(r[:])
+		d.w.WriteString(s.Name)
+		d.w.WriteByte(0)
+		d.w.Write(code[s.Offset : s.Offset+s.Size])
+	}
+	return d.w.Flush()
+}
+
+// Close writes a close record, and closes the file opened by OpenJITDump.
+func (d *JITDump) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var r [recHeaderSize]byte
+	binary.LittleEndian.PutUint32(r[0:], jitCodeClose)
+	binary.LittleEndian.PutUint32(r[4:], recHeaderSize)
+	binary.LittleEndian.PutUint64(r[8:], timestamp())
+	d.w.Write(r[:])
+	err := d.w.Flush()
+	if d.done != nil {
+		if err1 := d.done(); err == nil {
+			err = err1
+		}
+	}
+	if d.c != nil {
+		if err1 := d.c.Close(); err == nil {
+			err = err1
+		}
+	}
+	return err
+}
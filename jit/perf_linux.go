@@ -0,0 +1,27 @@
+package jit
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// timestamp returns CLOCK_MONOTONIC in nanoseconds, the clock perf
+// record -k 1 uses.
+func timestamp() uint64 {
+	var ts syscall.Timespec
+	syscall.Syscall(syscall.SYS_CLOCK_GETTIME, 1, uintptr(unsafe.Pointer(&ts)), 0)
+	return uint64(ts.Nano())
+}
+
+func gettid() int { return syscall.Gettid() }
+
+// markJITDump maps the start of f executable. perf record sees the
+// mapping and records the name of the file for perf inject.
+func markJITDump(f *os.File) (func() error, error) {
+	b, err := syscall.Mmap(int(f.Fd()), 0, pageSize, syscall.PROT_READ|syscall.PROT_EXEC, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return syscall.Munmap(b) }, nil
+}
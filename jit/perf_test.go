@@ -0,0 +1,195 @@
+package jit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crawshaw/asm/i64"
+)
+
+var loopProgram = i64.Program{
+	{i64.MOVQ, i64.Imm(uint32(10)), i64.CX.Addr()},
+	{Op: i64.LABEL, From: i64.LabelAddr("loop")},
+	{i64.SUBQ, i64.Imm(1), i64.CX.Addr()},
+	{Op: i64.JNE, To: i64.LabelAddr("loop")},
+	{Op: i64.RET},
+}
+
+func TestSymbols(t *testing.T) {
+	got, err := Symbols("count", loopProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []i64.Symbol{
+		{Name: "count", Offset: 0, Size: 7},
+		{Name: "count.loop", Offset: 7, Size: 7},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Symbols()=%+v, want %+v", got, want)
+	}
+}
+
+type perfEntry struct {
+	addr uintptr
+	size int
+	name string
+}
+
+func parsePerfMap(t *testing.T, b []byte) []perfEntry {
+	var entries []perfEntry
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		var e perfEntry
+		if _, err := fmt.Sscanf(s.Text(), "%x %x %s", &e.addr, &e.size, &e.name); err != nil {
+			t.Fatalf("perf map line %q: %v", s.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestPerfMap(t *testing.T) {
+	syms, err := Symbols("count", loopProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	m := NewPerfMap(buf)
+	if err := m.Add(0x7f0000001000, syms); err != nil {
+		t.Fatal(err)
+	}
+	got := parsePerfMap(t, buf.Bytes())
+	want := []perfEntry{
+		{0x7f0000001000, 7, "count"},
+		{0x7f0000001007, 7, "count.loop"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("perf map %+v, want %+v", got, want)
+	}
+
+	m, err = OpenPerfMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("/tmp/perf-%d.map", os.Getpid())
+	defer os.Remove(name)
+	if err := m.Add(0x1000, syms[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parsePerfMap(t, b); len(got) != 1 || got[0] != (perfEntry{0x1000, 7, "count"}) {
+		t.Errorf("%s: %+v", name, got)
+	}
+}
+
+type codeLoad struct {
+	pid, tid        uint32
+	vma, addr, size uint64
+	index           uint64
+	name            string
+	code            []byte
+}
+
+// parseJITDump parses the header and records of a jitdump file.
+func parseJITDump(t *testing.T, b []byte) (loads []codeLoad, closed bool) {
+	le := binary.LittleEndian
+	if len(b) < jitHeaderSize || le.Uint32(b) != jitdumpMagic {
+		t.Fatalf("bad jitdump header: %x", b)
+	}
+	if v, size, mach := le.Uint32(b[4:]), le.Uint32(b[8:]), le.Uint32(b[12:]); v != 1 || size != jitHeaderSize || mach != emX86_64 {
+		t.Errorf("header version %d, size %d, machine %d", v, size, mach)
+	}
+	if pid := le.Uint32(b[20:]); pid != uint32(os.Getpid()) {
+		t.Errorf("header pid %d, want %d", pid, os.Getpid())
+	}
+	b = b[jitHeaderSize:]
+	var last uint64
+	for len(b) > 0 {
+		id, size, ts := le.Uint32(b), le.Uint32(b[4:]), le.Uint64(b[8:])
+		if ts < last {
+			t.Errorf("timestamp %d before %d", ts, last)
+		}
+		last = ts
+		rec := b[recHeaderSize:size]
+		b = b[size:]
+		switch id {
+		case jitCodeLoad:
+			l := codeLoad{
+				pid:   le.Uint32(rec),
+				tid:   le.Uint32(rec[4:]),
+				vma:   le.Uint64(rec[8:]),
+				addr:  le.Uint64(rec[16:]),
+				size:  le.Uint64(rec[24:]),
+				index: le.Uint64(rec[32:]),
+			}
+			rec = rec[40:]
+			n := bytes.IndexByte(rec, 0)
+			l.name = string(rec[:n])
+			l.code = rec[n+1:]
+			loads = append(loads, l)
+		case jitCodeClose:
+			closed = true
+		default:
+			t.Errorf("unknown record %d", id)
+		}
+	}
+	return loads, closed
+}
+
+func TestJITDump(t *testing.T) {
+	code, err := loopProgram.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	syms, err := Symbols("count", loopProgram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := OpenJITDump(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := d.c.(*os.File).Name()
+	if err := d.Add(0x4000, code, syms); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(name, fmt.Sprintf("jit-%d.dump", os.Getpid())) {
+		t.Errorf("jitdump file name %s", name)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loads, closed := parseJITDump(t, b)
+	if !closed {
+		t.Error("no close record")
+	}
+	if len(loads) != 2 {
+		t.Fatalf("%d code load records, want 2", len(loads))
+	}
+	for i, l := range loads {
+		s := syms[i]
+		if l.name != s.Name || l.vma != 0x4000+uint64(s.Offset) || l.addr != l.vma || l.size != uint64(s.Size) || l.index != uint64(i) || l.pid != uint32(os.Getpid()) {
+			t.Errorf("record %d: %+v, want symbol %+v", i, l, s)
+		}
+		if !bytes.Equal(l.code, code[s.Offset:s.Offset+s.Size]) {
+			t.Errorf("record %d: code %x, want %x", i, l.code, code[s.Offset:s.Offset+s.Size])
+		}
+	}
+}
@@ -4,11 +4,14 @@ package program
 import (
 	"bytes"
 	"reflect"
+	"sync"
 	"testing"
 	"unsafe"
 
 	"github.com/crawshaw/asm/call"
+	"github.com/crawshaw/asm/cpu"
 	"github.com/crawshaw/asm/i64"
+	"github.com/crawshaw/asm/jit"
 )
 
 var (
@@ -23,7 +26,7 @@ var (
 	num4ptr = uint64(reflect.ValueOf(num4).Pointer())
 )
 
-var progtests = []struct {
+type progtest struct {
 	program i64.Program
 	init1   uint64
 	init2   uint64
@@ -33,7 +36,9 @@ var progtests = []struct {
 	want2   uint64
 	want3   int32
 	want4   uint8
-}{
+}
+
+var progtests = []progtest{
 	{
 		i64.Program{
 			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
@@ -100,9 +105,263 @@ var progtests = []struct {
 		0, 0, 0, 0,
 		9, 0, 0, 0,
 	},
+	{
+		i64.Program{
+			// *num1 = uint64(*num4); *num2 = uint64(int8(*num4))
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.MOVBQZX, i64.BX.Ind(0), i64.CX.Addr()},
+			{i64.MOVBQSX, i64.BX.Ind(0), i64.DX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.CX.Addr(), i64.BX.Ind(0)},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.DX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0, 0, 0, 0xfe,
+		0xfe, 0xfffffffffffffffe, 0, 0xfe,
+	},
+	{
+		i64.Program{
+			// *num1 = min(*num1, *num2); *num4 = *num1 < *num2
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.CX.Addr()},
+			{i64.MOVQ, i64.BX.Ind(0), i64.AX.Addr()},
+			{i64.CMPQ, i64.AX.Addr(), i64.CX.Ind(0)},
+			{Op: i64.SETL, To: i64.DX.Addr()},
+			{i64.CMOVQGT, i64.CX.Ind(0), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.MOVB, i64.DX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		9, 4, 0, 0,
+		4, 4, 0, 0,
+	},
+	{
+		i64.Program{
+			// *num1 |= 1<<*num2; *num2 = lowest set bit; *num4 = *num1&0x100 != 0
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.SI.Addr()},
+			{i64.MOVQ, i64.SI.Ind(0), i64.CX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.BTSQ, i64.CX.Addr(), i64.BX.Ind(0)},
+			{i64.BSFQ, i64.BX.Ind(0), i64.DX.Addr()},
+			{i64.MOVQ, i64.DX.Addr(), i64.SI.Ind(0)},
+			{i64.TESTQ, i64.Imm(uint32(0x100)), i64.BX.Ind(0)},
+			{Op: i64.SETNE, To: i64.DX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.MOVB, i64.DX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0x140, 4, 0, 0,
+		0x150, 4, 0, 1,
+	},
+	{
+		i64.Program{
+			// *num2 = popcount(*num1)
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.POPCNTQ, i64.BX.Ind(0), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0xf0f0, 0, 0, 0,
+		0xf0f0, 8, 0, 0,
+	},
+	{
+		i64.Program{
+			// copy(num2, num1); *num4 = *num1 == *num2
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.SI.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.DI.Addr()},
+			{i64.MOVQ, i64.Imm(uint32(8)), i64.CX.Addr()},
+			{Op: i64.CLD},
+			{Op: i64.MOVSB | i64.REP},
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.SI.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.DI.Addr()},
+			{i64.MOVQ, i64.Imm(uint32(8)), i64.CX.Addr()},
+			{Op: i64.CMPSB | i64.REPE},
+			{Op: i64.SETE, To: i64.DX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.MOVB, i64.DX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0x0123456789abcdef, 0, 0, 0,
+		0x0123456789abcdef, 0x0123456789abcdef, 0, 1,
+	},
+	{
+		i64.Program{
+			// *num2 = 0x0101...; *num1 = 7 - index of first 0x01 byte
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.DI.Addr()},
+			{i64.MOVQ, i64.Imm(uint32(8)), i64.CX.Addr()},
+			{i64.MOVL, i64.Imm(uint32(1)), i64.AX.Addr()},
+			{Op: i64.STOSB | i64.REP},
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.DI.Addr()},
+			{i64.MOVQ, i64.Imm(uint32(8)), i64.CX.Addr()},
+			{Op: i64.SCASB | i64.REPNE},
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.DI.Addr()},
+			{i64.MOVQ, i64.CX.Addr(), i64.DI.Ind(0)},
+			{Op: i64.RET},
+		},
+		0x0000000000010000, 0, 0, 0,
+		5, 0x0101010101010101, 0, 0,
+	},
+	{
+		i64.Program{
+			// x := math.Sqrt(float64(*num1)); *num2 = int64(x); *num4 = x < *num1
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.CVTSQ2SD, i64.BX.Ind(0), i64.X0.Addr()},
+			{i64.SQRTSD, i64.X0.Addr(), i64.X1.Addr()},
+			{i64.CVTTSD2SQ, i64.X1.Addr(), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{i64.UCOMISD, i64.X0.Addr(), i64.X1.Addr()},
+			{Op: i64.SETB, To: i64.DX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.MOVB, i64.DX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		50, 0, 0, 0,
+		50, 7, 0, 1,
+	},
+	{
+		i64.Program{
+			// *num2 = bytes.IndexByte(num1[:], *num4)
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.BX.Ind(0), i64.X0.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.MOVBLZX, i64.BX.Ind(0), i64.AX.Addr()},
+			{i64.MOVL, i64.AX.Addr(), i64.X1.Addr()},
+			{i64.PXOR, i64.X2.Addr(), i64.X2.Addr()},
+			{i64.PSHUFB, i64.X2.Addr(), i64.X1.Addr()},
+			{i64.PCMPEQB, i64.X1.Addr(), i64.X0.Addr()},
+			{i64.PMOVMSKB, i64.X0.Addr(), i64.AX.Addr()},
+			{i64.BSFL, i64.AX.Addr(), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0x1122334455667788, 0, 0, 0x55,
+		0x1122334455667788, 3, 0, 0x55,
+	},
+	{
+		// Three-operand IMULQ and SHLDQ.
+		i64.Program{
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.BX.Ind(0), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(0xab00000000000000)), i64.DX.Addr()},
+			{i64.SHLDQ, i64.Args(i64.Imm(uint8(8)), i64.DX.Addr()), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.IMULQ, i64.Args(i64.Imm(uint32(1000)), i64.BX.Ind(0)), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0x0022334455667788, 7, 0, 0,
+		0x22334455667788ab, 7000, 0, 0,
+	},
+	{
+		// Register moves and immediates sized by the encoder.
+		i64.Program{
+			{i64.MOVQ, i64.Imm(int64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.BX.Ind(0), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.CX.Addr()},
+			{i64.ADDQ, i64.Imm(-3), i64.CX.Addr()},
+			{i64.MOVQ, i64.Imm(-1), i64.DX.Addr()},
+			{i64.MOVQ, i64.Imm(0xffffffff), i64.DX.Addr()},
+			{i64.ADDQ, i64.DX.Addr(), i64.CX.Addr()},
+			{i64.MOVQ, i64.CX.Addr(), i64.BX.Ind(0)},
+			{i64.MOVQ, i64.Imm(int64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.Imm(0x123456789), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		10, 0, 0, 0,
+		0x100000006, 0x123456789, 0, 0,
+	},
 }
 
 func TestProgram(t *testing.T) {
+	runProgtests(t, progtests)
+}
+
+// AVX2 test programs, run only if the CPU supports AVX2.
+var avx2tests = []progtest{
+	{
+		// Mask of the bytes of a broadcast *num4 that double to zero.
+		i64.Program{
+			{i64.MOVQ, i64.Imm(uint64(num4ptr)), i64.BX.Addr()},
+			{i64.VPBROADCASTB, i64.BX.Ind(0), i64.Y0.Addr()},
+			{i64.VPADDB, i64.Args(i64.Y0.Addr(), i64.Y0.Addr()), i64.Y1.Addr()},
+			{i64.VPXOR, i64.Args(i64.Y2.Addr(), i64.Y2.Addr()), i64.Y2.Addr()},
+			{i64.VPCMPEQB, i64.Args(i64.Y2.Addr(), i64.Y1.Addr()), i64.Y3.Addr()},
+			{i64.VPMOVMSKB, i64.Y3.Addr(), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.VZEROUPPER},
+			{Op: i64.RET},
+		},
+		0, 0, 0, 0x80,
+		0, 0xffffffff, 0, 0x80,
+	},
+}
+
+func TestAVX2(t *testing.T) {
+	if !cpu.Host.Has(cpu.AVX2) {
+		t.Skip("CPU does not support AVX2")
+	}
+	runProgtests(t, avx2tests)
+}
+
+// AVX-512 test programs, run only if the CPU supports AVX-512F and BW.
+var avx512tests = []progtest{
+	{
+		// Mask of the nonzero elements of a broadcast *num3,
+		// with the odd elements zeroed by an opmask.
+		i64.Program{
+			{i64.MOVQ, i64.Imm(uint64(num3ptr)), i64.BX.Addr()},
+			{i64.VPBROADCASTD, i64.BX.Ind(0), i64.Z0.Addr()},
+			{i64.MOVL, i64.Imm(uint32(0x5555)), i64.AX.Addr()},
+			{i64.KMOVW, i64.AX.Addr(), i64.K1.Addr()},
+			{i64.VPADDD | i64.ZERO, i64.Args(i64.Z0.Addr(), i64.Z0.Addr(), i64.K1.Addr()), i64.Z17.Addr()},
+			{i64.VPTESTMD, i64.Args(i64.Z17.Addr(), i64.Z17.Addr()), i64.K2.Addr()},
+			{i64.KMOVW, i64.K2.Addr(), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.VZEROUPPER},
+			{Op: i64.RET},
+		},
+		0, 0, 7, 0,
+		0, 0x5555, 7, 0,
+	},
+	{
+		// Broadcast *num1 with an embedded broadcast and store the
+		// sum of the first two elements in *num2.
+		i64.Program{
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.VPXORQ, i64.Args(i64.Z1.Addr(), i64.Z1.Addr()), i64.Z1.Addr()},
+			{i64.VPADDQ | i64.BCST, i64.Args(i64.BX.Ind(0), i64.Z1.Addr()), i64.Z2.Addr()},
+			{i64.MOVL, i64.Imm(uint32(0x3)), i64.AX.Addr()},
+			{i64.KMOVW, i64.AX.Addr(), i64.K1.Addr()},
+			{i64.VPCOMPRESSQ | i64.ZERO, i64.Args(i64.Z2.Addr(), i64.K1.Addr()), i64.Z3.Addr()},
+			{i64.VPADDQ, i64.Args(i64.Z3.Addr(), i64.Z3.Addr()), i64.Z3.Addr()},
+			{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.X3.Addr(), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.VZEROUPPER},
+			{Op: i64.RET},
+		},
+		21, 0, 0, 0,
+		21, 42, 0, 0,
+	},
+}
+
+func TestAVX512(t *testing.T) {
+	if !cpu.Host.Has(cpu.AVX512F | cpu.AVX512BW) {
+		t.Skip("CPU does not support AVX-512F and AVX-512BW")
+	}
+	runProgtests(t, avx512tests)
+}
+
+func runProgtests(t *testing.T, progtests []progtest) {
 	var programText string
 	defer func() {
 		if x := recover(); x != nil {
@@ -119,7 +378,7 @@ func TestProgram(t *testing.T) {
 		programText = buf.String()
 
 		// Run the program.
-		code, err := test.program.Bytes()
+		code, err := test.program.Load()
 		if err != nil {
 			t.Errorf("%d: %v", i, err)
 			continue
@@ -132,3 +391,356 @@ func TestProgram(t *testing.T) {
 		}
 	}
 }
+
+// Programs with waste for the peephole optimizer to remove.
+var peepholetests = []progtest{
+	{
+		// *num1 = sum of 1..*num1
+		i64.Program{
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.Imm(0), i64.AX.Addr()},
+			{i64.MOVQ, i64.BX.Ind(0), i64.CX.Addr()},
+			{Op: i64.LABEL, From: i64.LabelAddr("loop")},
+			{i64.ADDQ, i64.CX.Addr(), i64.AX.Addr()},
+			{i64.MOVQ, i64.AX.Addr(), i64.AX.Addr()},
+			{i64.ADDQ, i64.Imm(0), i64.DX.Addr()},
+			{i64.SUBQ, i64.Imm(1), i64.CX.Addr()},
+			{Op: i64.JE, To: i64.LabelAddr("done")},
+			{Op: i64.JMP, To: i64.LabelAddr("again")},
+			{Op: i64.LABEL, From: i64.LabelAddr("done")},
+			{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.JMP, To: i64.LabelAddr("exit")},
+			{Op: i64.LABEL, From: i64.LabelAddr("exit")},
+			{Op: i64.RET},
+			{Op: i64.LABEL, From: i64.LabelAddr("again")},
+			{Op: i64.JMP, To: i64.LabelAddr("loop")},
+		},
+		5, 0, 0, 0,
+		15, 0, 0, 0,
+	},
+	{
+		// *num1 = 7 if *num1 == 0 else 0. The ADDQ $0 sets the flags
+		// the JNE reads, so neither it nor the MOVQ $0 can be rewritten.
+		i64.Program{
+			{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+			{i64.MOVQ, i64.BX.Ind(0), i64.AX.Addr()},
+			{i64.ADDQ, i64.Imm(0), i64.AX.Addr()},
+			{i64.MOVQ, i64.Imm(0), i64.CX.Addr()},
+			{Op: i64.JNE, To: i64.LabelAddr("nonzero")},
+			{i64.MOVQ, i64.Imm(7), i64.CX.Addr()},
+			{Op: i64.LABEL, From: i64.LabelAddr("nonzero")},
+			{i64.MOVQ, i64.CX.Addr(), i64.BX.Ind(0)},
+			{Op: i64.RET},
+		},
+		0, 0, 0, 0,
+		7, 0, 0, 0,
+	},
+}
+
+// TestPeephole runs each test program before and after optimization.
+func TestPeephole(t *testing.T) {
+	corpus := append(append([]progtest(nil), progtests...), peepholetests...)
+	runProgtests(t, corpus)
+
+	optimized := make([]progtest, len(corpus))
+	for i, test := range corpus {
+		optimized[i] = test
+		optimized[i].program = test.program.Optimize(i64.PeepholeRules)
+	}
+	runProgtests(t, optimized)
+
+	for i, test := range peepholetests {
+		before, err := test.program.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := optimized[len(progtests)+i].program.Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && len(after) >= len(before) {
+			t.Errorf("%d: optimized to %d bytes from %d", i, len(after), len(before))
+		}
+		if i == 1 && len(after) != len(before) {
+			t.Errorf("%d: optimized a flag-sensitive program", i)
+		}
+	}
+}
+
+// TestDeadCode runs each test program after removing its dead code,
+// alone and after optimization.
+func TestDeadCode(t *testing.T) {
+	corpus := append(append([]progtest(nil), progtests...), peepholetests...)
+	var stripped []progtest
+	for _, test := range corpus {
+		for _, p := range []i64.Program{test.program, test.program.Optimize(i64.PeepholeRules)} {
+			q, err := p.RemoveDeadCode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.program = q
+			stripped = append(stripped, test)
+		}
+	}
+	runProgtests(t, stripped)
+
+	// Optimizing the first peephole test leaves the block at "again"
+	// unreachable.
+	p := peepholetests[0].program.Optimize(i64.PeepholeRules)
+	q, err := p.RemoveDeadCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q) >= len(p) {
+		t.Errorf("RemoveDeadCode left %d of %d instructions:\n%v", len(q), len(p), q)
+	}
+}
+
+// Each atomic test program adds 1000 to *num1.
+var atomictests = []i64.Program{
+	{
+		{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+		{i64.MOVQ, i64.Imm(uint32(1000)), i64.CX.Addr()},
+		{Op: i64.LABEL, From: i64.LabelAddr("loop")},
+		{i64.ADDQ | i64.LOCK, i64.Imm(uint8(1)), i64.BX.Ind(0)},
+		{i64.SUBQ, i64.Imm(uint8(1)), i64.CX.Addr()},
+		{Op: i64.JNE, To: i64.LabelAddr("loop")},
+		{Op: i64.RET},
+	},
+	{
+		{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+		{i64.MOVQ, i64.Imm(uint32(1000)), i64.CX.Addr()},
+		{Op: i64.LABEL, From: i64.LabelAddr("loop")},
+		{i64.MOVQ, i64.Imm(uint32(1)), i64.AX.Addr()},
+		{i64.XADDQ | i64.LOCK, i64.AX.Addr(), i64.BX.Ind(0)},
+		{i64.SUBQ, i64.Imm(uint8(1)), i64.CX.Addr()},
+		{Op: i64.JNE, To: i64.LabelAddr("loop")},
+		{Op: i64.RET},
+	},
+	{
+		{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+		{i64.MOVQ, i64.Imm(uint32(1000)), i64.CX.Addr()},
+		{Op: i64.LABEL, From: i64.LabelAddr("loop")},
+		{i64.MOVQ, i64.BX.Ind(0), i64.AX.Addr()},
+		{i64.MOVQ, i64.Imm(uint32(1)), i64.DX.Addr()},
+		{i64.ADDQ, i64.AX.Addr(), i64.DX.Addr()},
+		{i64.CMPXCHGQ | i64.LOCK, i64.DX.Addr(), i64.BX.Ind(0)},
+		{Op: i64.JNE, To: i64.LabelAddr("loop")},
+		{i64.SUBQ, i64.Imm(uint8(1)), i64.CX.Addr()},
+		{Op: i64.JNE, To: i64.LabelAddr("loop")},
+		{Op: i64.MFENCE},
+		{Op: i64.RET},
+	},
+}
+
+func TestAtomic(t *testing.T) {
+	const procs = 8
+	for i, program := range atomictests {
+		code, err := program.Load()
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		fn := unsafe.Pointer(&code[0])
+		*num1 = 0
+		var wg sync.WaitGroup
+		for j := 0; j < procs; j++ {
+			wg.Add(1)
+			go func() {
+				call.Call(fn, nil, 0)
+				wg.Done()
+			}()
+		}
+		wg.Wait()
+		if want := uint64(procs * 1000); *num1 != want {
+			buf := new(bytes.Buffer)
+			program.PrintText(buf)
+			t.Errorf("%d: got %d, want %d\n%s", i, *num1, want, buf)
+		}
+	}
+}
+
+func TestArena(t *testing.T) {
+	var a jit.Arena
+	defer a.Close()
+	funcs := make([]*jit.Func, len(progtests))
+	for i, test := range progtests {
+		f, err := a.Load(test.program)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		funcs[i] = f
+	}
+	run := func(i int) {
+		test := progtests[i]
+		entry := funcs[i].Entry()
+		fn := *(*unsafe.Pointer)(unsafe.Pointer(&entry))
+		*num1, *num2, *num3, *num4 = test.init1, test.init2, test.init3, test.init4
+		call.Call(fn, nil, 0)
+		if *num1 != test.want1 || *num2 != test.want2 || *num3 != test.want3 || *num4 != test.want4 {
+			t.Errorf("%d: got %d,%d,%d,%d, want %d,%d,%d,%d", i, *num1, *num2, *num3, *num4, test.want1, test.want2, test.want3, test.want4)
+		}
+	}
+	for i := range progtests {
+		run(i)
+	}
+
+	// Free every other program, so compaction moves the rest.
+	for i := 0; i < len(funcs); i += 2 {
+		funcs[i].Free()
+	}
+	if err := a.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(funcs); i += 2 {
+		run(i)
+	}
+}
+
+func TestModule(t *testing.T) {
+	var a jit.Arena
+	defer a.Close()
+
+	// double is loaded on its own and called as an external function.
+	double, err := a.Load(i64.Program{
+		{i64.MOVQ, i64.SP.Ind(8), i64.AX.Addr()},
+		{i64.ADDQ, i64.AX.Addr(), i64.AX.Addr()},
+		{i64.MOVQ, i64.AX.Addr(), i64.SP.Ind(8)},
+		{Op: i64.RET},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m i64.Module
+	m.Add("main", i64.Program{
+		// *num1 = double(add_one(8))
+		{i64.SUBQ, i64.Imm(16), i64.SP.Addr()},
+		{i64.MOVQ, i64.Imm(8), i64.SP.Ind(0)},
+		{Op: i64.CALL, To: i64.LabelAddr("add_one")},
+		{Op: i64.CALL, To: i64.LabelAddr("double")},
+		{i64.MOVQ, i64.SP.Ind(0), i64.BX.Addr()},
+		{i64.MOVQ, i64.Imm(int64(num1ptr)), i64.CX.Addr()},
+		{i64.MOVQ, i64.BX.Addr(), i64.CX.Ind(0)},
+		{i64.ADDQ, i64.Imm(16), i64.SP.Addr()},
+		{Op: i64.RET},
+	})
+	m.Add("add_one", i64.Program{
+		{i64.MOVQ, i64.SP.Ind(8), i64.AX.Addr()},
+		{i64.ADDQ, i64.Imm(1), i64.AX.Addr()},
+		{i64.MOVQ, i64.AX.Addr(), i64.SP.Ind(8)},
+		{Op: i64.RET},
+	})
+	m.Extern("double", double.Entry())
+	obj, err := m.Assemble(i64.Options{Target: cpu.Host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := a.Alloc(obj.Code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym, _ := obj.Lookup("main")
+	entry := f.Entry() + uintptr(sym.Offset)
+	*num1 = 0
+	call.Call(*(*unsafe.Pointer)(unsafe.Pointer(&entry)), nil, 0)
+	if *num1 != 18 {
+		t.Errorf("got %d, want 18", *num1)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	var a jit.Arena
+	defer a.Close()
+	program := i64.Program{
+		{i64.MOVL, i64.Imm(uint32(7)), i64.AX.Addr()},
+		{Op: i64.JMP, To: i64.LabelAddr("store")},
+		{Op: i64.LABEL, From: i64.LabelAddr("store")},
+		{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.BX.Addr()},
+		{i64.MOVQ, i64.AX.Addr(), i64.BX.Ind(0)},
+		{Op: i64.RET},
+		{Op: i64.LABEL, From: i64.LabelAddr("inc")}, // at offset 21
+		{i64.ADDQ, i64.Imm(1), i64.AX.Addr()},
+		{Op: i64.JMP, To: i64.LabelAddr("store")},
+	}
+	pts, err := program.PatchPoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := a.Load(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(want uint64) {
+		t.Helper()
+		entry := f.Entry()
+		*num1 = 0
+		call.Call(*(*unsafe.Pointer)(unsafe.Pointer(&entry)), nil, 0)
+		if *num1 != want {
+			t.Errorf("got %d, want %d", *num1, want)
+		}
+	}
+	run(7)
+	if pts[0].Index != 0 || pts[0].Kind != i64.PatchImm {
+		t.Fatalf("first patch point is %+v, want MOVL immediate", pts[0])
+	}
+	if err := f.Patch(pts[0], 40); err != nil {
+		t.Fatal(err)
+	}
+	run(40)
+	if pts[1].Index != 1 || pts[1].Kind != i64.PatchRel {
+		t.Fatalf("second patch point is %+v, want JMP target", pts[1])
+	}
+	if err := f.PatchTarget(pts[1], f.Entry()+21); err != nil {
+		t.Fatal(err)
+	}
+	run(41)
+}
+
+func TestCPUID(t *testing.T) {
+	// Store the vendor string from CPUID leaf 0 in *num1 and *num2,
+	// and XCR0 in *num3.
+	program := i64.Program{
+		{i64.MOVL, i64.Imm(uint32(0)), i64.AX.Addr()},
+		{i64.MOVL, i64.Imm(uint32(0)), i64.CX.Addr()},
+		{Op: i64.CPUID},
+		{i64.MOVQ, i64.Imm(uint64(num1ptr)), i64.SI.Addr()},
+		{i64.MOVL, i64.BX.Addr(), i64.SI.Ind(0)},
+		{i64.MOVL, i64.DX.Addr(), i64.SI.Ind(4)},
+		{i64.MOVQ, i64.Imm(uint64(num2ptr)), i64.SI.Addr()},
+		{i64.MOVL, i64.CX.Addr(), i64.SI.Ind(0)},
+		{i64.MOVL, i64.Imm(uint32(0)), i64.CX.Addr()},
+		{Op: i64.XGETBV},
+		{i64.MOVQ, i64.Imm(uint64(num3ptr)), i64.SI.Addr()},
+		{i64.MOVL, i64.AX.Addr(), i64.SI.Ind(0)},
+		{Op: i64.RET},
+	}
+	if !cpu.Host.Has(cpu.AVX) {
+		// XGETBV needs OSXSAVE, which AVX support implies.
+		program = append(program[:9], program[12:]...)
+	}
+	code, err := program.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	*num1, *num2, *num3 = 0, 0, 0
+	call.Call(unsafe.Pointer(&code[0]), nil, 0)
+
+	var vendor []byte
+	for i := uint(0); i < 8; i++ {
+		vendor = append(vendor, byte(*num1>>(8*i)))
+	}
+	for i := uint(0); i < 4; i++ {
+		vendor = append(vendor, byte(*num2>>(8*i)))
+	}
+	t.Logf("vendor %q, XCR0 %#x", vendor, *num3)
+	for _, b := range vendor {
+		if b < ' ' || b > '~' {
+			t.Errorf("vendor string %q is not printable", vendor)
+			break
+		}
+	}
+	if cpu.Host.Has(cpu.AVX) && *num3&0x6 != 0x6 {
+		t.Errorf("AVX supported, but XCR0=%#x does not enable YMM state", *num3)
+	}
+}